@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/export"
+	"github.com/tehsis/logmeup-api/pkg/config"
+)
+
+var errExportUsage = errors.New("usage: api export -from YYYY-MM-DD [-to YYYY-MM-DD] [-dir DIR]")
+
+// runExport implements "api export": it writes a Markdown file per day, the
+// same files GET /api/v1/export/markdown zips, into a directory. -to
+// defaults to today and -dir to the working directory.
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	fromFlag := flags.String("from", "", "first day to export (YYYY-MM-DD)")
+	toFlag := flags.String("to", time.Now().UTC().Format("2006-01-02"), "last day to export (YYYY-MM-DD)")
+	dir := flags.String("dir", ".", "directory the files are written to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 || *fromFlag == "" {
+		return errExportUsage
+	}
+
+	from, err := time.Parse("2006-01-02", *fromFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -from %q: expected YYYY-MM-DD", *fromFlag)
+	}
+	to, err := time.Parse("2006-01-02", *toFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -to %q: expected YYYY-MM-DD", *toFlag)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("-to %s is before -from %s", *toFlag, *fromFlag)
+	}
+
+	// A new memory store is always empty, so there would be nothing to export
+	if cfg.DBDriver == "memory" {
+		return errors.New("the memory driver keeps no notes between runs; export from postgres or sqlite")
+	}
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	days := 0
+	err = export.EachDay(context.Background(), store.notes, store.actions, from, to, func(day export.Day) error {
+		days++
+		return export.WriteFile(*dir, day)
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Exported notes", "days", days, "dir", *dir)
+	return nil
+}
@@ -1,62 +1,319 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/graphql"
+	"github.com/tehsis/logmeup-api/internal/grpcserver"
 	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/metrics"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
 	"github.com/tehsis/logmeup-api/internal/repository"
 	"github.com/tehsis/logmeup-api/internal/routes"
+	"github.com/tehsis/logmeup-api/internal/tracing"
 	websocketHub "github.com/tehsis/logmeup-api/internal/websocket"
 	"github.com/tehsis/logmeup-api/pkg/config"
 	"github.com/tehsis/logmeup-api/pkg/database"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
 	// Load configuration
-	cfg, err := config.LoadConfig()
+	cfg, args, err := config.LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		// Logging is not configured yet, and the list of problems reads
+		// better as plain lines
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Configure logging
+	if err := logging.Setup(os.Stderr, logging.Options{Format: cfg.LogFormat, Level: cfg.LogLevel, Content: cfg.LogContent}); err != nil {
+		fatal("Failed to configure logging", err)
 	}
 
-	// Initialize database connection
-	db, err := database.NewDBConnection(cfg)
+	// Configure tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to configure tracing", err)
 	}
-	defer db.Close()
 
-	// Initialize WebSocket hub
-	hub := websocketHub.NewHub()
-	go hub.Run()
-	log.Printf("WebSocket hub started")
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			if err := runMigrate(cfg, args[1:]); err != nil {
+				fatal("Migration failed", err)
+			}
+			return
+		case "export":
+			if err := runExport(cfg, args[1:]); err != nil {
+				fatal("Export failed", err)
+			}
+			return
+		default:
+			fatal("Unknown command", fmt.Errorf("%q: run without arguments to start the server or use \"migrate\" or \"export\"", args[0]))
+		}
+	}
 
-	// Initialize repositories
-	noteRepo := repository.NewNoteRepository(db)
-	actionRepo := repository.NewActionRepository(db)
+	// Initialize storage
+	store, err := openStorage(cfg)
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+	noteRepo := tracing.NoteStore(metrics.NoteStore(store.notes), cfg.DBDriver)
+	actionRepo := tracing.ActionStore(metrics.ActionStore(store.actions), cfg.DBDriver)
 
-	// Initialize handlers
-	noteHandler := handlers.NewNoteHandler(noteRepo)
+	// Initialize WebSocket hub
+	hub := websocketHub.NewHub(noteRepo, actionRepo)
+	metrics.SetBroadcastQueue(hub.QueueDepth)
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	hubDone := make(chan struct{})
+	go func() {
+		hub.Run(hubCtx)
+		close(hubDone)
+	}()
+	slog.Info("WebSocket hub started")
+
+	// Initialize handlers. Every API draws from the same rate limit budgets.
+	limiter := ratelimit.NewLimiter()
+	noteHandler := handlers.NewNoteHandler(noteRepo, actionRepo, hub)
 	actionHandler := handlers.NewActionHandler(actionRepo, hub)
+	exportHandler := handlers.NewExportHandler(noteRepo, actionRepo, store.backups, hub)
+	graphQLHandler := graphql.NewHandler(noteRepo, actionRepo, hub, graphql.Options{
+		Limiter:   limiter,
+		RateLimit: cfg.RateLimit,
+	})
+	healthHandler := handlers.NewHealthHandler(cfg.ReadinessTimeout, append(store.checks, handlers.HealthCheck{
+		Name:  "hub",
+		Check: hub.Ping,
+	})...)
 
 	// Initialize router
-	r := gin.Default()
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+	r.Use(tracing.HTTP()...)
+	r.Use(handlers.RequestID(), handlers.RequestLogger(), metrics.HTTP(), handlers.Recovery(), handlers.MaxBodySize(cfg.MaxBodyBytes, map[string]int64{
+		"/api/import/json": cfg.ImportMaxBytes,
+		"/api/import/csv":  cfg.ImportMaxBytes,
+	}))
 
 	// Add CORS middleware
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:5174", "http://localhost:5175"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowOrigins: []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:5174", "http://localhost:5175"},
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Length", "Content-Type", "Authorization", handlers.RequestIDHeader},
+		ExposeHeaders: []string{
+			handlers.RequestIDHeader, "Retry-After", "Deprecation", "Sunset", "Link",
+			handlers.RateLimitLimitHeader, handlers.RateLimitRemainingHeader, handlers.RateLimitResetHeader, handlers.RateLimitPolicyHeader,
+		},
 		AllowCredentials: true,
 	}))
 
 	// Setup routes
-	routes.SetupRoutes(r, noteHandler, actionHandler, hub)
+	routes.SetupRoutes(r, noteHandler, actionHandler, exportHandler, healthHandler, hub, graphQLHandler, routes.Options{
+		APIMiddleware: []gin.HandlerFunc{handlers.RateLimit(limiter, cfg.RateLimit)},
+		LegacySunset:  cfg.LegacyAPISunset,
+	})
+
+	// The gRPC services share the stores, hub, rate limits and readiness
+	// checks of the HTTP API
+	grpcOpts := grpcserver.Options{
+		Limiter:         limiter,
+		RateLimit:       cfg.RateLimit,
+		MaxMessageBytes: int(cfg.MaxBodyBytes),
+		Readiness:       healthHandler,
+		Reflection:      cfg.GRPCReflection,
+	}
+	if cfg.GRPCTLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.GRPCTLSCert, cfg.GRPCTLSKey)
+		if err != nil {
+			fatal("Failed to load the gRPC certificate", err)
+		}
+		grpcOpts.Credentials = creds
+	} else {
+		slog.Warn("Serving gRPC without TLS; set GRPC_TLS_CERT and GRPC_TLS_KEY unless a proxy terminates TLS")
+	}
+	grpcServer := grpcserver.New(noteRepo, actionRepo, hub, grpcOpts)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		fatal("Failed to listen for gRPC", err)
+	}
 
 	// Start server
-	log.Printf("Starting server on port %s with WebSocket support", cfg.ServerPort)
-	if err := r.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:    ":" + cfg.ServerPort,
+		Handler: r,
 	}
+	go func() {
+		slog.Info("Starting server", "port", cfg.ServerPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Failed to start server", err)
+		}
+	}()
+	go func() {
+		slog.Info("Starting gRPC server", "port", cfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			fatal("Failed to start gRPC server", err)
+		}
+	}()
+
+	// Wait for a termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Report NOT_SERVING to gRPC health checks and stop accepting calls;
+	// WatchActions streams end with the hub
+	grpcDone := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcDone)
+	}()
+
+	// Stop accepting requests and let in-flight ones finish
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown failed", "error", err)
+	}
+	// Shutdown leaves hijacked connections alone, so GraphQL sockets are
+	// closed here, before the storage their operations use
+	if err := graphQLHandler.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("GraphQL operations did not finish before the shutdown timeout")
+	}
+
+	// Close WebSocket clients and save notes being edited. The hub returns
+	// once its clients' goroutines and session saves have finished.
+	stopHub()
+	select {
+	case <-hubDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("WebSocket hub did not stop before the shutdown timeout")
+	}
+	select {
+	case <-grpcDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("gRPC calls did not finish before the shutdown timeout")
+		grpcServer.Stop()
+	}
+
+	// The database goes last since the hub saves notes until it stops
+	if err := store.close(); err != nil {
+		slog.Error("Closing database failed", "error", err)
+	}
+	// Flush spans last so shutdown work is traced
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Flushing traces failed", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// storage holds the repositories for the configured backend, the readiness
+// checks of the database behind them and a function releasing it
+type storage struct {
+	notes   repository.NoteStore
+	actions repository.ActionStore
+	backups repository.BackupStore
+	checks  []handlers.HealthCheck
+	close   func() error
+}
+
+// openStorage creates the repositories for the configured backend
+func openStorage(cfg *config.Config) (*storage, error) {
+	if cfg.DBDriver == "memory" {
+		slog.Warn("Using in-memory storage; data will be lost when the server stops")
+		store := repository.NewMemoryStore()
+		return &storage{
+			notes:   repository.NewMemoryNoteRepository(store),
+			actions: repository.NewMemoryActionRepository(store),
+			backups: repository.NewMemoryBackupRepository(store),
+			close:   func() error { return nil },
+		}, nil
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrateUp(db, cfg.DBDriver); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if err := metrics.RegisterDB(db, cfg.DBDriver); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	migrator, err := database.NewMigrator(db, cfg.DBDriver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &storage{
+		checks: []handlers.HealthCheck{
+			{Name: "database", Check: db.PingContext},
+			{Name: "migrations", Check: migrator.Check},
+		},
+		close: db.Close,
+	}
+	if cfg.DBDriver == "sqlite" {
+		s.notes = repository.NewSQLiteNoteRepository(db, cfg.DBQueryTimeout)
+		s.actions = repository.NewSQLiteActionRepository(db, cfg.DBQueryTimeout)
+		s.backups = repository.NewSQLiteBackupRepository(db)
+	} else {
+		s.notes = repository.NewNoteRepository(db, cfg.DBQueryTimeout)
+		s.actions = repository.NewActionRepository(db, cfg.DBQueryTimeout)
+		s.backups = repository.NewBackupRepository(db)
+	}
+	s.notes = repository.RetryNoteReads(s.notes, cfg.DBReadRetries)
+	s.actions = repository.RetryActionReads(s.actions, cfg.DBReadRetries)
+	return s, nil
+}
+
+// openDatabase connects to the SQL database of the configured driver
+func openDatabase(cfg *config.Config) (*sql.DB, error) {
+	switch cfg.DBDriver {
+	case "sqlite":
+		slog.Info("Using SQLite database", "path", cfg.DBPath)
+		return database.NewSQLiteConnection(cfg)
+	case "postgres":
+		return database.NewDBConnection(cfg)
+	}
+	return nil, fmt.Errorf("the %s driver has no database", cfg.DBDriver)
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/tehsis/logmeup-api/pkg/config"
+	"github.com/tehsis/logmeup-api/pkg/database"
+)
+
+var errMigrateUsage = errors.New("usage: api migrate up | down [N] | status | to N")
+
+// runMigrate implements "api migrate": up applies every pending migration,
+// down rolls back N migrations (one by default), to migrates up or down to
+// version N and status lists what has been applied.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, cfg.DBDriver)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		err = migrator.Up(ctx)
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		} else if len(args) != 1 {
+			return errMigrateUsage
+		}
+		err = migrator.Down(ctx, steps)
+
+	case "to":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, parseErr := strconv.ParseUint(args[1], 10, 32)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.To(ctx, uint(version))
+
+	case "status":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		return printMigrationStatus(ctx, migrator)
+
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Database migrated", "version", version)
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range status {
+		state := "pending"
+		if s.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%06d_%s\t%s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
+
+// migrateUp applies pending migrations before the server starts
+func migrateUp(db *sql.DB, driver string) error {
+	migrator, err := database.NewMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := migrator.Up(ctx); err != nil {
+		return err
+	}
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Database migrated", "version", version)
+	return nil
+}
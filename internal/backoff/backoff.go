@@ -0,0 +1,41 @@
+// Package backoff spaces out retries with exponentially growing delays.
+package backoff
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff hands out the delay before each retry: Initial, then doubling up
+// to Max. Each delay is jittered by up to a quarter so clients retrying
+// together spread out.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+
+	next time.Duration
+}
+
+// Next returns the delay before the next retry
+func (b *Backoff) Next() time.Duration {
+	if b.next == 0 {
+		b.next = b.Initial
+	}
+	delay := b.next
+	b.next = min(b.next*2, b.Max)
+	return delay - time.Duration(rand.Int64N(int64(delay)/4+1))
+}
+
+// Wait sleeps for the next delay, returning early with ctx's error if it
+// ends first
+func (b *Backoff) Wait(ctx context.Context) error {
+	timer := time.NewTimer(b.Next())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,31 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Run("Next", func(t *testing.T) {
+		b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+		for _, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+			want *= time.Millisecond
+			got := b.Next()
+			if got > want || got < want*3/4 {
+				t.Errorf("Expected a delay between %v and %v, got %v", want*3/4, want, got)
+			}
+		}
+	})
+
+	t.Run("Wait", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		b := &Backoff{Initial: time.Hour, Max: time.Hour}
+		if err := b.Wait(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the context's error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Package backup writes every note and action to a versioned JSON document
+// and restores a database from one.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// Format names backup documents, telling them apart from other JSON
+const Format = "logmeup-backup"
+
+// Version is the document version written, and the only one read. It is
+// bumped whenever a change would make older readers misread a backup.
+const Version = 1
+
+// Document is a backup. Records keep the IDs and timestamps they had in the
+// database they were dumped from; actions refer to notes by those IDs.
+type Document struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Notes      []*models.Note   `json:"notes"`
+	Actions    []*models.Action `json:"actions"`
+}
+
+// header is a Document without its records, written before them
+type header struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Write streams a Document with everything in store to w, a record at a
+// time, so a backup never has to fit in memory
+func Write(ctx context.Context, w io.Writer, store repository.BackupStore) error {
+	start, err := json.Marshal(header{Format: Format, Version: Version, ExportedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	// Reopen the header object to append the record arrays to it
+	out.Write(start[:len(start)-1])
+	out.WriteString(`,"notes":[`)
+
+	count, inActions := 0, false
+	record := func(v any) error {
+		if count > 0 {
+			out.WriteByte(',')
+		}
+		count++
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	startActions := func() {
+		if !inActions {
+			out.WriteString(`],"actions":[`)
+			count, inActions = 0, true
+		}
+	}
+
+	err = store.Dump(ctx,
+		func(note *models.Note) error { return record(note) },
+		func(action *models.Action) error {
+			startActions()
+			return record(action)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	startActions()
+	out.WriteString("]}\n")
+	return out.Flush()
+}
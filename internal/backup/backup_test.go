@@ -0,0 +1,261 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// memoryDatabase is an empty memory store with its repositories
+type memoryDatabase struct {
+	notes   repository.NoteStore
+	actions repository.ActionStore
+	backups repository.BackupStore
+}
+
+func newMemoryDatabase() *memoryDatabase {
+	store := repository.NewMemoryStore()
+	return &memoryDatabase{
+		notes:   repository.NewMemoryNoteRepository(store),
+		actions: repository.NewMemoryActionRepository(store),
+		backups: repository.NewMemoryBackupRepository(store),
+	}
+}
+
+// seed adds a note for day with an action, returning both
+func (db *memoryDatabase) seed(t *testing.T, day time.Time, content string) (*models.Note, *models.Action) {
+	t.Helper()
+	note, err := db.notes.Create(context.Background(), &models.CreateNoteRequest{Content: content, Date: day})
+	if err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+	action, err := db.actions.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: content + " action"})
+	if err != nil {
+		t.Fatalf("Failed to create test action: %v", err)
+	}
+	return note, action
+}
+
+// dump writes a backup of db and reads it back
+func (db *memoryDatabase) dump(t *testing.T) *Document {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, db.backups); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode backup %s: %v", buf.String(), err)
+	}
+	return &doc
+}
+
+func TestWrite(t *testing.T) {
+	day := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Records", func(t *testing.T) {
+		db := newMemoryDatabase()
+		note, action := db.seed(t, day, "First")
+		db.seed(t, day, "Second")
+
+		doc := db.dump(t)
+		if doc.Format != Format || doc.Version != Version || doc.ExportedAt.IsZero() {
+			t.Errorf("Expected a %s version %d header, got %s version %d at %v", Format, Version, doc.Format, doc.Version, doc.ExportedAt)
+		}
+		if len(doc.Notes) != 2 || len(doc.Actions) != 2 {
+			t.Fatalf("Expected 2 notes and 2 actions, got %d and %d", len(doc.Notes), len(doc.Actions))
+		}
+		if doc.Notes[0].ID != note.ID || !doc.Notes[0].CreatedAt.Equal(note.CreatedAt) {
+			t.Errorf("Expected the first note with its ID and timestamps, got %+v", doc.Notes[0])
+		}
+		if doc.Actions[0].ID != action.ID || doc.Actions[0].NoteID != note.ID {
+			t.Errorf("Expected the first action on its note, got %+v", doc.Actions[0])
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(context.Background(), &buf, newMemoryDatabase().backups); err != nil {
+			t.Fatalf("Failed to write backup: %v", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("Expected valid JSON, got %s: %v", buf.String(), err)
+		}
+		if notes, ok := doc["notes"].([]any); !ok || len(notes) != 0 {
+			t.Errorf("Expected an empty notes array, got %v", doc["notes"])
+		}
+		if actions, ok := doc["actions"].([]any); !ok || len(actions) != 0 {
+			t.Errorf("Expected an empty actions array, got %v", doc["actions"])
+		}
+	})
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+	t.Run("IntoEmptyDatabase", func(t *testing.T) {
+		source := newMemoryDatabase()
+		// Leave a gap in the IDs so they have to be remapped
+		gone, _ := source.seed(t, day, "Deleted")
+		source.notes.Delete(ctx, gone.ID)
+		note, action := source.seed(t, day, "Kept")
+		doc := source.dump(t)
+
+		target := newMemoryDatabase()
+		report, err := Import(ctx, target.backups, doc, ModeMerge)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if report.Notes.Created != 1 || report.Actions.Created != 1 || len(report.Conflicts) != 0 {
+			t.Errorf("Expected 1 note and 1 action created, got %+v", report)
+		}
+
+		restored, err := target.notes.GetByID(ctx, report.NoteIDs[note.ID])
+		if err != nil {
+			t.Fatalf("Failed to get the restored note: %v", err)
+		}
+		if restored.ID == note.ID {
+			t.Errorf("Expected note %d to be given a new ID", note.ID)
+		}
+		if !restored.CreatedAt.Equal(note.CreatedAt) || !restored.UpdatedAt.Equal(note.UpdatedAt) {
+			t.Errorf("Expected the note's timestamps to be kept, got %v and %v", restored.CreatedAt, restored.UpdatedAt)
+		}
+		restoredAction, err := target.actions.GetByID(ctx, report.ActionIDs[action.ID])
+		if err != nil {
+			t.Fatalf("Failed to get the restored action: %v", err)
+		}
+		if restoredAction.NoteID != restored.ID {
+			t.Errorf("Expected the action on note %d, got %d", restored.ID, restoredAction.NoteID)
+		}
+	})
+
+	t.Run("MergeSkipsAndReportsConflicts", func(t *testing.T) {
+		db := newMemoryDatabase()
+		same, _ := db.seed(t, day, "Same")
+		changed, changedAction := db.seed(t, day, "Changed")
+		doc := db.dump(t)
+
+		db.notes.Update(ctx, changed.ID, &models.UpdateNoteRequest{Content: "Edited since"})
+		db.actions.Update(ctx, changedAction.ID, &models.UpdateActionRequest{Completed: true})
+		db.notes.Delete(ctx, same.ID)
+
+		report, err := Import(ctx, db.backups, doc, ModeMerge)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if report.Notes != (Counts{Created: 1, Conflicting: 1}) {
+			t.Errorf("Expected the deleted note restored and the edited one in conflict, got %+v", report.Notes)
+		}
+		if report.Actions != (Counts{Created: 1, Conflicting: 1}) {
+			t.Errorf("Expected the deleted action restored and the completed one in conflict, got %+v", report.Actions)
+		}
+		if len(report.Conflicts) != 2 || report.Conflicts[0] != (Conflict{Type: "note", ID: changed.ID, ExistingID: changed.ID, Reason: "content differs"}) {
+			t.Errorf("Expected the conflicts to be listed, got %+v", report.Conflicts)
+		}
+
+		kept, _ := db.notes.GetByID(ctx, changed.ID)
+		if kept.Content != "Edited since" {
+			t.Errorf("Expected the stored content to be kept, got %q", kept.Content)
+		}
+
+		report, err = Import(ctx, db.backups, doc, ModeMerge)
+		if err != nil {
+			t.Fatalf("Failed to import again: %v", err)
+		}
+		if report.Notes.Created != 0 || report.Notes.Unchanged != 1 {
+			t.Errorf("Expected a second import to create nothing, got %+v", report.Notes)
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		source := newMemoryDatabase()
+		source.seed(t, day, "From backup")
+		doc := source.dump(t)
+
+		target := newMemoryDatabase()
+		stale, _ := target.seed(t, day, "Stale")
+		target.seed(t, day, "Also stale")
+
+		report, err := Import(ctx, target.backups, doc, ModeReplace)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if report.Notes != (Counts{Created: 1, Deleted: 2}) || report.Actions != (Counts{Created: 1, Deleted: 2}) {
+			t.Errorf("Expected 2 records of each deleted and 1 created, got %+v and %+v", report.Notes, report.Actions)
+		}
+		if _, err := target.notes.GetByID(ctx, stale.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected the stale note to be deleted, got %v", err)
+		}
+		notes, _ := target.notes.GetByDate(ctx, day)
+		if len(notes) != 1 || notes[0].Content != "From backup" {
+			t.Errorf("Expected only the note from the backup, got %v", notes)
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		source := newMemoryDatabase()
+		source.seed(t, day, "Not written")
+		doc := source.dump(t)
+
+		target := newMemoryDatabase()
+		report, err := Import(ctx, target.backups, doc, ModeDryRun)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if report.Mode != ModeDryRun || report.Notes.Created != 1 || report.Actions.Created != 1 {
+			t.Errorf("Expected the merge to be reported, got %+v", report)
+		}
+		notes, _ := target.notes.GetByDate(ctx, day)
+		if len(notes) != 0 {
+			t.Errorf("Expected nothing to be written, got %v", notes)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		db := newMemoryDatabase()
+		doc := &Document{
+			Format:  Format,
+			Version: 2,
+			Notes: []*models.Note{
+				{ID: 1, Content: "Fine", Date: day, CreatedAt: day},
+				{ID: 1, Date: day, CreatedAt: day},
+			},
+			Actions: []*models.Action{{ID: 1, NoteID: 7, Description: "Orphan", CreatedAt: day}},
+		}
+
+		_, err := Import(ctx, db.backups, doc, ModeReplace)
+		var invalid *InvalidError
+		if !errors.As(err, &invalid) {
+			t.Fatalf("Expected an InvalidError, got %v", err)
+		}
+		fields := make(map[string]string)
+		for _, field := range invalid.Fields {
+			fields[field.Field] = field.Rule
+		}
+		expected := map[string]string{
+			"version":            "version",
+			"notes[1].id":        "unique",
+			"notes[1].content":   "required",
+			"actions[0].note_id": "reference",
+		}
+		for field, rule := range expected {
+			if fields[field] != rule {
+				t.Errorf("Expected %s to break the %s rule, got %v", field, rule, invalid.Fields)
+			}
+		}
+	})
+
+	t.Run("UnknownMode", func(t *testing.T) {
+		if _, err := Import(ctx, newMemoryDatabase().backups, &Document{Format: Format, Version: Version}, "overwrite"); err == nil {
+			t.Error("Expected an error for an unknown mode")
+		}
+	})
+}
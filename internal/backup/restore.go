@@ -0,0 +1,302 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// Mode is how Import treats the records already in the database
+type Mode string
+
+const (
+	// ModeMerge adds the records the database lacks, leaving the ones it
+	// has untouched
+	ModeMerge Mode = "merge"
+	// ModeReplace deletes every record before restoring the backup
+	ModeReplace Mode = "replace"
+	// ModeDryRun reports what a merge would do without writing anything
+	ModeDryRun Mode = "dry-run"
+)
+
+// Modes lists every Mode, in the order they are documented
+var Modes = []Mode{ModeMerge, ModeReplace, ModeDryRun}
+
+// Valid reports whether m is one of Modes
+func (m Mode) Valid() bool {
+	for _, mode := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Report says what an import did or, in a dry run, would have done
+type Report struct {
+	Mode      Mode       `json:"mode"`
+	Notes     Counts     `json:"notes"`
+	Actions   Counts     `json:"actions"`
+	Conflicts []Conflict `json:"conflicts"`
+	// NoteIDs maps the ID of each note in the backup to the ID of the note
+	// holding it in the database; ActionIDs does the same for actions
+	NoteIDs   map[int64]int64 `json:"note_ids"`
+	ActionIDs map[int64]int64 `json:"action_ids"`
+}
+
+// Counts tallies what happened to one kind of record
+type Counts struct {
+	// Created records were added from the backup
+	Created int `json:"created"`
+	// Unchanged records were already in the database as in the backup
+	Unchanged int `json:"unchanged"`
+	// Conflicting records were in the database with other content, which
+	// was kept
+	Conflicting int `json:"conflicting"`
+	// Deleted records were removed to replace them with the backup
+	Deleted int `json:"deleted"`
+}
+
+// Conflict is a record of the backup that a merge found in the database
+// with different content. The database's version is kept.
+type Conflict struct {
+	// Type is "note" or "action"
+	Type       string `json:"type"`
+	ID         int64  `json:"id"`
+	ExistingID int64  `json:"existing_id"`
+	Reason     string `json:"reason"`
+}
+
+// FieldError is one invalid value in a backup, named by its path in the
+// document, such as notes[3].content
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// InvalidError is returned by Import for a backup that cannot be restored.
+// Nothing is written when it is.
+type InvalidError struct {
+	Fields []FieldError
+}
+
+func (e *InvalidError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return "invalid backup: " + strings.Join(messages, "; ")
+}
+
+// errDryRun rolls back a dry run once it has been reported
+var errDryRun = errors.New("dry run")
+
+// Import restores doc into store in a single transaction: either every
+// record is written or none is. Records already in the database are
+// recognized by their creation time, their note's date and, for actions,
+// their note, since IDs are assigned anew by each database.
+func Import(ctx context.Context, store repository.BackupStore, doc *Document, mode Mode) (*Report, error) {
+	if !mode.Valid() {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+	if err := Validate(doc); err != nil {
+		return nil, err
+	}
+
+	var report *Report
+	err := store.Restore(ctx, func(tx repository.RestoreTx) error {
+		report = &Report{
+			Mode:      mode,
+			Conflicts: []Conflict{},
+			NoteIDs:   make(map[int64]int64, len(doc.Notes)),
+			ActionIDs: make(map[int64]int64, len(doc.Actions)),
+		}
+		if mode == ModeReplace {
+			var err error
+			if report.Notes.Deleted, report.Actions.Deleted, err = tx.Clear(ctx); err != nil {
+				return err
+			}
+		}
+		// A replaced database is empty, so there is nothing to match
+		match := mode != ModeReplace
+		for _, note := range doc.Notes {
+			if err := restoreNote(ctx, tx, report, note, match); err != nil {
+				return err
+			}
+		}
+		for _, action := range doc.Actions {
+			if err := restoreAction(ctx, tx, report, action, match); err != nil {
+				return err
+			}
+		}
+		if mode == ModeDryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return report, nil
+}
+
+func restoreNote(ctx context.Context, tx repository.RestoreTx, report *Report, note *models.Note, match bool) error {
+	restored := *note
+	restored.Date = time.Date(note.Date.Year(), note.Date.Month(), note.Date.Day(), 0, 0, 0, 0, time.UTC)
+	restored.CreatedAt = stored(note.CreatedAt)
+	restored.UpdatedAt = stored(note.UpdatedAt)
+	if restored.UpdatedAt.IsZero() {
+		restored.UpdatedAt = restored.CreatedAt
+	}
+
+	var existing *models.Note
+	err := repository.ErrNotFound
+	if match {
+		existing, err = tx.FindNote(ctx, restored.Date, restored.CreatedAt)
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		id, err := tx.InsertNote(ctx, &restored)
+		if err != nil {
+			return err
+		}
+		report.NoteIDs[note.ID] = id
+		report.Notes.Created++
+	case err != nil:
+		return err
+	case existing.Content == restored.Content:
+		report.NoteIDs[note.ID] = existing.ID
+		report.Notes.Unchanged++
+	default:
+		// Its actions still belong to the existing note
+		report.NoteIDs[note.ID] = existing.ID
+		report.Notes.Conflicting++
+		report.Conflicts = append(report.Conflicts, Conflict{Type: "note", ID: note.ID, ExistingID: existing.ID, Reason: "content differs"})
+	}
+	return nil
+}
+
+func restoreAction(ctx context.Context, tx repository.RestoreTx, report *Report, action *models.Action, match bool) error {
+	restored := *action
+	restored.NoteID = report.NoteIDs[action.NoteID]
+	restored.CreatedAt = stored(action.CreatedAt)
+	restored.UpdatedAt = stored(action.UpdatedAt)
+	if restored.UpdatedAt.IsZero() {
+		restored.UpdatedAt = restored.CreatedAt
+	}
+
+	var existing *models.Action
+	err := repository.ErrNotFound
+	if match {
+		existing, err = tx.FindAction(ctx, restored.NoteID, restored.CreatedAt)
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		id, err := tx.InsertAction(ctx, &restored)
+		if err != nil {
+			return err
+		}
+		report.ActionIDs[action.ID] = id
+		report.Actions.Created++
+	case err != nil:
+		return err
+	case existing.Description == restored.Description && existing.Completed == restored.Completed:
+		report.ActionIDs[action.ID] = existing.ID
+		report.Actions.Unchanged++
+	default:
+		report.ActionIDs[action.ID] = existing.ID
+		report.Actions.Conflicting++
+		reason := "description differs"
+		if existing.Description == restored.Description {
+			reason = "completed differs"
+		}
+		report.Conflicts = append(report.Conflicts, Conflict{Type: "action", ID: action.ID, ExistingID: existing.ID, Reason: reason})
+	}
+	return nil
+}
+
+// stored returns t at the precision the stores keep timestamps, so records
+// can be found again by it
+func stored(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// Validate checks that doc is a backup Import can restore, returning an
+// *InvalidError listing every problem found
+func Validate(doc *Document) error {
+	var fields []FieldError
+	invalid := func(field, rule, message string) {
+		fields = append(fields, FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	if doc.Format != Format {
+		invalid("format", "format", fmt.Sprintf("must be %q", Format))
+	}
+	if doc.Version != Version {
+		invalid("version", "version", fmt.Sprintf("must be %d, got %d", Version, doc.Version))
+	}
+
+	notes := make(map[int64]bool, len(doc.Notes))
+	for i, note := range doc.Notes {
+		path := fmt.Sprintf("notes[%d]", i)
+		if note == nil {
+			invalid(path, "required", "must be a note")
+			continue
+		}
+		switch {
+		case note.ID <= 0:
+			invalid(path+".id", "required", "must be a positive ID")
+		case notes[note.ID]:
+			invalid(path+".id", "unique", fmt.Sprintf("repeats note %d", note.ID))
+		}
+		notes[note.ID] = true
+		if note.Content == "" {
+			invalid(path+".content", "required", "is required")
+		} else if utf8.RuneCountInString(note.Content) > models.MaxNoteContentLength {
+			invalid(path+".content", "max", fmt.Sprintf("must be at most %d characters", models.MaxNoteContentLength))
+		}
+		if note.Date.IsZero() {
+			invalid(path+".date", "required", "is required")
+		}
+		if note.CreatedAt.IsZero() {
+			invalid(path+".created_at", "required", "is required")
+		}
+	}
+
+	actions := make(map[int64]bool, len(doc.Actions))
+	for i, action := range doc.Actions {
+		path := fmt.Sprintf("actions[%d]", i)
+		if action == nil {
+			invalid(path, "required", "must be an action")
+			continue
+		}
+		switch {
+		case action.ID <= 0:
+			invalid(path+".id", "required", "must be a positive ID")
+		case actions[action.ID]:
+			invalid(path+".id", "unique", fmt.Sprintf("repeats action %d", action.ID))
+		}
+		actions[action.ID] = true
+		if !notes[action.NoteID] {
+			invalid(path+".note_id", "reference", fmt.Sprintf("note %d is not in the backup", action.NoteID))
+		}
+		if action.Description == "" {
+			invalid(path+".description", "required", "is required")
+		}
+		if action.CreatedAt.IsZero() {
+			invalid(path+".created_at", "required", "is required")
+		}
+	}
+
+	if len(fields) > 0 {
+		return &InvalidError{Fields: fields}
+	}
+	return nil
+}
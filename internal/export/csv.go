@@ -0,0 +1,342 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// ActionColumns are the columns of an actions CSV export, in order
+var ActionColumns = []string{"id", "note_id", "note_date", "note_excerpt", "description", "completed", "created_at", "updated_at"}
+
+// excerptLength bounds note excerpts, in characters
+const excerptLength = 80
+
+// LoadActionNotes reads the notes of actions, by ID, in batches of IDs
+func LoadActionNotes(ctx context.Context, notes repository.NoteStore, actions []*models.Action) (map[int64]*models.Note, error) {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, action := range actions {
+		if !seen[action.NoteID] {
+			seen[action.NoteID] = true
+			ids = append(ids, action.NoteID)
+		}
+	}
+
+	byID := make(map[int64]*models.Note, len(ids))
+	for start := 0; start < len(ids); start += noteBatch {
+		found, err := notes.GetByIDs(ctx, ids[start:min(start+noteBatch, len(ids))])
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range found {
+			byID[note.ID] = note
+		}
+	}
+	return byID, nil
+}
+
+// WriteActionsCSV writes actions as CSV under a header of ActionColumns,
+// with the date and an excerpt of each action's note from notes. Cells that
+// spreadsheets would run as formulas are prefixed with a quote, which
+// ReadActionsCSV removes.
+func WriteActionsCSV(w io.Writer, actions []*models.Action, notes map[int64]*models.Note) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(ActionColumns); err != nil {
+		return err
+	}
+	for _, action := range actions {
+		var date, excerpt string
+		if note, ok := notes[action.NoteID]; ok {
+			date, excerpt = note.Date.Format(dateLayout), noteExcerpt(note.Content)
+		}
+		err := out.Write([]string{
+			strconv.FormatInt(action.ID, 10),
+			strconv.FormatInt(action.NoteID, 10),
+			date,
+			escapeCell(excerpt),
+			escapeCell(action.Description),
+			strconv.FormatBool(action.Completed),
+			action.CreatedAt.UTC().Format(time.RFC3339Nano),
+			action.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// noteExcerpt is the start of content on a single line
+func noteExcerpt(content string) string {
+	excerpt := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(excerpt) <= excerptLength {
+		return excerpt
+	}
+	return string([]rune(excerpt)[:excerptLength-1]) + "…"
+}
+
+// formulaPrefixes start cells spreadsheets evaluate
+const formulaPrefixes = "=+-@\t\r"
+
+func escapeCell(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// ActionImport is what reading an actions CSV found: the rows to create and
+// the problems with the others
+type ActionImport struct {
+	// Preview is set when nothing was meant to be written
+	Preview bool        `json:"preview"`
+	Rows    []ImportRow `json:"rows"`
+	Errors  []RowError  `json:"errors"`
+	// Created counts the actions written, which is none unless every row
+	// is valid
+	Created int `json:"created"`
+	// Actions are the actions written, in row order
+	Actions []*models.Action `json:"-"`
+}
+
+// ImportRow is a valid row, matched to a note
+type ImportRow struct {
+	// Line is the row's line in the file, the header being line 1
+	Line        int    `json:"line"`
+	NoteID      int64  `json:"note_id"`
+	NoteDate    string `json:"note_date"`
+	Description string `json:"description"`
+	Completed   bool   `json:"completed"`
+	// ActionID is set once the action is created
+	ActionID int64 `json:"action_id,omitempty"`
+}
+
+// RowError is a problem with one cell of a row, or the whole row when
+// Column is empty
+type RowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// CSVError is a file that cannot be read as an actions CSV at all
+type CSVError struct {
+	Line    int
+	Message string
+}
+
+func (e *CSVError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// ImportActionsCSV reads actions from a CSV with note_date and description
+// columns, and optionally completed and note_id, as written by
+// WriteActionsCSV; other columns are ignored. Each row's note is the one
+// dated note_date, or the one note_id picks when the date has several.
+// The actions are created in a single transaction, and only when preview is
+// unset and every row is valid.
+func ImportActionsCSV(ctx context.Context, r io.Reader, notes repository.NoteStore, actions repository.ActionStore, preview bool) (*ActionImport, error) {
+	result, err := readActionsCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	result.Preview = preview
+	if err := matchNotes(ctx, notes, result); err != nil {
+		return nil, err
+	}
+	if preview || len(result.Errors) > 0 || len(result.Rows) == 0 {
+		return result, nil
+	}
+
+	pending := make([]*models.Action, len(result.Rows))
+	for i, row := range result.Rows {
+		pending[i] = &models.Action{NoteID: row.NoteID, Description: row.Description, Completed: row.Completed}
+	}
+	created, err := actions.CreateMany(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	for i, action := range created {
+		result.Rows[i].ActionID = action.ID
+	}
+	result.Actions = created
+	result.Created = len(created)
+	return result, nil
+}
+
+// readActionsCSV parses and checks the rows, leaving NoteID unset
+func readActionsCSV(r io.Reader) (*ActionImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &CSVError{Message: "missing header row"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets may start the file with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			return nil, &CSVError{Line: 1, Message: fmt.Sprintf("column %s appears twice", name)}
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"note_date", "description"} {
+		if _, ok := columns[required]; !ok {
+			return nil, &CSVError{Line: 1, Message: "missing " + required + " column"}
+		}
+	}
+
+	result := &ActionImport{Rows: []ImportRow{}, Errors: []RowError{}}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) != len(header) {
+			result.Errors = append(result.Errors, RowError{Line: line, Rule: "columns", Message: fmt.Sprintf("has %d columns, the header has %d", len(record), len(header))})
+			continue
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		valid := true
+		invalid := func(column, rule, message string) {
+			result.Errors = append(result.Errors, RowError{Line: line, Column: column, Rule: rule, Message: message})
+			valid = false
+		}
+
+		row := ImportRow{Line: line, NoteDate: cell("note_date"), Description: unescapeCell(cell("description"))}
+		if row.NoteDate == "" {
+			invalid("note_date", "required", "is required")
+		} else if _, err := time.Parse(dateLayout, row.NoteDate); err != nil {
+			invalid("note_date", "format", "must be a date in YYYY-MM-DD format")
+		}
+		if row.Description == "" {
+			invalid("description", "required", "is required")
+		}
+		if value := cell("completed"); value != "" {
+			if row.Completed, err = strconv.ParseBool(value); err != nil {
+				invalid("completed", "format", "must be true or false")
+			}
+		}
+		if value := cell("note_id"); value != "" {
+			if row.NoteID, err = strconv.ParseInt(value, 10, 64); err != nil {
+				invalid("note_id", "format", "must be an integer")
+			}
+		}
+		if valid {
+			result.Rows = append(result.Rows, row)
+		}
+	}
+	return result, nil
+}
+
+// matchNotes sets the NoteID of each row to the note it belongs to, moving
+// rows without exactly one such note to the errors. Only the dates the rows
+// name are read, in batches.
+func matchNotes(ctx context.Context, notes repository.NoteStore, result *ActionImport) error {
+	var dates []time.Time
+	seen := make(map[string]bool)
+	for _, row := range result.Rows {
+		if !seen[row.NoteDate] {
+			seen[row.NoteDate] = true
+			date, _ := time.Parse(dateLayout, row.NoteDate)
+			dates = append(dates, date)
+		}
+	}
+
+	var found []*models.Note
+	for start := 0; start < len(dates); start += noteBatch {
+		batch, err := notes.GetByDates(ctx, dates[start:min(start+noteBatch, len(dates))])
+		if err != nil {
+			return err
+		}
+		found = append(found, batch...)
+	}
+	byDate := make(map[string][]*models.Note)
+	for _, note := range found {
+		date := note.Date.Format(dateLayout)
+		byDate[date] = append(byDate[date], note)
+	}
+
+	matched := result.Rows[:0]
+	for _, row := range result.Rows {
+		candidates := byDate[row.NoteDate]
+		switch {
+		case row.NoteID != 0:
+			dated := false
+			for _, note := range candidates {
+				dated = dated || note.ID == row.NoteID
+			}
+			if !dated {
+				result.Errors = append(result.Errors, RowError{Line: row.Line, Column: "note_id", Rule: "reference", Message: fmt.Sprintf("note %d is not dated %s", row.NoteID, row.NoteDate)})
+				continue
+			}
+		case len(candidates) == 0:
+			result.Errors = append(result.Errors, RowError{Line: row.Line, Column: "note_date", Rule: "reference", Message: "no note is dated " + row.NoteDate})
+			continue
+		case len(candidates) > 1:
+			result.Errors = append(result.Errors, RowError{Line: row.Line, Column: "note_id", Rule: "ambiguous", Message: fmt.Sprintf("%d notes are dated %s, set note_id to pick one", len(candidates), row.NoteDate)})
+			continue
+		default:
+			row.NoteID = candidates[0].ID
+		}
+		matched = append(matched, row)
+	}
+	result.Rows = matched
+
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+	return nil
+}
+
+// csvError reports a malformed file, keeping read failures such as an
+// oversized body as they are
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &CSVError{Line: parseErr.Line, Message: parseErr.Err.Error()}
+	}
+	return err
+}
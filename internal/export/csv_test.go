@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+func TestActionsCSV(t *testing.T) {
+	store := repository.NewMemoryStore()
+	notes := repository.NewMemoryNoteRepository(store)
+	actions := repository.NewMemoryActionRepository(store)
+	ctx := context.Background()
+
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	planning, _ := notes.Create(ctx, &models.CreateNoteRequest{Content: "Planning\n\nTalked to Ana about " + strings.Repeat("the roadmap ", 10), Date: day})
+	standup, _ := notes.Create(ctx, &models.CreateNoteRequest{Content: "Standup", Date: day.AddDate(0, 0, 1)})
+	notes.Create(ctx, &models.CreateNoteRequest{Content: "Retro", Date: day.AddDate(0, 0, 1)})
+	call, _ := actions.Create(ctx, &models.CreateActionRequest{NoteID: planning.ID, Description: "=HYPERLINK(\"x\")"})
+
+	t.Run("Write", func(t *testing.T) {
+		found, _ := actions.Find(ctx, models.ActionFilter{})
+		byID, err := LoadActionNotes(ctx, notes, found)
+		if err != nil {
+			t.Fatalf("Failed to load notes: %v", err)
+		}
+
+		var b bytes.Buffer
+		if err := WriteActionsCSV(&b, found, byID); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+		records, err := csv.NewReader(&b).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read CSV back: %v", err)
+		}
+		if len(records) != 2 || strings.Join(records[0], ",") != strings.Join(ActionColumns, ",") {
+			t.Fatalf("Expected a header and a row, got %v", records)
+		}
+		row := records[1]
+		if row[2] != "2026-10-17" {
+			t.Errorf("Expected the note date, got %s", row[2])
+		}
+		if !strings.HasPrefix(row[3], "Planning Talked to Ana") || !strings.HasSuffix(row[3], "…") || len([]rune(row[3])) != excerptLength {
+			t.Errorf("Expected a shortened single line excerpt, got %q", row[3])
+		}
+		if row[4] != `'=HYPERLINK("x")` {
+			t.Errorf("Expected the formula to be escaped, got %s", row[4])
+		}
+	})
+
+	t.Run("Preview", func(t *testing.T) {
+		file := "note_date,description,completed,note_id\n" +
+			"2026-10-17,Send notes,true,\n" +
+			"2026-10-18,Ambiguous,,\n" +
+			"2026-10-18,Picked,," + strconv.FormatInt(standup.ID, 10) + "\n" +
+			"2026-10-19,No note,,\n" +
+			"yesterday,,maybe,\n" +
+			"2026-10-17,'=1+1,false," + strconv.FormatInt(planning.ID, 10) + "\n"
+
+		result, err := ImportActionsCSV(ctx, strings.NewReader(file), notes, actions, true)
+		if err != nil {
+			t.Fatalf("Failed to preview import: %v", err)
+		}
+		if len(result.Rows) != 3 || result.Rows[0].NoteID != planning.ID || !result.Rows[0].Completed {
+			t.Errorf("Expected 3 valid rows matched to notes, got %+v", result.Rows)
+		}
+		if result.Rows[1].NoteID != standup.ID || result.Rows[2].Description != "=1+1" {
+			t.Errorf("Expected note_id to pick the note and the formula to be unescaped, got %+v", result.Rows[1:])
+		}
+
+		expected := []RowError{
+			{Line: 3, Column: "note_id", Rule: "ambiguous"},
+			{Line: 5, Column: "note_date", Rule: "reference"},
+			{Line: 6, Column: "note_date", Rule: "format"},
+			{Line: 6, Column: "description", Rule: "required"},
+			{Line: 6, Column: "completed", Rule: "format"},
+		}
+		if len(result.Errors) != len(expected) {
+			t.Fatalf("Expected %d errors, got %+v", len(expected), result.Errors)
+		}
+		for i, want := range expected {
+			got := result.Errors[i]
+			if got.Line != want.Line || got.Column != want.Column || got.Rule != want.Rule {
+				t.Errorf("Expected error %+v, got %+v", want, got)
+			}
+		}
+
+		if found, _ := actions.Find(ctx, models.ActionFilter{}); len(found) != 1 {
+			t.Errorf("Expected a preview to write nothing, got %d actions", len(found))
+		}
+	})
+
+	t.Run("InvalidRowsWriteNothing", func(t *testing.T) {
+		file := "note_date,description\n2026-10-17,Fine\n2026-10-19,No note\n"
+		result, err := ImportActionsCSV(ctx, strings.NewReader(file), notes, actions, false)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if result.Created != 0 || len(result.Errors) != 1 {
+			t.Errorf("Expected nothing created and 1 error, got %+v", result)
+		}
+		if found, _ := actions.Find(ctx, models.ActionFilter{}); len(found) != 1 {
+			t.Errorf("Expected nothing to be written, got %d actions", len(found))
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		found, _ := actions.Find(ctx, models.ActionFilter{})
+		byID, _ := LoadActionNotes(ctx, notes, found)
+		var b bytes.Buffer
+		WriteActionsCSV(&b, found, byID)
+
+		result, err := ImportActionsCSV(ctx, &b, notes, actions, false)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if result.Created != 1 || len(result.Errors) != 0 {
+			t.Fatalf("Expected the exported action to be created again, got %+v", result)
+		}
+		created, err := actions.GetByID(ctx, result.Rows[0].ActionID)
+		if err != nil {
+			t.Fatalf("Failed to get the created action: %v", err)
+		}
+		if created.NoteID != planning.ID || created.Description != call.Description {
+			t.Errorf("Expected a copy of action %d, got %+v", call.ID, created)
+		}
+	})
+
+	t.Run("UnreadableFile", func(t *testing.T) {
+		tests := []struct {
+			name string
+			file string
+		}{
+			{"empty", ""},
+			{"missing column", "note_date,completed\n2026-10-17,true\n"},
+			{"bad quoting", "note_date,description\n2026-10-17,\"unterminated\n"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := ImportActionsCSV(ctx, strings.NewReader(tt.file), notes, actions, true)
+				var csvErr *CSVError
+				if !errors.As(err, &csvErr) {
+					t.Errorf("Expected a CSVError, got %v", err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,249 @@
+// Package export moves notes and their actions out of the API, and actions
+// back in, in formats meant for archiving and other tools.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"gopkg.in/yaml.v3"
+)
+
+// dateLayout names days in file names and front matter
+const dateLayout = "2006-01-02"
+
+// noteBatch bounds the note IDs or dates sent in one query
+const noteBatch = 500
+
+// Day is one day's notes with their actions, oldest first
+type Day struct {
+	Date  time.Time
+	Notes []DayNote
+}
+
+// DayNote is a note with its actions
+type DayNote struct {
+	*models.Note
+	Actions []*models.Action
+}
+
+// dayWindow is how many days of notes EachDay reads in one query
+const dayWindow = 31
+
+// EachDay reads the notes dated from one day to another, inclusive, and
+// their actions, and calls fn with each day in date order. Days without
+// notes are left out. Notes are read dayWindow days at a time and actions
+// one day at a time, so a long range is never held in memory at once. An
+// error from fn stops the walk and is returned.
+func EachDay(ctx context.Context, notes repository.NoteStore, actions repository.ActionStore, from, to time.Time, fn func(Day) error) error {
+	for start := from; !start.After(to); start = start.AddDate(0, 0, dayWindow) {
+		end := start.AddDate(0, 0, dayWindow-1)
+		if end.After(to) {
+			end = to
+		}
+		loaded, err := notes.GetByDateRange(ctx, start, end)
+		if err != nil {
+			return err
+		}
+
+		// Stores list newest first; archives read better in the order
+		// things were written
+		sort.SliceStable(loaded, func(i, j int) bool {
+			if !loaded[i].Date.Equal(loaded[j].Date) {
+				return loaded[i].Date.Before(loaded[j].Date)
+			}
+			if !loaded[i].CreatedAt.Equal(loaded[j].CreatedAt) {
+				return loaded[i].CreatedAt.Before(loaded[j].CreatedAt)
+			}
+			return loaded[i].ID < loaded[j].ID
+		})
+
+		for len(loaded) > 0 {
+			n := 1
+			for n < len(loaded) && loaded[n].Date.Format(dateLayout) == loaded[0].Date.Format(dateLayout) {
+				n++
+			}
+			day, err := loadDay(ctx, actions, loaded[:n])
+			if err != nil {
+				return err
+			}
+			if err := fn(day); err != nil {
+				return err
+			}
+			loaded = loaded[n:]
+		}
+	}
+	return nil
+}
+
+// loadDay adds their actions to one day's notes, oldest first
+func loadDay(ctx context.Context, actions repository.ActionStore, notes []*models.Note) (Day, error) {
+	byNote := make(map[int64][]*models.Action, len(notes))
+	for start := 0; start < len(notes); start += noteBatch {
+		batch := notes[start:min(start+noteBatch, len(notes))]
+		ids := make([]int64, len(batch))
+		for i, note := range batch {
+			ids[i] = note.ID
+		}
+		found, err := actions.Find(ctx, models.ActionFilter{NoteIDs: ids})
+		if err != nil {
+			return Day{}, err
+		}
+		for _, action := range found {
+			byNote[action.NoteID] = append(byNote[action.NoteID], action)
+		}
+	}
+
+	day := Day{Date: notes[0].Date, Notes: make([]DayNote, 0, len(notes))}
+	for _, note := range notes {
+		noteActions := byNote[note.ID]
+		sort.SliceStable(noteActions, func(i, j int) bool {
+			if !noteActions[i].CreatedAt.Equal(noteActions[j].CreatedAt) {
+				return noteActions[i].CreatedAt.Before(noteActions[j].CreatedAt)
+			}
+			return noteActions[i].ID < noteActions[j].ID
+		})
+		day.Notes = append(day.Notes, DayNote{Note: note, Actions: noteActions})
+	}
+	return day, nil
+}
+
+// FileName is the name of a day's Markdown file, such as 2026-10-18.md
+func FileName(day Day) string {
+	return day.Date.Format(dateLayout) + ".md"
+}
+
+// frontMatter lists the IDs and timestamps the Markdown body leaves out
+type frontMatter struct {
+	Date  string     `yaml:"date"`
+	Notes []noteMeta `yaml:"notes"`
+}
+
+type noteMeta struct {
+	ID        int64        `yaml:"id"`
+	CreatedAt time.Time    `yaml:"created_at"`
+	UpdatedAt time.Time    `yaml:"updated_at"`
+	Actions   []actionMeta `yaml:"actions,omitempty"`
+}
+
+type actionMeta struct {
+	ID        int64     `yaml:"id"`
+	Completed bool      `yaml:"completed"`
+	CreatedAt time.Time `yaml:"created_at"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+}
+
+// WriteMarkdown renders a day as Markdown: YAML front matter with the IDs
+// and timestamps, then a section per note with its actions as a task list
+func WriteMarkdown(w io.Writer, day Day) error {
+	meta := frontMatter{Date: day.Date.Format(dateLayout)}
+	for _, note := range day.Notes {
+		nm := noteMeta{ID: note.ID, CreatedAt: note.CreatedAt.UTC(), UpdatedAt: note.UpdatedAt.UTC()}
+		for _, action := range note.Actions {
+			nm.Actions = append(nm.Actions, actionMeta{
+				ID:        action.ID,
+				Completed: action.Completed,
+				CreatedAt: action.CreatedAt.UTC(),
+				UpdatedAt: action.UpdatedAt.UTC(),
+			})
+		}
+		meta.Notes = append(meta.Notes, nm)
+	}
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(meta); err != nil {
+		return err
+	}
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n", meta.Date)
+	for _, note := range day.Notes {
+		fmt.Fprintf(&b, "\n## Note %d (%s UTC)\n\n", note.ID, note.CreatedAt.UTC().Format("15:04"))
+		if content := strings.TrimSpace(note.Content); content != "" {
+			b.WriteString(content)
+			b.WriteString("\n")
+		}
+		if len(note.Actions) == 0 {
+			continue
+		}
+		b.WriteString("\n### Actions\n\n")
+		for _, action := range note.Actions {
+			box := " "
+			if action.Completed {
+				box = "x"
+			}
+			// A line break would end the list item
+			description := strings.Join(strings.Fields(action.Description), " ")
+			fmt.Fprintf(&b, "- [%s] %s\n", box, description)
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// modified is when a day last changed, so unchanged days keep their file
+// times from one export to the next
+func modified(day Day) time.Time {
+	var latest time.Time
+	for _, note := range day.Notes {
+		if note.UpdatedAt.After(latest) {
+			latest = note.UpdatedAt
+		}
+		for _, action := range note.Actions {
+			if action.UpdatedAt.After(latest) {
+				latest = action.UpdatedAt
+			}
+		}
+	}
+	return latest
+}
+
+// ZipWriter streams a zip archive with a Markdown file per day
+type ZipWriter struct {
+	archive *zip.Writer
+}
+
+// NewZipWriter starts an archive written to w
+func NewZipWriter(w io.Writer) *ZipWriter {
+	return &ZipWriter{archive: zip.NewWriter(w)}
+}
+
+// Add writes a day's file to the archive
+func (z *ZipWriter) Add(day Day) error {
+	file, err := z.archive.CreateHeader(&zip.FileHeader{
+		Name:     FileName(day),
+		Method:   zip.Deflate,
+		Modified: modified(day),
+	})
+	if err != nil {
+		return err
+	}
+	return WriteMarkdown(file, day)
+}
+
+// Close finishes the archive
+func (z *ZipWriter) Close() error {
+	return z.archive.Close()
+}
+
+// WriteFile writes a day's Markdown file into dir, replacing the file of an
+// earlier export
+func WriteFile(dir string, day Day) error {
+	var b bytes.Buffer
+	if err := WriteMarkdown(&b, day); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName(day)), b.Bytes(), 0o644)
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+func TestMarkdown(t *testing.T) {
+	store := repository.NewMemoryStore()
+	notes := repository.NewMemoryNoteRepository(store)
+	actions := repository.NewMemoryActionRepository(store)
+	ctx := context.Background()
+
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	first, _ := notes.Create(ctx, &models.CreateNoteRequest{Content: "Planning\n\nTalked to Ana.", Date: day})
+	notes.Create(ctx, &models.CreateNoteRequest{Content: "Standup", Date: day.AddDate(0, 0, 1)})
+	notes.Create(ctx, &models.CreateNoteRequest{Content: "Out of range", Date: day.AddDate(0, 0, 3)})
+	open, _ := actions.Create(ctx, &models.CreateActionRequest{NoteID: first.ID, Description: "Call\nAna"})
+	done, _ := actions.Create(ctx, &models.CreateActionRequest{NoteID: first.ID, Description: "Book flights"})
+	actions.Update(ctx, done.ID, &models.UpdateActionRequest{Completed: true})
+
+	load := func(from, to time.Time) []Day {
+		t.Helper()
+		var days []Day
+		err := EachDay(ctx, notes, actions, from, to, func(d Day) error {
+			days = append(days, d)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to load days: %v", err)
+		}
+		return days
+	}
+	days := load(day, day.AddDate(0, 0, 2))
+
+	t.Run("EachDay", func(t *testing.T) {
+		if len(days) != 2 || FileName(days[0]) != "2026-10-17.md" || FileName(days[1]) != "2026-10-18.md" {
+			t.Fatalf("Expected the 2 days with notes in date order, got %d", len(days))
+		}
+		if got := days[0].Notes[0].Actions; len(got) != 2 || got[0].ID != open.ID {
+			t.Errorf("Expected the note's actions oldest first, got %v", got)
+		}
+	})
+
+	t.Run("EachDayAcrossWindows", func(t *testing.T) {
+		later := day.AddDate(0, 0, dayWindow+5)
+		notes.Create(ctx, &models.CreateNoteRequest{Content: "Next month", Date: later})
+
+		got := load(day, later)
+		if len(got) != 4 || FileName(got[3]) != FileName(Day{Date: later}) {
+			t.Fatalf("Expected the 4 days with notes across windows, got %d", len(got))
+		}
+		for i := 1; i < len(got); i++ {
+			if !got[i-1].Date.Before(got[i].Date) {
+				t.Errorf("Expected days in date order, got %s before %s", FileName(got[i-1]), FileName(got[i]))
+			}
+		}
+	})
+
+	t.Run("EachDayStops", func(t *testing.T) {
+		stop := errors.New("disk full")
+		calls := 0
+		err := EachDay(ctx, notes, actions, day, day.AddDate(0, 0, 2), func(Day) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("Expected the walk to stop at the first error, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("WriteMarkdown", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := WriteMarkdown(&b, days[0]); err != nil {
+			t.Fatalf("Failed to write markdown: %v", err)
+		}
+		md := b.String()
+		for _, want := range []string{
+			"---\ndate: \"2026-10-17\"\nnotes:\n  - id: 1\n",
+			"\n---\n\n# 2026-10-17\n",
+			"## Note 1 (",
+			"Planning\n\nTalked to Ana.\n",
+			"- [ ] Call Ana\n- [x] Book flights\n",
+		} {
+			if !strings.Contains(md, want) {
+				t.Errorf("Expected the markdown to contain %q, got:\n%s", want, md)
+			}
+		}
+	})
+
+	t.Run("ZipWriter", func(t *testing.T) {
+		var b bytes.Buffer
+		writer := NewZipWriter(&b)
+		for _, d := range days {
+			if err := writer.Add(d); err != nil {
+				t.Fatalf("Failed to add %s: %v", FileName(d), err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to write zip: %v", err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+		if err != nil {
+			t.Fatalf("Failed to read zip: %v", err)
+		}
+		if len(archive.File) != 2 || archive.File[1].Name != "2026-10-18.md" {
+			t.Fatalf("Expected a file per day, got %d", len(archive.File))
+		}
+		f, _ := archive.File[1].Open()
+		content, _ := io.ReadAll(f)
+		if !strings.Contains(string(content), "Standup") {
+			t.Errorf("Expected the day's note in its file, got:\n%s", content)
+		}
+	})
+
+	t.Run("WriteFile", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, d := range days {
+			if err := WriteFile(dir, d); err != nil {
+				t.Fatalf("Failed to write %s: %v", FileName(d), err)
+			}
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 2 {
+			t.Errorf("Expected 2 files, got %d", len(entries))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+)
+
+// Error is a failed field. Its code, one of the codes REST problems use,
+// and any invalid input fields are reported in the error's extensions.
+type Error struct {
+	Code    string
+	Message string
+	Fields  []handlers.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions is added to the GraphQL error by the executor
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		extensions["fields"] = e.Fields
+	}
+	return extensions
+}
+
+// repositoryError maps an error from a store to the error for it. Database
+// details are logged rather than returned.
+func repositoryError(ctx context.Context, err error, resource string) error {
+	failure := handlers.ClassifyStoreError(err, resource)
+	if failure.Code == handlers.CodeDatabaseError {
+		logging.FromContext(ctx).Error("graphql repository error", "resource", resource, "error", err)
+	}
+	return &Error{Code: failure.Code, Message: failure.Message}
+}
+
+// validate applies the binding rules the REST handlers use to an input
+func validate(input interface{}) error {
+	err := binding.Validator.ValidateStruct(input)
+	fields, ok := handlers.ValidationFields(err, func(fe validator.FieldError) string {
+		return fieldName(fe.StructField())
+	})
+	if !ok {
+		return err
+	}
+	return &Error{Code: handlers.CodeValidation, Message: "input has invalid fields", Fields: fields}
+}
+
+// fieldName turns a Go field name such as NoteID into its schema name, noteId
+func fieldName(name string) string {
+	if base, ok := strings.CutSuffix(name, "ID"); ok {
+		name = base + "Id"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// parseID reads a record ID argument
+func parseID(name string, id string) (int64, error) {
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, &Error{
+			Code:    handlers.CodeInvalidParameter,
+			Message: "invalid " + name,
+			Fields:  []handlers.FieldError{{Field: name, Rule: "format", Message: "must be an integer"}},
+		}
+	}
+	return parsed, nil
+}
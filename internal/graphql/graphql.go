@@ -0,0 +1,118 @@
+// Package graphql serves the notes and actions as a GraphQL API: queries and
+// mutations over HTTP POST, and subscriptions fed by the WebSocket hub over
+// the graphql-transport-ws protocol.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+// Handler serves the GraphQL endpoint
+type Handler struct {
+	schema  *gql.Schema
+	actions repository.ActionStore
+	limits  limits
+
+	// mu guards the open WebSocket sessions; work counts them and their
+	// running subscriptions so Shutdown can wait for them
+	mu       sync.Mutex
+	stopping bool
+	sessions map[*session]struct{}
+	work     sync.WaitGroup
+}
+
+// Options configures a Handler
+type Options struct {
+	// Limiter, when set, charges every mutation field and every operation
+	// sent over a WebSocket to the client's budget in RateLimit
+	Limiter   *ratelimit.Limiter
+	RateLimit ratelimit.Policy
+}
+
+// NewHandler creates a GraphQL handler resolving fields from the stores.
+// Note and action mutations are broadcast through hub, which also feeds
+// subscriptions.
+func NewHandler(notes repository.NoteStore, actions repository.ActionStore, hub Hub, opts Options) *Handler {
+	l := limits{limiter: opts.Limiter, policy: opts.RateLimit}
+	schema := gql.MustParseSchema(schemaSource, &resolver{notes: notes, actions: actions, hub: hub, limits: l},
+		gql.UseStringDescriptions(),
+	)
+	return &Handler{schema: schema, actions: actions, limits: l, sessions: make(map[*session]struct{})}
+}
+
+// Shutdown closes the open WebSocket connections, refuses new ones and waits
+// for their operations to finish or ctx to be done. Queries and mutations
+// over POST are left to the HTTP server's own shutdown.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.stopping = true
+	for s := range h.sessions {
+		s.close(websocket.CloseGoingAway, "Server is shutting down")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.work.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// request is a GraphQL operation, as sent in a POST body or a subscribe message
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query runs the query or mutation in the request body. Failed fields are
+// reported in the response's errors with 200 OK; only a body that is not an
+// operation gets an error status.
+func (h *Handler) Query(c *gin.Context) {
+	var req request
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			requestFailed(c, http.StatusRequestEntityTooLarge, handlers.CodeBodyTooLarge, "request body is too large")
+			return
+		}
+		requestFailed(c, http.StatusBadRequest, handlers.CodeInvalidJSON, "request body is not valid JSON")
+		return
+	}
+	if req.Query == "" {
+		requestFailed(c, http.StatusBadRequest, handlers.CodeValidation, "query is required")
+		return
+	}
+
+	ctx := withClient(c.Request.Context(), ratelimit.ClientKey(c.ClientIP()))
+	ctx = withLoader(ctx, newActionLoader(h.actions))
+	c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// requestFailed answers a request that could not be executed at all
+func requestFailed(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"errors": []gin.H{{"message": message, "extensions": gin.H{"code": code}}},
+	})
+}
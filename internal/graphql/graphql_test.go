@@ -0,0 +1,389 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+)
+
+// countingActionStore counts the queries behind Note.actions
+type countingActionStore struct {
+	repository.ActionStore
+	finds atomic.Int32
+}
+
+func (s *countingActionStore) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	s.finds.Add(1)
+	return s.ActionStore.Find(ctx, filter)
+}
+
+// recordingHub remembers broadcasts and has no subscribers
+type recordingHub struct {
+	created []int64
+	deleted []int64
+	// notes lists note broadcasts as type and note ID
+	notes []string
+}
+
+func (h *recordingHub) BroadcastNoteCreated(ctx context.Context, note *models.Note) {
+	h.notes = append(h.notes, fmt.Sprintf("%s %d", websocket.NoteCreated, note.ID))
+}
+func (h *recordingHub) BroadcastNoteUpdated(ctx context.Context, note *models.Note) {
+	h.notes = append(h.notes, fmt.Sprintf("%s %d", websocket.NoteUpdated, note.ID))
+}
+func (h *recordingHub) BroadcastNoteDeleted(ctx context.Context, noteID int64) {
+	h.notes = append(h.notes, fmt.Sprintf("%s %d", websocket.NoteDeleted, noteID))
+}
+
+func (h *recordingHub) BroadcastActionCreated(ctx context.Context, action *models.Action) {
+	h.created = append(h.created, action.ID)
+}
+func (h *recordingHub) BroadcastActionUpdated(ctx context.Context, action *models.Action) {}
+func (h *recordingHub) BroadcastActionDeleted(ctx context.Context, actionID int64) {
+	h.deleted = append(h.deleted, actionID)
+}
+func (h *recordingHub) Subscribe(ctx context.Context) (<-chan websocket.Event, error) {
+	return nil, websocket.ErrHubStopped
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code   string                `json:"code"`
+			Fields []handlers.FieldError `json:"fields"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type fixture struct {
+	router  *gin.Engine
+	notes   repository.NoteStore
+	actions *countingActionStore
+	hub     *recordingHub
+}
+
+func setup(t *testing.T) *fixture {
+	return setupWith(t, Options{})
+}
+
+func setupWith(t *testing.T, opts Options) *fixture {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	f := &fixture{
+		notes:   repository.NewMemoryNoteRepository(store),
+		actions: &countingActionStore{ActionStore: repository.NewMemoryActionRepository(store)},
+		hub:     &recordingHub{},
+	}
+	f.router = gin.New()
+	f.router.POST("/graphql", NewHandler(f.notes, f.actions, f.hub, opts).Query)
+	return f
+}
+
+func (f *fixture) exec(t *testing.T, query string, variables map[string]interface{}, data interface{}) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if data != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+	}
+	return resp
+}
+
+func (f *fixture) createNote(t *testing.T, date time.Time, actions ...string) *models.Note {
+	t.Helper()
+	note, err := f.notes.Create(context.Background(), &models.CreateNoteRequest{Content: "Note", Date: date})
+	if err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+	for _, description := range actions {
+		if _, err := f.actions.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: description}); err != nil {
+			t.Fatalf("Failed to create test action: %v", err)
+		}
+	}
+	return note
+}
+
+func TestQueries(t *testing.T) {
+	t.Run("NotesWithActions", func(t *testing.T) {
+		f := setup(t)
+		day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+		first := f.createNote(t, day, "Call Ana", "Book flights")
+		second := f.createNote(t, day.AddDate(0, 0, 1), "Review PR")
+		f.createNote(t, day)
+		f.createNote(t, day.AddDate(0, 0, 2), "Out of range")
+
+		var data struct {
+			Notes []struct {
+				ID      string
+				Date    string
+				Actions []struct{ Description string }
+			}
+		}
+		resp := f.exec(t, `query($from: Date!, $to: Date!) {
+			notes(from: $from, to: $to) { id date actions { description } }
+		}`, map[string]interface{}{"from": "2026-10-17", "to": "2026-10-18"}, &data)
+
+		if len(resp.Errors) > 0 {
+			t.Fatalf("Expected no errors, got %+v", resp.Errors)
+		}
+		if len(data.Notes) != 3 {
+			t.Fatalf("Expected 3 notes, got %d", len(data.Notes))
+		}
+		if data.Notes[0].ID != strconv.FormatInt(second.ID, 10) || data.Notes[0].Date != "2026-10-18" {
+			t.Errorf("Expected the latest day first, got %+v", data.Notes[0])
+		}
+		for _, note := range data.Notes {
+			if note.ID == strconv.FormatInt(first.ID, 10) && len(note.Actions) != 2 {
+				t.Errorf("Expected the first note's 2 actions, got %+v", note.Actions)
+			}
+		}
+		if got := f.actions.finds.Load(); got != 1 {
+			t.Errorf("Expected the actions of every note to be loaded in 1 query, got %d", got)
+		}
+	})
+
+	t.Run("Note", func(t *testing.T) {
+		f := setup(t)
+		note := f.createNote(t, time.Now(), "Only one")
+
+		var data struct {
+			Note *struct {
+				Content string
+				Actions []struct{ Description string }
+			}
+			Missing *struct{ ID string }
+		}
+		resp := f.exec(t, `query($id: ID!) {
+			note(id: $id) { content actions { description } }
+			missing: note(id: "999") { id }
+		}`, map[string]interface{}{"id": strconv.FormatInt(note.ID, 10)}, &data)
+
+		if len(resp.Errors) > 0 {
+			t.Fatalf("Expected no errors, got %+v", resp.Errors)
+		}
+		if data.Note == nil || len(data.Note.Actions) != 1 {
+			t.Errorf("Expected the note and its action, got %+v", data.Note)
+		}
+		if data.Missing != nil {
+			t.Errorf("Expected null for a missing note, got %+v", data.Missing)
+		}
+	})
+
+	t.Run("ActionsFilter", func(t *testing.T) {
+		f := setup(t)
+		note := f.createNote(t, time.Now(), "Open", "Done")
+		other := f.createNote(t, time.Now(), "Elsewhere")
+		actions, _ := f.actions.GetByNoteID(context.Background(), note.ID)
+		f.actions.Update(context.Background(), actions[0].ID, &models.UpdateActionRequest{Completed: true})
+
+		var data struct {
+			Actions []struct {
+				Description string
+				NoteID      string
+			}
+		}
+		f.exec(t, `query($notes: [ID!]) {
+			actions(filter: {noteIds: $notes, completed: false}) { description noteId }
+		}`, map[string]interface{}{"notes": []string{strconv.FormatInt(note.ID, 10), strconv.FormatInt(other.ID, 10)}}, &data)
+
+		if len(data.Actions) != 2 {
+			t.Fatalf("Expected the 2 open actions, got %+v", data.Actions)
+		}
+		for _, action := range data.Actions {
+			if action.Description == "Done" {
+				t.Errorf("Expected completed actions to be left out, got %+v", action)
+			}
+		}
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		f := setup(t)
+		resp := f.exec(t, `{ notes(from: "2026-10-18", to: "2026-10-17") { id } }`, nil, nil)
+		if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != handlers.CodeInvalidParameter {
+			t.Errorf("Expected an %s error, got %+v", handlers.CodeInvalidParameter, resp.Errors)
+		}
+	})
+}
+
+func TestActionLoader(t *testing.T) {
+	f := setup(t)
+	loader := newActionLoader(f.actions)
+	ids := make([]int64, noteBatch+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	loader.prime(ids)
+
+	if _, err := loader.load(context.Background(), 1); err != nil {
+		t.Fatalf("Failed to load actions: %v", err)
+	}
+	if got := f.actions.finds.Load(); got != 2 {
+		t.Errorf("Expected %d notes to be loaded in 2 queries, got %d", len(ids), got)
+	}
+	loader.load(context.Background(), int64(len(ids)))
+	if got := f.actions.finds.Load(); got != 2 {
+		t.Errorf("Expected the rest of the batch not to query again, got %d queries", got)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	t.Run("CreateAndDelete", func(t *testing.T) {
+		f := setup(t)
+
+		var created struct {
+			CreateNote struct {
+				ID      string
+				Date    string
+				Actions []struct{ ID string }
+			}
+		}
+		f.exec(t, `mutation { createNote(input: {content: "Plan", date: "2026-10-18"}) { id date actions { id } } }`, nil, &created)
+		if created.CreateNote.Date != "2026-10-18" || len(created.CreateNote.Actions) != 0 {
+			t.Fatalf("Expected a new note without actions, got %+v", created.CreateNote)
+		}
+
+		var action struct {
+			CreateAction struct {
+				ID        string
+				Completed bool
+			}
+		}
+		resp := f.exec(t, `mutation($note: ID!) { createAction(input: {noteId: $note, description: "Act"}) { id completed } }`,
+			map[string]interface{}{"note": created.CreateNote.ID}, &action)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("Expected no errors, got %+v", resp.Errors)
+		}
+		if len(f.hub.created) != 1 || strconv.FormatInt(f.hub.created[0], 10) != action.CreateAction.ID {
+			t.Errorf("Expected the created action to be broadcast, got %v", f.hub.created)
+		}
+
+		var deleted struct{ DeleteAction string }
+		f.exec(t, `mutation($id: ID!) { deleteAction(id: $id) }`, map[string]interface{}{"id": action.CreateAction.ID}, &deleted)
+		if deleted.DeleteAction != action.CreateAction.ID || len(f.hub.deleted) != 1 {
+			t.Errorf("Expected the deletion to be returned and broadcast, got %q and %v", deleted.DeleteAction, f.hub.deleted)
+		}
+	})
+
+	t.Run("NoteBroadcasts", func(t *testing.T) {
+		f := setup(t)
+
+		var created struct{ CreateNote struct{ ID string } }
+		f.exec(t, `mutation { createNote(input: {content: "Plan", date: "2026-10-18"}) { id } }`, nil, &created)
+		id := created.CreateNote.ID
+		f.exec(t, `mutation($id: ID!) { updateNote(id: $id, content: "Plan more") { id } }`, map[string]interface{}{"id": id}, nil)
+		f.exec(t, `mutation($id: ID!) { deleteNote(id: $id) }`, map[string]interface{}{"id": id}, nil)
+
+		want := []string{"note_created " + id, "note_updated " + id, "note_deleted " + id}
+		if fmt.Sprint(f.hub.notes) != fmt.Sprint(want) {
+			t.Errorf("Expected broadcasts %v, got %v", want, f.hub.notes)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		f := setup(t)
+		tests := []struct {
+			name  string
+			query string
+			code  string
+		}{
+			{"validation", `mutation { createNote(input: {content: "", date: "2026-10-18"}) { id } }`, handlers.CodeValidation},
+			{"missing note", `mutation { createAction(input: {noteId: "999", description: "Orphan"}) { id } }`, handlers.CodeInvalidReference},
+			{"not found", `mutation { updateAction(id: "999", completed: true) { id } }`, handlers.CodeNotFound},
+			{"malformed id", `mutation { deleteNote(id: "abc") }`, handlers.CodeInvalidParameter},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := f.exec(t, tt.query, nil, nil)
+				if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != tt.code {
+					t.Errorf("Expected a %s error, got %+v", tt.code, resp.Errors)
+				}
+			})
+		}
+
+		resp := f.exec(t, tests[0].query, nil, nil)
+		if fields := resp.Errors[0].Extensions.Fields; len(fields) != 1 || fields[0].Field != "content" {
+			t.Errorf("Expected the content field to be reported, got %+v", fields)
+		}
+	})
+}
+
+func TestMutationRateLimit(t *testing.T) {
+	f := setupWith(t, Options{
+		Limiter:   ratelimit.NewLimiter(),
+		RateLimit: ratelimit.Policy{Routes: map[string]ratelimit.Rate{"POST /api/notes": {Limit: 2, Period: time.Minute}}},
+	})
+
+	// Aliases run the same mutation several times in one request
+	resp := f.exec(t, `mutation {
+		a: createNote(input: {content: "A", date: "2026-10-18"}) { id }
+		b: createNote(input: {content: "B", date: "2026-10-18"}) { id }
+		c: createNote(input: {content: "C", date: "2026-10-18"}) { id }
+	}`, nil, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != handlers.CodeRateLimited {
+		t.Errorf("Expected the third note to be rate limited, got %+v", resp.Errors)
+	}
+	notes, _ := f.notes.GetByDate(context.Background(), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if len(notes) != 2 {
+		t.Errorf("Expected 2 notes to be created, got %d", len(notes))
+	}
+}
+
+func TestQueryBody(t *testing.T) {
+	f := setup(t)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"invalid JSON", `{"query":`, http.StatusBadRequest, handlers.CodeInvalidJSON},
+		{"missing query", `{}`, http.StatusBadRequest, handlers.CodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			f.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			var resp response
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != tt.code {
+				t.Errorf("Expected a %s error, got %s", tt.code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+)
+
+// limits charges GraphQL work to the client's rate limit budgets. The HTTP
+// middleware charges each POST once; on top of that every mutation field
+// draws from the budget of the REST route it stands for, so aliasing many
+// createNote fields into one request does not get around the create limit,
+// and every operation sent over a WebSocket counts as a POST /graphql.
+type limits struct {
+	limiter *ratelimit.Limiter
+	policy  ratelimit.Policy
+}
+
+type clientKey struct{}
+
+// withClient records the rate limit key of the client a request comes from
+func withClient(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, clientKey{}, key)
+}
+
+// allow takes one request from the client's budget for the REST route given
+// by method and route, failing with RATE_LIMITED once it is spent
+func (l limits) allow(ctx context.Context, method, route string) error {
+	if l.limiter == nil {
+		return nil
+	}
+	name, rate := l.policy.For(method, route)
+	if rate.Unlimited() {
+		return nil
+	}
+
+	key, _ := ctx.Value(clientKey{}).(string)
+	result := l.limiter.Allow(key+" "+name, rate)
+	if result.Allowed {
+		return nil
+	}
+	retry := strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+	return &Error{Code: handlers.CodeRateLimited, Message: "rate limit exceeded, retry in " + retry + "s"}
+}
@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// actionLoader batches the Note.actions lookups of one request. Resolvers
+// returning several notes prime it with their IDs; the first of those notes
+// to need its actions then loads the actions of all of them in one query per
+// noteBatch notes, and the rest wait for it instead of running their own.
+type actionLoader struct {
+	store repository.ActionStore
+
+	mu      sync.Mutex
+	batches map[int64]*actionBatch
+}
+
+// noteBatch bounds the note IDs sent in one query, keeping large batches
+// under the databases' limits on query parameters
+const noteBatch = 500
+
+// actionBatch loads the actions of a set of notes
+type actionBatch struct {
+	noteIDs []int64
+	started bool
+	done    chan struct{}
+	actions map[int64][]*models.Action
+	err     error
+}
+
+func newActionLoader(store repository.ActionStore) *actionLoader {
+	return &actionLoader{store: store, batches: make(map[int64]*actionBatch)}
+}
+
+// prime groups the notes that do not have a batch yet into a new one
+func (l *actionLoader) prime(noteIDs []int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch := &actionBatch{done: make(chan struct{})}
+	for _, id := range noteIDs {
+		if _, ok := l.batches[id]; !ok {
+			batch.noteIDs = append(batch.noteIDs, id)
+			l.batches[id] = batch
+		}
+	}
+}
+
+// load returns a note's actions, running its batch if no one has yet
+func (l *actionLoader) load(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	l.mu.Lock()
+	batch, ok := l.batches[noteID]
+	if !ok {
+		batch = &actionBatch{noteIDs: []int64{noteID}, done: make(chan struct{})}
+		l.batches[noteID] = batch
+	}
+	run := !batch.started
+	batch.started = true
+	l.mu.Unlock()
+
+	if run {
+		batch.run(ctx, l.store)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.actions[noteID], nil
+}
+
+func (b *actionBatch) run(ctx context.Context, store repository.ActionStore) {
+	defer close(b.done)
+
+	b.actions = make(map[int64][]*models.Action, len(b.noteIDs))
+	for start := 0; start < len(b.noteIDs); start += noteBatch {
+		actions, err := store.Find(ctx, models.ActionFilter{NoteIDs: b.noteIDs[start:min(start+noteBatch, len(b.noteIDs))]})
+		if err != nil {
+			b.actions, b.err = nil, err
+			return
+		}
+		for _, action := range actions {
+			b.actions[action.NoteID] = append(b.actions[action.NoteID], action)
+		}
+	}
+}
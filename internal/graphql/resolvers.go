@@ -0,0 +1,425 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+)
+
+// Hub is the part of the WebSocket hub the resolvers use: note and action
+// mutations are broadcast like the REST ones, and subscriptions read its
+// events
+type Hub interface {
+	BroadcastNoteCreated(ctx context.Context, note *models.Note)
+	BroadcastNoteUpdated(ctx context.Context, note *models.Note)
+	BroadcastNoteDeleted(ctx context.Context, noteID int64)
+	BroadcastActionCreated(ctx context.Context, action *models.Action)
+	BroadcastActionUpdated(ctx context.Context, action *models.Action)
+	BroadcastActionDeleted(ctx context.Context, actionID int64)
+	Subscribe(ctx context.Context) (<-chan websocket.Event, error)
+}
+
+// Date is the Date scalar, a day without a time
+type Date struct {
+	time.Time
+}
+
+func (Date) ImplementsGraphQLType(name string) bool {
+	return name == "Date"
+}
+
+func (d *Date) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("wrong type for Date: %T", input)
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return fmt.Errorf("date must be in YYYY-MM-DD format: %q", s)
+	}
+	d.Time = t
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format("2006-01-02"))
+}
+
+// resolver is the root of queries, mutations and subscriptions
+type resolver struct {
+	notes   repository.NoteStore
+	actions repository.ActionStore
+	hub     Hub
+	limits  limits
+}
+
+type loaderKey struct{}
+
+// withLoader gives the resolvers of one request a shared action loader
+func withLoader(ctx context.Context, loader *actionLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// loader returns the request's action loader, or a new one outside requests
+// such as for subscription events
+func (r *resolver) loader(ctx context.Context) *actionLoader {
+	if loader, ok := ctx.Value(loaderKey{}).(*actionLoader); ok {
+		return loader
+	}
+	return newActionLoader(r.actions)
+}
+
+func (r *resolver) Note(ctx context.Context, args struct{ ID gql.ID }) (*noteResolver, error) {
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	note, err := r.notes.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	return &noteResolver{note: note, loader: r.loader(ctx)}, nil
+}
+
+func (r *resolver) Notes(ctx context.Context, args struct{ From, To Date }) ([]*noteResolver, error) {
+	if args.To.Before(args.From.Time) {
+		return nil, &Error{
+			Code:    handlers.CodeInvalidParameter,
+			Message: "invalid to",
+			Fields:  []handlers.FieldError{{Field: "to", Rule: "gtefield", Message: "must not be before from"}},
+		}
+	}
+	notes, err := r.notes.GetByDateRange(ctx, args.From.Time, args.To.Time)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+
+	loader := r.loader(ctx)
+	ids := make([]int64, len(notes))
+	resolvers := make([]*noteResolver, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+		resolvers[i] = &noteResolver{note: note, loader: loader}
+	}
+	loader.prime(ids)
+	return resolvers, nil
+}
+
+func (r *resolver) Action(ctx context.Context, args struct{ ID gql.ID }) (*actionResolver, error) {
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	action, err := r.actions.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	return &actionResolver{action}, nil
+}
+
+// actionFilter is the ActionFilter input
+type actionFilter struct {
+	NoteIDs       *[]gql.ID
+	Completed     *bool
+	CreatedAfter  *gql.Time
+	CreatedBefore *gql.Time
+}
+
+func (r *resolver) Actions(ctx context.Context, args struct{ Filter *actionFilter }) ([]*actionResolver, error) {
+	var filter models.ActionFilter
+	if f := args.Filter; f != nil {
+		if f.NoteIDs != nil {
+			for _, id := range *f.NoteIDs {
+				noteID, err := parseID("noteIds", string(id))
+				if err != nil {
+					return nil, err
+				}
+				filter.NoteIDs = append(filter.NoteIDs, noteID)
+			}
+		}
+		filter.Completed = f.Completed
+		if f.CreatedAfter != nil {
+			filter.CreatedAfter = f.CreatedAfter.Time
+		}
+		if f.CreatedBefore != nil {
+			filter.CreatedBefore = f.CreatedBefore.Time
+		}
+	}
+
+	actions, err := r.actions.Find(ctx, filter)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	return actionResolvers(actions), nil
+}
+
+func (r *resolver) CreateNote(ctx context.Context, args struct {
+	Input struct {
+		Content string
+		Date    Date
+	}
+}) (*noteResolver, error) {
+	if err := r.limits.allow(ctx, http.MethodPost, "/api/notes"); err != nil {
+		return nil, err
+	}
+	req := &models.CreateNoteRequest{Content: args.Input.Content, Date: args.Input.Date.Time}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	note, err := r.notes.Create(ctx, req)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	r.hub.BroadcastNoteCreated(ctx, note)
+	return &noteResolver{note: note, loader: r.loader(ctx)}, nil
+}
+
+func (r *resolver) UpdateNote(ctx context.Context, args struct {
+	ID      gql.ID
+	Content string
+}) (*noteResolver, error) {
+	if err := r.limits.allow(ctx, http.MethodPut, "/api/notes/:id"); err != nil {
+		return nil, err
+	}
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	req := &models.UpdateNoteRequest{Content: args.Content}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	note, err := r.notes.Update(ctx, id, req)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	r.hub.BroadcastNoteUpdated(ctx, note)
+	return &noteResolver{note: note, loader: r.loader(ctx)}, nil
+}
+
+func (r *resolver) DeleteNote(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	if err := r.limits.allow(ctx, http.MethodDelete, "/api/notes/:id"); err != nil {
+		return "", err
+	}
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return "", err
+	}
+	if err := r.notes.Delete(ctx, id); err != nil {
+		return "", repositoryError(ctx, err, "note")
+	}
+	r.hub.BroadcastNoteDeleted(ctx, id)
+	return args.ID, nil
+}
+
+func (r *resolver) CreateAction(ctx context.Context, args struct {
+	Input struct {
+		NoteID      gql.ID
+		Description string
+	}
+}) (*actionResolver, error) {
+	if err := r.limits.allow(ctx, http.MethodPost, "/api/actions"); err != nil {
+		return nil, err
+	}
+	noteID, err := parseID("noteId", string(args.Input.NoteID))
+	if err != nil {
+		return nil, err
+	}
+	req := &models.CreateActionRequest{NoteID: noteID, Description: args.Input.Description}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	action, err := r.actions.Create(ctx, req)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	r.hub.BroadcastActionCreated(ctx, action)
+	return &actionResolver{action}, nil
+}
+
+func (r *resolver) UpdateAction(ctx context.Context, args struct {
+	ID        gql.ID
+	Completed bool
+}) (*actionResolver, error) {
+	if err := r.limits.allow(ctx, http.MethodPut, "/api/actions/:id"); err != nil {
+		return nil, err
+	}
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	action, _, err := r.actions.Update(ctx, id, &models.UpdateActionRequest{Completed: args.Completed})
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	r.hub.BroadcastActionUpdated(ctx, action)
+	return &actionResolver{action}, nil
+}
+
+func (r *resolver) DeleteAction(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	if err := r.limits.allow(ctx, http.MethodDelete, "/api/actions/:id"); err != nil {
+		return "", err
+	}
+	id, err := parseID("id", string(args.ID))
+	if err != nil {
+		return "", err
+	}
+	if err := r.actions.Delete(ctx, id); err != nil {
+		return "", repositoryError(ctx, err, "action")
+	}
+	r.hub.BroadcastActionDeleted(ctx, id)
+	return args.ID, nil
+}
+
+func (r *resolver) ActionChanged(ctx context.Context, args struct{ NoteID *gql.ID }) (<-chan *actionChangeResolver, error) {
+	var noteID int64
+	if args.NoteID != nil {
+		id, err := parseID("noteId", string(*args.NoteID))
+		if err != nil {
+			return nil, err
+		}
+		noteID = id
+	}
+	events, err := r.hub.Subscribe(ctx)
+	if err != nil {
+		return nil, &Error{Code: handlers.CodeInternal, Message: "subscriptions are not available"}
+	}
+
+	changes := make(chan *actionChangeResolver)
+	go func() {
+		defer close(changes)
+		for event := range events {
+			if event.Type != websocket.ActionCreated && event.Type != websocket.ActionUpdated && event.Type != websocket.ActionDeleted {
+				continue
+			}
+			if noteID != 0 && event.Action != nil && event.Action.NoteID != noteID {
+				continue
+			}
+			select {
+			case changes <- &actionChangeResolver{event}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func (r *resolver) NoteChanged(ctx context.Context) (<-chan *noteChangeResolver, error) {
+	events, err := r.hub.Subscribe(ctx)
+	if err != nil {
+		return nil, &Error{Code: handlers.CodeInternal, Message: "subscriptions are not available"}
+	}
+
+	changes := make(chan *noteChangeResolver)
+	go func() {
+		defer close(changes)
+		for event := range events {
+			if event.Type != websocket.NoteCreated && event.Type != websocket.NoteUpdated && event.Type != websocket.NoteDeleted {
+				continue
+			}
+			select {
+			case changes <- &noteChangeResolver{event: event, actions: r.actions}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+type noteResolver struct {
+	note   *models.Note
+	loader *actionLoader
+}
+
+func (r *noteResolver) ID() gql.ID          { return idOf(r.note.ID) }
+func (r *noteResolver) Content() string     { return r.note.Content }
+func (r *noteResolver) Date() Date          { return Date{r.note.Date} }
+func (r *noteResolver) CreatedAt() gql.Time { return gql.Time{Time: r.note.CreatedAt} }
+func (r *noteResolver) UpdatedAt() gql.Time { return gql.Time{Time: r.note.UpdatedAt} }
+
+func (r *noteResolver) Actions(ctx context.Context) ([]*actionResolver, error) {
+	actions, err := r.loader.load(ctx, r.note.ID)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	return actionResolvers(actions), nil
+}
+
+type actionResolver struct {
+	action *models.Action
+}
+
+func (r *actionResolver) ID() gql.ID          { return idOf(r.action.ID) }
+func (r *actionResolver) NoteID() gql.ID      { return idOf(r.action.NoteID) }
+func (r *actionResolver) Description() string { return r.action.Description }
+func (r *actionResolver) Completed() bool     { return r.action.Completed }
+func (r *actionResolver) CreatedAt() gql.Time { return gql.Time{Time: r.action.CreatedAt} }
+func (r *actionResolver) UpdatedAt() gql.Time { return gql.Time{Time: r.action.UpdatedAt} }
+
+func actionResolvers(actions []*models.Action) []*actionResolver {
+	resolvers := make([]*actionResolver, len(actions))
+	for i, action := range actions {
+		resolvers[i] = &actionResolver{action}
+	}
+	return resolvers
+}
+
+// changeTypes maps hub events to the ChangeType enum
+var changeTypes = map[websocket.MessageType]string{
+	websocket.ActionCreated: "CREATED",
+	websocket.ActionUpdated: "UPDATED",
+	websocket.ActionDeleted: "DELETED",
+	websocket.NoteCreated:   "CREATED",
+	websocket.NoteUpdated:   "UPDATED",
+	websocket.NoteDeleted:   "DELETED",
+}
+
+type actionChangeResolver struct {
+	event websocket.Event
+}
+
+func (r *actionChangeResolver) Type() string { return changeTypes[r.event.Type] }
+func (r *actionChangeResolver) ID() gql.ID   { return idOf(r.event.ID) }
+
+func (r *actionChangeResolver) Action() *actionResolver {
+	if r.event.Action == nil {
+		return nil
+	}
+	return &actionResolver{r.event.Action}
+}
+
+type noteChangeResolver struct {
+	event   websocket.Event
+	actions repository.ActionStore
+}
+
+func (r *noteChangeResolver) Type() string { return changeTypes[r.event.Type] }
+func (r *noteChangeResolver) ID() gql.ID   { return idOf(r.event.ID) }
+
+func (r *noteChangeResolver) Note() *noteResolver {
+	if r.event.Note == nil {
+		return nil
+	}
+	return &noteResolver{note: r.event.Note, loader: newActionLoader(r.actions)}
+}
+
+func idOf(id int64) gql.ID {
+	return gql.ID(fmt.Sprint(id))
+}
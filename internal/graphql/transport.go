@@ -0,0 +1,299 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+)
+
+// subprotocol is the WebSocket subprotocol subscriptions are served over,
+// as spoken by the graphql-ws client library
+const subprotocol = "graphql-transport-ws"
+
+// Message types of the graphql-transport-ws protocol
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes the protocol defines for misbehaving clients
+const (
+	closeBadRequest         = 4400
+	closeUnauthorized       = 4401
+	closeBadSubprotocol     = 4406
+	closeInitTimeout        = 4408
+	closeDuplicateID        = 4409
+	closeTooManyInitialised = 4429
+)
+
+const (
+	// initTimeout is how long a client has to send connection_init
+	initTimeout = 10 * time.Second
+	// maxMessageSize bounds a single client message
+	maxMessageSize = 1 << 20
+	// maxSubscriptions bounds the operations running at once on a
+	// connection; more are refused with an error message
+	maxSubscriptions = 100
+)
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{subprotocol},
+	// Like /ws, any origin may connect
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// message is a graphql-transport-ws frame
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// session is one graphql-transport-ws connection
+type session struct {
+	handler *Handler
+	conn    *websocket.Conn
+	ctx     context.Context
+
+	// writeMu serialises writes, which come from every subscription
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	initialised   bool
+	subscriptions map[string]context.CancelFunc
+}
+
+// Subscribe upgrades the request to a WebSocket speaking graphql-transport-ws,
+// over which clients run subscriptions as well as queries and mutations
+func (h *Handler) Subscribe(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("graphql websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != subprotocol {
+		closeConn(conn, closeBadSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(withClient(c.Request.Context(), ratelimit.ClientKey(c.ClientIP())))
+	defer cancel()
+	s := &session{handler: h, conn: conn, ctx: ctx, subscriptions: make(map[string]context.CancelFunc)}
+	if !h.open(s) {
+		closeConn(conn, websocket.CloseGoingAway, "Server is shutting down")
+		return
+	}
+	defer h.release(s)
+	s.serve()
+}
+
+// open registers a session, reporting false once the handler is shutting
+// down
+func (h *Handler) open(s *session) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopping {
+		return false
+	}
+	h.sessions[s] = struct{}{}
+	h.work.Add(1)
+	return true
+}
+
+// release forgets a session whose connection has closed
+func (h *Handler) release(s *session) {
+	h.mu.Lock()
+	delete(h.sessions, s)
+	h.mu.Unlock()
+	h.work.Done()
+}
+
+// serve reads client messages until the connection closes
+func (s *session) serve() {
+	s.conn.SetReadLimit(maxMessageSize)
+	timer := time.AfterFunc(initTimeout, func() {
+		s.mu.Lock()
+		initialised := s.initialised
+		s.mu.Unlock()
+		if !initialised {
+			s.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer timer.Stop()
+
+	for {
+		var msg message
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				s.close(closeBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			s.mu.Lock()
+			again := s.initialised
+			s.initialised = true
+			s.mu.Unlock()
+			if again {
+				s.close(closeTooManyInitialised, "Too many initialisation requests")
+				return
+			}
+			s.send(message{Type: msgConnectionAck})
+
+		case msgPing:
+			s.send(message{Type: msgPong})
+
+		case msgPong:
+
+		case msgSubscribe:
+			if !s.start(msg) {
+				return
+			}
+
+		case msgComplete:
+			s.mu.Lock()
+			if stop, ok := s.subscriptions[msg.ID]; ok {
+				stop()
+				delete(s.subscriptions, msg.ID)
+			}
+			s.mu.Unlock()
+
+		default:
+			s.close(closeBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// start runs a subscribe message's operation, returning false when the
+// message broke the protocol and the connection was closed
+func (s *session) start(msg message) bool {
+	var req request
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+		s.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+
+	s.mu.Lock()
+	if !s.initialised {
+		s.mu.Unlock()
+		s.close(closeUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, ok := s.subscriptions[msg.ID]; ok {
+		s.mu.Unlock()
+		s.close(closeDuplicateID, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	if len(s.subscriptions) >= maxSubscriptions {
+		s.mu.Unlock()
+		s.sendPayload(msgError, msg.ID, []gin.H{{"message": fmt.Sprintf("too many operations: at most %d may run at once on a connection", maxSubscriptions)}})
+		return true
+	}
+	ctx, stop := context.WithCancel(s.ctx)
+	s.subscriptions[msg.ID] = stop
+	s.mu.Unlock()
+
+	// Each operation costs as much as running it over POST /graphql
+	if err := s.handler.limits.allow(ctx, http.MethodPost, "/graphql"); err != nil {
+		s.finish(msg.ID)
+		s.sendPayload(msgError, msg.ID, []gin.H{{"message": err.Error(), "extensions": err.(*Error).Extensions()}})
+		return true
+	}
+
+	ctx = withLoader(ctx, newActionLoader(s.handler.actions))
+	responses, err := s.handler.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		s.finish(msg.ID)
+		s.sendPayload(msgError, msg.ID, []gin.H{{"message": err.Error()}})
+		return true
+	}
+
+	// The session's own count keeps Wait from returning before this is added
+	s.handler.work.Add(1)
+	go func() {
+		defer s.handler.work.Done()
+		first := true
+		for r := range responses {
+			response := r.(*gql.Response)
+			// An operation that fails before running gets an error message,
+			// which also ends it
+			if first && response.Data == nil && len(response.Errors) > 0 {
+				if s.finish(msg.ID) {
+					s.sendPayload(msgError, msg.ID, response.Errors)
+				}
+				return
+			}
+			first = false
+			s.sendPayload(msgNext, msg.ID, response)
+		}
+		// The client asked to stop unless the subscription is still listed
+		if s.finish(msg.ID) {
+			s.send(message{ID: msg.ID, Type: msgComplete})
+		}
+	}()
+	return true
+}
+
+// finish forgets a subscription, reporting whether it was still running
+func (s *session) finish(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, ok := s.subscriptions[id]
+	if ok {
+		stop()
+		delete(s.subscriptions, id)
+	}
+	return ok
+}
+
+func (s *session) sendPayload(msgType, id string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logging.FromContext(s.ctx).Error("marshaling graphql payload failed", "error", err)
+		return
+	}
+	s.send(message{ID: id, Type: msgType, Payload: data})
+}
+
+func (s *session) send(msg message) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.WriteJSON(msg); err != nil {
+		logging.FromContext(s.ctx).Debug("graphql websocket write failed", "error", err)
+	}
+}
+
+// close ends the connection with one of the protocol's close codes
+func (s *session) close(code int, reason string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	closeConn(s.conn, code, reason)
+}
+
+func closeConn(conn *websocket.Conn, code int, reason string) {
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	conn.Close()
+}
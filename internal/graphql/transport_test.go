@@ -0,0 +1,206 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+)
+
+func TestSubscriptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := websocket.NewHub(nil, nil)
+	ctx, stopHub := context.WithCancel(context.Background())
+	defer stopHub()
+	go hub.Run(ctx)
+
+	store := repository.NewMemoryStore()
+	r := gin.New()
+	r.GET("/graphql", NewHandler(repository.NewMemoryNoteRepository(store), repository.NewMemoryActionRepository(store), hub, Options{}).Subscribe)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	dial := func(t *testing.T) *gorilla.Conn {
+		t.Helper()
+		dialer := gorilla.Dialer{Subprotocols: []string{subprotocol}}
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		return conn
+	}
+	receive := func(t *testing.T, conn *gorilla.Conn, msgType string) message {
+		t.Helper()
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Expected a %s message: %v", msgType, err)
+		}
+		if msg.Type != msgType {
+			t.Fatalf("Expected a %s message, got %s: %s", msgType, msg.Type, msg.Payload)
+		}
+		return msg
+	}
+
+	t.Run("ActionChanged", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+
+		conn.WriteJSON(message{Type: msgConnectionInit})
+		receive(t, conn, msgConnectionAck)
+
+		payload, _ := json.Marshal(request{Query: `subscription { actionChanged(noteId: "1") { type id action { description } } }`})
+		conn.WriteJSON(message{ID: "sub", Type: msgSubscribe, Payload: payload})
+		// Messages are handled in order, so the pong means the subscription is live
+		conn.WriteJSON(message{Type: msgPing})
+		receive(t, conn, msgPong)
+
+		hub.BroadcastActionCreated(context.Background(), &models.Action{ID: 1, NoteID: 2, Description: "Other note"})
+		hub.BroadcastActionCreated(context.Background(), &models.Action{ID: 2, NoteID: 1, Description: "Wanted"})
+
+		msg := receive(t, conn, msgNext)
+		var result struct {
+			Data struct {
+				ActionChanged struct {
+					Type   string
+					ID     string
+					Action struct{ Description string }
+				}
+			}
+		}
+		json.Unmarshal(msg.Payload, &result)
+		if msg.ID != "sub" || result.Data.ActionChanged.ID != "2" || result.Data.ActionChanged.Type != "CREATED" {
+			t.Errorf("Expected the change on note 1, got %s", msg.Payload)
+		}
+
+		conn.WriteJSON(message{ID: "sub", Type: msgComplete})
+		conn.WriteJSON(message{Type: msgPing})
+		receive(t, conn, msgPong)
+	})
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+
+		conn.WriteJSON(message{Type: msgConnectionInit})
+		receive(t, conn, msgConnectionAck)
+
+		payload, _ := json.Marshal(request{Query: `subscription { unknownField }`})
+		conn.WriteJSON(message{ID: "bad", Type: msgSubscribe, Payload: payload})
+		if msg := receive(t, conn, msgError); msg.ID != "bad" {
+			t.Errorf("Expected the error for bad, got %q", msg.ID)
+		}
+	})
+
+	t.Run("TooManySubscriptions", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+
+		conn.WriteJSON(message{Type: msgConnectionInit})
+		receive(t, conn, msgConnectionAck)
+
+		payload, _ := json.Marshal(request{Query: `subscription { noteChanged { id } }`})
+		for i := 0; i <= maxSubscriptions; i++ {
+			conn.WriteJSON(message{ID: strconv.Itoa(i), Type: msgSubscribe, Payload: payload})
+		}
+		if msg := receive(t, conn, msgError); msg.ID != strconv.Itoa(maxSubscriptions) {
+			t.Errorf("Expected the extra subscription to be refused, got %q", msg.ID)
+		}
+
+		// Completing one makes room for another
+		conn.WriteJSON(message{ID: "0", Type: msgComplete})
+		conn.WriteJSON(message{ID: "again", Type: msgSubscribe, Payload: payload})
+		conn.WriteJSON(message{Type: msgPing})
+		receive(t, conn, msgPong)
+	})
+
+	t.Run("SubscribeBeforeInit", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+
+		payload, _ := json.Marshal(request{Query: `subscription { noteChanged { id } }`})
+		conn.WriteJSON(message{ID: "early", Type: msgSubscribe, Payload: payload})
+
+		_, _, err := conn.ReadMessage()
+		if !gorilla.IsCloseError(err, closeUnauthorized) {
+			t.Errorf("Expected close code %d, got %v", closeUnauthorized, err)
+		}
+	})
+}
+
+func TestWebSocketLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	handler := NewHandler(repository.NewMemoryNoteRepository(store), repository.NewMemoryActionRepository(store), websocket.NewHub(nil, nil), Options{
+		Limiter:   ratelimit.NewLimiter(),
+		RateLimit: ratelimit.Policy{Routes: map[string]ratelimit.Rate{"POST /graphql": {Limit: 1, Period: time.Minute}}},
+	})
+	r := gin.New()
+	r.GET("/graphql", handler.Subscribe)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	dialer := gorilla.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	conn.WriteJSON(message{Type: msgConnectionInit})
+	var ack message
+	conn.ReadJSON(&ack)
+
+	t.Run("Operations", func(t *testing.T) {
+		payload, _ := json.Marshal(request{Query: `query { notes(from: "2026-10-18") { id } }`})
+		conn.WriteJSON(message{ID: "first", Type: msgSubscribe, Payload: payload})
+		conn.WriteJSON(message{ID: "second", Type: msgSubscribe, Payload: payload})
+
+		var msg message
+		for msg.ID != "second" {
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("Expected a message for the second operation: %v", err)
+			}
+		}
+		if msg.Type != msgError || !strings.Contains(string(msg.Payload), "RATE_LIMITED") {
+			t.Errorf("Expected the second operation to be rate limited, got %s %s", msg.Type, msg.Payload)
+		}
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := handler.Shutdown(ctx); err != nil {
+			t.Fatalf("Expected the sessions to end, got %v", err)
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if !gorilla.IsCloseError(err, gorilla.CloseGoingAway) {
+					t.Errorf("Expected close code %d, got %v", gorilla.CloseGoingAway, err)
+				}
+				break
+			}
+		}
+
+		// New connections are refused once shutting down
+		late, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer late.Close()
+		late.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := late.ReadMessage(); !gorilla.IsCloseError(err, gorilla.CloseGoingAway) {
+			t.Errorf("Expected close code %d, got %v", gorilla.CloseGoingAway, err)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+	logmeupv1 "github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Hub broadcasts note and action changes to WebSocket clients and streams
+// action changes to watchers
+type Hub interface {
+	BroadcastNoteCreated(ctx context.Context, note *models.Note)
+	BroadcastNoteUpdated(ctx context.Context, note *models.Note)
+	BroadcastNoteDeleted(ctx context.Context, noteID int64)
+	BroadcastActionCreated(ctx context.Context, action *models.Action)
+	BroadcastActionUpdated(ctx context.Context, action *models.Action)
+	BroadcastActionDeleted(ctx context.Context, actionID int64)
+	Subscribe(ctx context.Context) (<-chan websocket.Event, error)
+}
+
+// actionService implements logmeup.v1.ActionService on an action store
+type actionService struct {
+	logmeupv1.UnimplementedActionServiceServer
+	actions repository.ActionStore
+	hub     Hub
+}
+
+func (s *actionService) CreateAction(ctx context.Context, req *logmeupv1.CreateActionRequest) (*logmeupv1.Action, error) {
+	create := &models.CreateActionRequest{NoteID: req.NoteId, Description: req.Description}
+	if err := validate(create); err != nil {
+		return nil, err
+	}
+
+	action, err := s.actions.Create(ctx, create)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	s.hub.BroadcastActionCreated(ctx, action)
+	return actionPB(action), nil
+}
+
+func (s *actionService) GetAction(ctx context.Context, req *logmeupv1.GetActionRequest) (*logmeupv1.Action, error) {
+	action, err := s.actions.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	return actionPB(action), nil
+}
+
+func (s *actionService) ListActions(ctx context.Context, req *logmeupv1.ListActionsRequest) (*logmeupv1.ListActionsResponse, error) {
+	filter := models.ActionFilter{NoteIDs: req.NoteIds, Completed: req.Completed}
+	if req.CreatedAfter != nil {
+		if err := req.CreatedAfter.CheckValid(); err != nil {
+			return nil, invalidArgument("created_after", "format", "must be a valid timestamp")
+		}
+		filter.CreatedAfter = req.CreatedAfter.AsTime()
+	}
+	if req.CreatedBefore != nil {
+		if err := req.CreatedBefore.CheckValid(); err != nil {
+			return nil, invalidArgument("created_before", "format", "must be a valid timestamp")
+		}
+		filter.CreatedBefore = req.CreatedBefore.AsTime()
+	}
+
+	actions, err := s.actions.Find(ctx, filter)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	resp := &logmeupv1.ListActionsResponse{Actions: make([]*logmeupv1.Action, len(actions))}
+	for i, action := range actions {
+		resp.Actions[i] = actionPB(action)
+	}
+	return resp, nil
+}
+
+func (s *actionService) UpdateAction(ctx context.Context, req *logmeupv1.UpdateActionRequest) (*logmeupv1.Action, error) {
+	action, _, err := s.actions.Update(ctx, req.Id, &models.UpdateActionRequest{Completed: req.Completed})
+	if err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	s.hub.BroadcastActionUpdated(ctx, action)
+	return actionPB(action), nil
+}
+
+func (s *actionService) DeleteAction(ctx context.Context, req *logmeupv1.DeleteActionRequest) (*logmeupv1.DeleteActionResponse, error) {
+	if err := s.actions.Delete(ctx, req.Id); err != nil {
+		return nil, repositoryError(ctx, err, "action")
+	}
+	s.hub.BroadcastActionDeleted(ctx, req.Id)
+	return &logmeupv1.DeleteActionResponse{}, nil
+}
+
+// eventTypes maps hub events to the ActionEvent types
+var eventTypes = map[websocket.MessageType]logmeupv1.ActionEvent_Type{
+	websocket.ActionCreated: logmeupv1.ActionEvent_TYPE_CREATED,
+	websocket.ActionUpdated: logmeupv1.ActionEvent_TYPE_UPDATED,
+	websocket.ActionDeleted: logmeupv1.ActionEvent_TYPE_DELETED,
+}
+
+// WatchActions sends the headers once the stream is subscribed to the hub,
+// so a client that has read them will not miss later changes
+func (s *actionService) WatchActions(req *logmeupv1.WatchActionsRequest, stream grpc.ServerStreamingServer[logmeupv1.ActionEvent]) error {
+	ctx := stream.Context()
+	events, err := s.hub.Subscribe(ctx)
+	if errors.Is(err, websocket.ErrHubStopped) {
+		return status.Error(codes.Unavailable, "server is stopping")
+	}
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	notes := make(map[int64]bool, len(req.NoteIds))
+	for _, id := range req.NoteIds {
+		notes[id] = true
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream closed; list actions again after reconnecting to catch up")
+			}
+			eventType, ok := eventTypes[event.Type]
+			if !ok {
+				continue
+			}
+			// Deletions carry no action, so their note is unknown
+			if len(notes) > 0 && event.Action != nil && !notes[event.Action.NoteID] {
+				continue
+			}
+
+			msg := &logmeupv1.ActionEvent{Type: eventType, Id: event.ID}
+			if event.Action != nil {
+				msg.Action = actionPB(event.Action)
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func actionPB(action *models.Action) *logmeupv1.Action {
+	return &logmeupv1.Action{
+		Id:          action.ID,
+		NoteId:      action.NoteID,
+		Description: action.Description,
+		Completed:   action.Completed,
+		CreatedAt:   timestamppb.New(action.CreatedAt),
+		UpdatedAt:   timestamppb.New(action.UpdatedAt),
+	}
+}
@@ -0,0 +1,77 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain names the service in ErrorInfo details
+const errorDomain = "logmeup"
+
+// statusError builds a status carrying reason, one of the codes REST
+// problems use, in an ErrorInfo and any invalid fields in a BadRequest
+func statusError(code codes.Code, reason, message string, fields ...handlers.FieldError) error {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}}
+	if len(fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st, err := status.New(code, message).WithDetails(details...)
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// storeCodes is the status code for each code ClassifyStoreError returns
+var storeCodes = map[string]codes.Code{
+	handlers.CodeNotFound:         codes.NotFound,
+	handlers.CodeInvalidReference: codes.FailedPrecondition,
+	handlers.CodeConflict:         codes.AlreadyExists,
+	handlers.CodeValidation:       codes.InvalidArgument,
+	handlers.CodeTimeout:          codes.DeadlineExceeded,
+	handlers.CodeCanceled:         codes.Canceled,
+	handlers.CodeDatabaseError:    codes.Internal,
+}
+
+// repositoryError maps an error from a store to the status for it. Database
+// details are logged rather than returned.
+func repositoryError(ctx context.Context, err error, resource string) error {
+	failure := handlers.ClassifyStoreError(err, resource)
+	if failure.Code == handlers.CodeDatabaseError {
+		logging.FromContext(ctx).Error("grpc repository error", "resource", resource, "error", err)
+	}
+	return statusError(storeCodes[failure.Code], failure.Code, failure.Message)
+}
+
+// validate applies the binding rules the REST handlers use to a request,
+// reporting fields by their JSON names, which match the proto field names
+func validate(req interface{}) error {
+	err := binding.Validator.ValidateStruct(req)
+	fields, ok := handlers.ValidationFields(err, validator.FieldError.Field)
+	if !ok {
+		return err
+	}
+	return statusError(codes.InvalidArgument, handlers.CodeValidation, "request has invalid fields", fields...)
+}
+
+// invalidArgument is the error for a malformed request field
+func invalidArgument(field, rule, message string) error {
+	return statusError(codes.InvalidArgument, handlers.CodeInvalidParameter, "invalid "+field,
+		handlers.FieldError{Field: field, Rule: rule, Message: message})
+}
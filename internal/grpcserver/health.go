@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	logmeupv1 "github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// servedNames are the names the health service answers for: the server as
+// a whole ("") and each of its services
+var servedNames = []string{
+	"",
+	logmeupv1.NoteService_ServiceDesc.ServiceName,
+	logmeupv1.ActionService_ServiceDesc.ServiceName,
+}
+
+// healthService answers health checks from the readiness checks behind
+// /readyz. Every service shares the same storage, so all of them are
+// SERVING or NOT_SERVING together. Each Check also updates the status Watch
+// streams report, and once the server stops every name stays NOT_SERVING.
+type healthService struct {
+	*health.Server
+	readiness *handlers.HealthHandler
+}
+
+func newHealthService(readiness *handlers.HealthHandler) *healthService {
+	s := &healthService{Server: health.NewServer(), readiness: readiness}
+	for _, name := range servedNames {
+		s.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	return s
+}
+
+func (s *healthService) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if s.readiness != nil {
+		status := healthpb.HealthCheckResponse_SERVING
+		if s.readiness.Report(ctx).Status != "ok" {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		// Ignored once the server has stopped
+		for _, name := range servedNames {
+			s.SetServingStatus(name, status)
+		}
+	}
+	return s.Server.Check(ctx, req)
+}
+
+// Server is the gRPC server. Stopping it first marks every service
+// NOT_SERVING, so health checks fail while calls drain.
+type Server struct {
+	*grpc.Server
+	health *healthService
+}
+
+// GracefulStop marks the services NOT_SERVING, stops accepting calls and
+// waits for those in flight to finish
+func (s *Server) GracefulStop() {
+	s.health.Shutdown()
+	s.Server.GracefulStop()
+}
+
+// Stop marks the services NOT_SERVING and cancels every call
+func (s *Server) Stop() {
+	s.health.Shutdown()
+	s.Server.Stop()
+}
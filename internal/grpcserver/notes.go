@@ -0,0 +1,117 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	logmeupv1 "github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// dateLayout is how note dates are written in messages
+const dateLayout = "2006-01-02"
+
+// noteService implements logmeup.v1.NoteService on a note store
+type noteService struct {
+	logmeupv1.UnimplementedNoteServiceServer
+	notes repository.NoteStore
+	hub   Hub
+}
+
+func (s *noteService) CreateNote(ctx context.Context, req *logmeupv1.CreateNoteRequest) (*logmeupv1.Note, error) {
+	create := &models.CreateNoteRequest{Content: req.Content}
+	if req.Date != "" {
+		date, err := parseDate("date", req.Date)
+		if err != nil {
+			return nil, err
+		}
+		create.Date = date
+	}
+	if err := validate(create); err != nil {
+		return nil, err
+	}
+
+	note, err := s.notes.Create(ctx, create)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	s.hub.BroadcastNoteCreated(ctx, note)
+	return notePB(note), nil
+}
+
+func (s *noteService) GetNote(ctx context.Context, req *logmeupv1.GetNoteRequest) (*logmeupv1.Note, error) {
+	note, err := s.notes.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	return notePB(note), nil
+}
+
+func (s *noteService) ListNotes(ctx context.Context, req *logmeupv1.ListNotesRequest) (*logmeupv1.ListNotesResponse, error) {
+	from, err := parseDate("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to := from
+	if req.To != "" {
+		if to, err = parseDate("to", req.To); err != nil {
+			return nil, err
+		}
+	}
+	if to.Before(from) {
+		return nil, invalidArgument("to", "gtefield", "must not be before from")
+	}
+
+	notes, err := s.notes.GetByDateRange(ctx, from, to)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	resp := &logmeupv1.ListNotesResponse{Notes: make([]*logmeupv1.Note, len(notes))}
+	for i, note := range notes {
+		resp.Notes[i] = notePB(note)
+	}
+	return resp, nil
+}
+
+func (s *noteService) UpdateNote(ctx context.Context, req *logmeupv1.UpdateNoteRequest) (*logmeupv1.Note, error) {
+	update := &models.UpdateNoteRequest{Content: req.Content}
+	if err := validate(update); err != nil {
+		return nil, err
+	}
+
+	note, err := s.notes.Update(ctx, req.Id, update)
+	if err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	s.hub.BroadcastNoteUpdated(ctx, note)
+	return notePB(note), nil
+}
+
+func (s *noteService) DeleteNote(ctx context.Context, req *logmeupv1.DeleteNoteRequest) (*logmeupv1.DeleteNoteResponse, error) {
+	if err := s.notes.Delete(ctx, req.Id); err != nil {
+		return nil, repositoryError(ctx, err, "note")
+	}
+	s.hub.BroadcastNoteDeleted(ctx, req.Id)
+	return &logmeupv1.DeleteNoteResponse{}, nil
+}
+
+// parseDate reads a YYYY-MM-DD field
+func parseDate(field, value string) (time.Time, error) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, invalidArgument(field, "format", "must be a date in YYYY-MM-DD format")
+	}
+	return date, nil
+}
+
+func notePB(note *models.Note) *logmeupv1.Note {
+	return &logmeupv1.Note{
+		Id:        note.ID,
+		Content:   note.Content,
+		Date:      note.Date.Format(dateLayout),
+		CreatedAt: timestamppb.New(note.CreatedAt),
+		UpdatedAt: timestamppb.New(note.UpdatedAt),
+	}
+}
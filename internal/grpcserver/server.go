@@ -0,0 +1,242 @@
+// Package grpcserver serves the logmeup.v1 gRPC services from the same
+// stores, hub and rate limits as the REST API.
+//
+// Like the REST API, the services do not authenticate callers: the
+// x-user-id metadata only labels log lines, and calls are limited by the
+// caller's address. Without TLS credentials calls travel in plaintext, so
+// serve them on a trusted network or behind a proxy terminating TLS and
+// authenticating clients.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/metrics"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/tracing"
+	logmeupv1 "github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Metadata keys identifying the caller, the gRPC form of the REST headers
+const (
+	userKey      = "x-user-id"
+	requestIDKey = "x-request-id"
+)
+
+// rateLimitMethod is the method rate limit routes use for gRPC calls, as in
+// "GRPC /logmeup.v1.NoteService/CreateNote=60/m"
+const rateLimitMethod = "GRPC"
+
+// restRoutes maps the methods that write records to the REST route doing the
+// same, whose budget they draw from unless the method has a rate of its own,
+// so that the create limits configured for REST also hold over gRPC
+var restRoutes = map[string]struct{ method, route string }{
+	logmeupv1.NoteService_CreateNote_FullMethodName:     {http.MethodPost, "/api/notes"},
+	logmeupv1.NoteService_UpdateNote_FullMethodName:     {http.MethodPut, "/api/notes/:id"},
+	logmeupv1.NoteService_DeleteNote_FullMethodName:     {http.MethodDelete, "/api/notes/:id"},
+	logmeupv1.ActionService_CreateAction_FullMethodName: {http.MethodPost, "/api/actions"},
+	logmeupv1.ActionService_UpdateAction_FullMethodName: {http.MethodPut, "/api/actions/:id"},
+	logmeupv1.ActionService_DeleteAction_FullMethodName: {http.MethodDelete, "/api/actions/:id"},
+}
+
+// Options configures the server
+type Options struct {
+	// Limiter counts calls; sharing the REST API's limiter makes a client's
+	// calls to both draw from one budget
+	Limiter *ratelimit.Limiter
+	// RateLimit is the rate allowed per client
+	RateLimit ratelimit.Policy
+	// MaxMessageBytes bounds received messages; zero keeps gRPC's default
+	MaxMessageBytes int
+	// Readiness runs the checks the health service reports; nil always
+	// reports SERVING until the server stops
+	Readiness *handlers.HealthHandler
+	// Reflection registers the reflection service, which lets clients such
+	// as grpcurl list and call the services without the proto file
+	Reflection bool
+	// Credentials secures the transport, typically with TLS; nil serves
+	// plaintext
+	Credentials credentials.TransportCredentials
+}
+
+// New creates a gRPC server with the NoteService, ActionService and the
+// standard health service registered, plus reflection if opts ask for it.
+// Calls are traced and counted in the Prometheus metrics. Action changes
+// are broadcast through hub, which also feeds WatchActions.
+func New(notes repository.NoteStore, actions repository.ActionStore, hub Hub, opts Options) *Server {
+	i := &interceptors{limiter: opts.Limiter, policy: opts.RateLimit}
+	serverOpts := []grpc.ServerOption{
+		tracing.GRPC(),
+		grpc.ChainUnaryInterceptor(metrics.GRPCUnary, i.unary),
+		grpc.ChainStreamInterceptor(metrics.GRPCStream, i.stream),
+	}
+	if opts.MaxMessageBytes > 0 {
+		serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(opts.MaxMessageBytes))
+	}
+	if opts.Credentials != nil {
+		serverOpts = append(serverOpts, grpc.Creds(opts.Credentials))
+	}
+
+	srv := &Server{Server: grpc.NewServer(serverOpts...), health: newHealthService(opts.Readiness)}
+	logmeupv1.RegisterNoteServiceServer(srv, &noteService{notes: notes, hub: hub})
+	logmeupv1.RegisterActionServiceServer(srv, &actionService{actions: actions, hub: hub})
+	healthpb.RegisterHealthServer(srv, srv.health)
+	if opts.Reflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
+
+// interceptors tag each call's context for logging, enforce the rate limit,
+// recover panics and log the outcome
+type interceptors struct {
+	limiter *ratelimit.Limiter
+	policy  ratelimit.Policy
+}
+
+func (i *interceptors) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	ctx = callContext(ctx, info.FullMethod)
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = panicked(ctx, recovered)
+		}
+		logCall(ctx, start, err)
+	}()
+
+	if err := i.allow(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i *interceptors) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := callContext(ss.Context(), info.FullMethod)
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = panicked(ctx, recovered)
+		}
+		logCall(ctx, start, err)
+	}()
+
+	if err := i.allow(ctx, info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// allow takes a call from the client's budget, failing with
+// RESOURCE_EXHAUSTED and a RetryInfo when it is spent
+func (i *interceptors) allow(ctx context.Context, method string) error {
+	if i.limiter == nil {
+		return nil
+	}
+	name, rate := i.policy.For(rateLimitMethod, method)
+	if _, own := i.policy.Routes[rateLimitMethod+" "+method]; !own {
+		if rest, ok := restRoutes[method]; ok {
+			name, rate = i.policy.For(rest.method, rest.route)
+		}
+	}
+	if rate.Unlimited() {
+		return nil
+	}
+
+	result := i.limiter.Allow(clientKey(ctx)+" "+name, rate)
+	if result.Allowed {
+		return nil
+	}
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails([]protoadapt.MessageV1{
+		&errdetails.ErrorInfo{Reason: handlers.CodeRateLimited, Domain: errorDomain},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)},
+	}...)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return st.Err()
+}
+
+// contextStream replaces a stream's context with the tagged one
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// callContext gives a call a logger tagged with its request ID, method and
+// user, like the RequestID middleware does for HTTP requests
+func callContext(ctx context.Context, method string) context.Context {
+	id := firstValue(ctx, requestIDKey)
+	if id == "" || len(id) > 128 {
+		id = uuid.NewString()
+	}
+	user := firstValue(ctx, userKey)
+	if user == "" {
+		user = "anonymous"
+	}
+	return logging.WithAttrs(ctx, "request_id", id, "method", method, "user", user)
+}
+
+// clientKey identifies the client a call counts against by its address, as
+// for REST; metadata naming a key or user is not trusted for it
+func clientKey(ctx context.Context) string {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return ratelimit.ClientKey(ip)
+}
+
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func panicked(ctx context.Context, recovered interface{}) error {
+	logging.FromContext(ctx).Error("grpc handler panicked", "panic", recovered, "stack", string(debug.Stack()))
+	return statusError(codes.Internal, handlers.CodeInternal, "internal server error")
+}
+
+// logCall writes one line per call once it has finished. Server failures
+// are logged at error level, client errors at warn level and everything
+// else at info level.
+func logCall(ctx context.Context, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	logging.FromContext(ctx).Log(ctx, level, "grpc call", "code", code.String(), "latency", time.Since(start))
+}
@@ -0,0 +1,362 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+	logmeupv1 "github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type client struct {
+	srv     *Server
+	notes   logmeupv1.NoteServiceClient
+	actions logmeupv1.ActionServiceClient
+	health  healthpb.HealthClient
+}
+
+// start serves the services over an in-memory connection, with a running
+// hub unless one is given
+func start(t *testing.T, hub Hub, opts Options) *client {
+	t.Helper()
+	if hub == nil {
+		h := websocket.NewHub(nil, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		go h.Run(ctx)
+		t.Cleanup(cancel)
+		hub = h
+	}
+	store := repository.NewMemoryStore()
+	srv := New(repository.NewMemoryNoteRepository(store), repository.NewMemoryActionRepository(store), hub, opts)
+
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{
+		srv:     srv,
+		notes:   logmeupv1.NewNoteServiceClient(conn),
+		actions: logmeupv1.NewActionServiceClient(conn),
+		health:  healthpb.NewHealthClient(conn),
+	}
+}
+
+// reason returns the ErrorInfo reason of a failed call
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestNoteService(t *testing.T) {
+	c := start(t, nil, Options{})
+	ctx := context.Background()
+
+	t.Run("CRUD", func(t *testing.T) {
+		created, err := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Standup", Date: "2026-10-18"})
+		if err != nil {
+			t.Fatalf("Expected the note to be created, got %v", err)
+		}
+		if created.Date != "2026-10-18" || created.CreatedAt == nil {
+			t.Errorf("Expected the date and timestamps to be set, got %v", created)
+		}
+
+		updated, err := c.notes.UpdateNote(ctx, &logmeupv1.UpdateNoteRequest{Id: created.Id, Content: "Retro"})
+		if err != nil || updated.Content != "Retro" {
+			t.Fatalf("Expected the content to be updated, got %v, %v", updated, err)
+		}
+
+		if _, err := c.notes.DeleteNote(ctx, &logmeupv1.DeleteNoteRequest{Id: created.Id}); err != nil {
+			t.Fatalf("Expected the note to be deleted, got %v", err)
+		}
+		_, err = c.notes.GetNote(ctx, &logmeupv1.GetNoteRequest{Id: created.Id})
+		if status.Code(err) != codes.NotFound || reason(err) != handlers.CodeNotFound {
+			t.Errorf("Expected NotFound with reason %s, got %v", handlers.CodeNotFound, err)
+		}
+	})
+
+	t.Run("ListNotes", func(t *testing.T) {
+		for _, date := range []string{"2026-10-10", "2026-10-11", "2026-10-12"} {
+			c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Day", Date: date})
+		}
+
+		resp, err := c.notes.ListNotes(ctx, &logmeupv1.ListNotesRequest{From: "2026-10-10", To: "2026-10-11"})
+		if err != nil {
+			t.Fatalf("Expected the notes, got %v", err)
+		}
+		if len(resp.Notes) != 2 || resp.Notes[0].Date != "2026-10-11" {
+			t.Errorf("Expected 2 notes, latest first, got %v", resp.Notes)
+		}
+
+		resp, _ = c.notes.ListNotes(ctx, &logmeupv1.ListNotesRequest{From: "2026-10-12"})
+		if len(resp.Notes) != 1 {
+			t.Errorf("Expected a single day without to, got %v", resp.Notes)
+		}
+	})
+
+	t.Run("InvalidArguments", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			call  func() error
+			code  string
+			field string
+		}{
+			{"empty content", func() error {
+				_, err := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Date: "2026-10-18"})
+				return err
+			}, handlers.CodeValidation, "content"},
+			{"malformed date", func() error {
+				_, err := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Note", Date: "18/10/2026"})
+				return err
+			}, handlers.CodeInvalidParameter, "date"},
+			{"reversed range", func() error {
+				_, err := c.notes.ListNotes(ctx, &logmeupv1.ListNotesRequest{From: "2026-10-18", To: "2026-10-17"})
+				return err
+			}, handlers.CodeInvalidParameter, "to"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := tt.call()
+				if status.Code(err) != codes.InvalidArgument || reason(err) != tt.code {
+					t.Fatalf("Expected InvalidArgument with reason %s, got %v", tt.code, err)
+				}
+				var fields []string
+				for _, detail := range status.Convert(err).Details() {
+					if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+						for _, violation := range badRequest.FieldViolations {
+							fields = append(fields, violation.Field)
+						}
+					}
+				}
+				if len(fields) != 1 || fields[0] != tt.field {
+					t.Errorf("Expected the %s field to be reported, got %v", tt.field, fields)
+				}
+			})
+		}
+	})
+}
+
+func TestActionService(t *testing.T) {
+	c := start(t, nil, Options{})
+	ctx := context.Background()
+	note, _ := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Note", Date: "2026-10-18"})
+	other, _ := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Other", Date: "2026-10-18"})
+
+	t.Run("ListActions", func(t *testing.T) {
+		open, _ := c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: note.Id, Description: "Open"})
+		done, _ := c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: note.Id, Description: "Done"})
+		c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: other.Id, Description: "Elsewhere"})
+		if _, err := c.actions.UpdateAction(ctx, &logmeupv1.UpdateActionRequest{Id: done.Id, Completed: true}); err != nil {
+			t.Fatalf("Expected the action to be updated, got %v", err)
+		}
+
+		completed := false
+		resp, err := c.actions.ListActions(ctx, &logmeupv1.ListActionsRequest{NoteIds: []int64{note.Id}, Completed: &completed})
+		if err != nil {
+			t.Fatalf("Expected the actions, got %v", err)
+		}
+		if len(resp.Actions) != 1 || resp.Actions[0].Id != open.Id {
+			t.Errorf("Expected only the open action on the note, got %v", resp.Actions)
+		}
+	})
+
+	t.Run("MissingNote", func(t *testing.T) {
+		_, err := c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: 999, Description: "Orphan"})
+		if status.Code(err) != codes.FailedPrecondition || reason(err) != handlers.CodeInvalidReference {
+			t.Errorf("Expected FailedPrecondition with reason %s, got %v", handlers.CodeInvalidReference, err)
+		}
+	})
+
+	t.Run("WatchActions", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		stream, err := c.actions.WatchActions(watchCtx, &logmeupv1.WatchActionsRequest{NoteIds: []int64{note.Id}})
+		if err != nil {
+			t.Fatalf("Expected the stream to open, got %v", err)
+		}
+		// The headers arrive once the stream is subscribed
+		if _, err := stream.Header(); err != nil {
+			t.Fatalf("Expected the stream headers, got %v", err)
+		}
+
+		c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: other.Id, Description: "Filtered out"})
+		created, _ := c.actions.CreateAction(ctx, &logmeupv1.CreateActionRequest{NoteId: note.Id, Description: "Watched"})
+		c.actions.DeleteAction(ctx, &logmeupv1.DeleteActionRequest{Id: created.Id})
+
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected an event, got %v", err)
+		}
+		if event.Type != logmeupv1.ActionEvent_TYPE_CREATED || event.Id != created.Id || event.Action.GetDescription() != "Watched" {
+			t.Errorf("Expected the creation on the watched note, got %v", event)
+		}
+
+		event, err = stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected an event, got %v", err)
+		}
+		if event.Type != logmeupv1.ActionEvent_TYPE_DELETED || event.Id != created.Id || event.Action != nil {
+			t.Errorf("Expected the deletion without an action, got %v", event)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	c := start(t, nil, Options{
+		Limiter: ratelimit.NewLimiter(),
+		RateLimit: ratelimit.Policy{
+			Default: ratelimit.Rate{Limit: 100, Period: time.Minute},
+			Routes: map[string]ratelimit.Rate{
+				"GRPC " + logmeupv1.NoteService_GetNote_FullMethodName: {Limit: 1, Period: time.Minute},
+			},
+		},
+	})
+	note, _ := c.notes.CreateNote(context.Background(), &logmeupv1.CreateNoteRequest{Content: "Note", Date: "2026-10-18"})
+	alice := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "alice-key", "x-user-id", "alice")
+	bob := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "bob-key", "x-user-id", "bob")
+
+	if _, err := c.notes.GetNote(alice, &logmeupv1.GetNoteRequest{Id: note.Id}); err != nil {
+		t.Fatalf("Expected the first call to be allowed, got %v", err)
+	}
+	_, err := c.notes.GetNote(alice, &logmeupv1.GetNoteRequest{Id: note.Id})
+	if status.Code(err) != codes.ResourceExhausted || reason(err) != handlers.CodeRateLimited {
+		t.Fatalf("Expected ResourceExhausted with reason %s, got %v", handlers.CodeRateLimited, err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() <= 0 {
+		t.Errorf("Expected a retry delay, got %v", retry)
+	}
+
+	// Calls from the same address share a budget whatever metadata they send
+	if _, err := c.notes.GetNote(bob, &logmeupv1.GetNoteRequest{Id: note.Id}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected new key and user metadata not to reset the budget, got %v", err)
+	}
+	if _, err := c.notes.ListNotes(alice, &logmeupv1.ListNotesRequest{From: "2026-10-18"}); err != nil {
+		t.Errorf("Expected other methods to use the default budget, got %v", err)
+	}
+}
+
+func TestCreateRateLimit(t *testing.T) {
+	c := start(t, nil, Options{
+		Limiter: ratelimit.NewLimiter(),
+		RateLimit: ratelimit.Policy{Routes: map[string]ratelimit.Rate{
+			"POST /api/notes": {Limit: 1, Period: time.Minute},
+		}},
+	})
+	ctx := context.Background()
+
+	if _, err := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Note", Date: "2026-10-18"}); err != nil {
+		t.Fatalf("Expected the first call to be allowed, got %v", err)
+	}
+	_, err := c.notes.CreateNote(ctx, &logmeupv1.CreateNoteRequest{Content: "Note", Date: "2026-10-18"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected CreateNote to share the POST /api/notes budget, got %v", err)
+	}
+	if _, err := c.notes.ListNotes(ctx, &logmeupv1.ListNotesRequest{From: "2026-10-18"}); err != nil {
+		t.Errorf("Expected reads to use the default budget, got %v", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	var failing atomic.Bool
+	readiness := handlers.NewHealthHandler(time.Second, handlers.HealthCheck{
+		Name: "database",
+		Check: func(ctx context.Context) error {
+			if failing.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	})
+	c := start(t, nil, Options{Readiness: readiness})
+	ctx := context.Background()
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Expected the health check to answer, got %v", err)
+		}
+		return resp.Status
+	}
+
+	t.Run("Ready", func(t *testing.T) {
+		for _, service := range []string{"", "logmeup.v1.NoteService", "logmeup.v1.ActionService"} {
+			if got := check(service); got != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("Expected %q to be SERVING, got %v", service, got)
+			}
+		}
+	})
+
+	t.Run("FailingCheck", func(t *testing.T) {
+		failing.Store(true)
+		defer failing.Store(false)
+		if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("Expected NOT_SERVING while a readiness check fails, got %v", got)
+		}
+	})
+
+	t.Run("UnknownService", func(t *testing.T) {
+		_, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: "logmeup.v1.Missing"})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NOT_FOUND, got %v", err)
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		c.srv.Stop()
+		resp, err := c.srv.health.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("Expected the health check to answer, got %v", err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("Expected NOT_SERVING once stopped, got %v", resp.Status)
+		}
+	})
+}
+
+func TestReflection(t *testing.T) {
+	const service = "grpc.reflection.v1.ServerReflection"
+	hub := websocket.NewHub(nil, nil)
+	store := repository.NewMemoryStore()
+	notes, actions := repository.NewMemoryNoteRepository(store), repository.NewMemoryActionRepository(store)
+
+	if _, ok := New(notes, actions, hub, Options{}).GetServiceInfo()[service]; ok {
+		t.Error("Expected reflection to be off by default")
+	}
+	if _, ok := New(notes, actions, hub, Options{Reflection: true}).GetServiceInfo()[service]; !ok {
+		t.Error("Expected reflection to be registered when enabled")
+	}
+}
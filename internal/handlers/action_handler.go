@@ -1,9 +1,10 @@
 package handlers
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,262 +14,186 @@ import (
 
 // WebSocketHub interface for broadcasting
 type WebSocketHub interface {
-	BroadcastActionCreated(action *models.Action)
-	BroadcastActionUpdated(action *models.Action)
-	BroadcastActionDeleted(actionID int64)
+	BroadcastActionCreated(ctx context.Context, action *models.Action)
+	BroadcastActionUpdated(ctx context.Context, action *models.Action)
+	BroadcastActionDeleted(ctx context.Context, actionID int64)
 }
 
 type ActionHandler struct {
-	repo *repository.ActionRepository
-	hub  WebSocketHub
+	repo    repository.ActionStore
+	hub     WebSocketHub
+	present Presenter
 }
 
-func NewActionHandler(repo *repository.ActionRepository, hub WebSocketHub) *ActionHandler {
-	log.Printf("[ActionHandler] Initializing action handler with WebSocket support")
+func NewActionHandler(repo repository.ActionStore, hub WebSocketHub) *ActionHandler {
 	return &ActionHandler{
-		repo: repo,
-		hub:  hub,
+		repo:    repo,
+		hub:     hub,
+		present: V1{},
 	}
 }
 
-// Helper function to log request details
-func logRequest(c *gin.Context, operation string, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	clientIP := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-
-	log.Printf("[ActionHandler-%s] %s | IP: %s | User-Agent: %s | Details: %v",
-		operation, timestamp, clientIP, userAgent, details)
-}
-
-// Helper function to log errors with context
-func logError(c *gin.Context, operation string, err error, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	clientIP := c.ClientIP()
-
-	log.Printf("[ActionHandler-%s-ERROR] %s | IP: %s | Error: %v | Details: %v",
-		operation, timestamp, clientIP, err, details)
-}
-
-// Helper function to log successful operations
-func logSuccess(c *gin.Context, operation string, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-
-	log.Printf("[ActionHandler-%s-SUCCESS] %s | Details: %v",
-		operation, timestamp, details)
+// WithPresenter returns a handler sharing h's store and hub that shapes
+// responses with p, for another API version
+func (h *ActionHandler) WithPresenter(p Presenter) *ActionHandler {
+	copied := *h
+	copied.present = p
+	return &copied
 }
 
 func (h *ActionHandler) Create(c *gin.Context) {
-	logRequest(c, "Create", "Starting action creation")
-
 	var req models.CreateActionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logError(c, "Create", err, "Failed to bind JSON request")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-			"code":  "INVALID_JSON",
-		})
+		abortWithError(c, bindingError(err))
 		return
 	}
 
-	logRequest(c, "Create", "Request data", map[string]interface{}{
-		"note_id":     req.NoteID,
-		"description": req.Description,
-	})
-
-	action, err := h.repo.Create(&req)
+	action, err := h.repo.Create(c.Request.Context(), &req)
 	if err != nil {
-		logError(c, "Create", err, "Database creation failed", req)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-			"code":  "DATABASE_ERROR",
-		})
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "Create", "Action created successfully", map[string]interface{}{
-		"action_id":   action.ID,
-		"note_id":     action.NoteID,
-		"description": action.Description,
-	})
+	h.hub.BroadcastActionCreated(c.Request.Context(), action)
 
-	h.hub.BroadcastActionCreated(action)
-
-	c.JSON(http.StatusCreated, action)
+	h.present.Record(c, http.StatusCreated, action)
 }
 
 func (h *ActionHandler) GetByID(c *gin.Context) {
-	idParam := c.Param("id")
-	logRequest(c, "GetByID", "Fetching action by ID", idParam)
-
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		logError(c, "GetByID", err, "Invalid ID parameter", idParam)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid id",
-			"code":  "INVALID_ID",
-		})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
 		return
 	}
 
-	action, err := h.repo.GetByID(id)
+	action, err := h.repo.GetByID(c.Request.Context(), id)
 	if err != nil {
-		logError(c, "GetByID", err, "Action not found in database", id)
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "action not found",
-			"code":  "NOT_FOUND",
-		})
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "GetByID", "Action retrieved successfully", map[string]interface{}{
-		"action_id":   action.ID,
-		"description": action.Description,
-		"completed":   action.Completed,
-	})
-
-	c.JSON(http.StatusOK, action)
+	h.present.Record(c, http.StatusOK, action)
 }
 
+// GetAll lists every action, or those matching the filter query parameters
+// read by actionFilter
 func (h *ActionHandler) GetAll(c *gin.Context) {
-	logRequest(c, "GetAll", "Fetching all actions")
+	filter, ok := actionFilter(c)
+	if !ok {
+		return
+	}
 
-	actions, err := h.repo.GetAll()
+	actions, err := h.repo.Find(c.Request.Context(), filter)
 	if err != nil {
-		logError(c, "GetAll", err, "Failed to retrieve actions from database")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-			"code":  "DATABASE_ERROR",
-		})
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "GetAll", "Actions retrieved successfully", map[string]interface{}{
-		"count": len(actions),
-	})
-
-	c.JSON(http.StatusOK, actions)
+	h.present.List(c, actions)
 }
 
 func (h *ActionHandler) GetByNoteID(c *gin.Context) {
-	noteIDParam := c.Param("note_id")
-	logRequest(c, "GetByNoteID", "Fetching actions by note ID", noteIDParam)
-
-	noteID, err := strconv.ParseInt(noteIDParam, 10, 64)
+	noteID, err := strconv.ParseInt(c.Param("note_id"), 10, 64)
 	if err != nil {
-		logError(c, "GetByNoteID", err, "Invalid note ID parameter", noteIDParam)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid note_id",
-			"code":  "INVALID_NOTE_ID",
-		})
+		abortWithError(c, invalidParameter("note_id", "must be an integer"))
 		return
 	}
 
-	actions, err := h.repo.GetByNoteID(noteID)
+	actions, err := h.repo.GetByNoteID(c.Request.Context(), noteID)
 	if err != nil {
-		logError(c, "GetByNoteID", err, "Failed to retrieve actions by note ID", noteID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-			"code":  "DATABASE_ERROR",
-		})
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "GetByNoteID", "Actions retrieved successfully", map[string]interface{}{
-		"note_id": noteID,
-		"count":   len(actions),
-	})
-
-	c.JSON(http.StatusOK, actions)
+	h.present.List(c, actions)
 }
 
 func (h *ActionHandler) Update(c *gin.Context) {
-	idParam := c.Param("id")
-	logRequest(c, "Update", "Starting action update", idParam)
-
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		logError(c, "Update", err, "Invalid ID parameter", idParam)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid id",
-			"code":  "INVALID_ID",
-		})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
 		return
 	}
 
 	var req models.UpdateActionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logError(c, "Update", err, "Failed to bind JSON request", id)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-			"code":  "INVALID_JSON",
-		})
+		abortWithError(c, bindingError(err))
 		return
 	}
 
-	logRequest(c, "Update", "Update data", map[string]interface{}{
-		"action_id": id,
-		"completed": req.Completed,
-	})
-
-	action, err := h.repo.Update(id, &req)
+	action, _, err := h.repo.Update(c.Request.Context(), id, &req)
 	if err != nil {
-		logError(c, "Update", err, "Database update failed", map[string]interface{}{
-			"action_id": id,
-			"completed": req.Completed,
-		})
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-			"code":  "DATABASE_ERROR",
-		})
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "Update", "Action updated successfully", map[string]interface{}{
-		"action_id":  action.ID,
-		"completed":  action.Completed,
-		"updated_at": action.UpdatedAt,
-	})
-
-	h.hub.BroadcastActionUpdated(action)
+	h.hub.BroadcastActionUpdated(c.Request.Context(), action)
 
-	c.JSON(http.StatusOK, action)
+	h.present.Record(c, http.StatusOK, action)
 }
 
 func (h *ActionHandler) Delete(c *gin.Context) {
-	idParam := c.Param("id")
-	logRequest(c, "Delete", "Starting action deletion", idParam)
-
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		logError(c, "Delete", err, "Invalid ID parameter", idParam)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid id",
-			"code":  "INVALID_ID",
-		})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		logError(c, "Delete", err, "Database deletion failed", id)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-			"code":  "DATABASE_ERROR",
-		})
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		abortWithError(c, repositoryError(err, "action"))
 		return
 	}
 
-	logSuccess(c, "Delete", "Action deleted successfully", map[string]interface{}{
-		"action_id": id,
-	})
-
-	h.hub.BroadcastActionDeleted(id)
+	h.hub.BroadcastActionDeleted(c.Request.Context(), id)
 
 	c.Status(http.StatusNoContent)
 }
 
+// Health answers HEAD /api/v1/actions for older clients; probes should use
+// /healthz and /readyz
 func (h *ActionHandler) Health(c *gin.Context) {
-	logRequest(c, "Health", "Health check request")
-	logSuccess(c, "Health", "Health check successful")
 	c.Status(http.StatusOK)
 }
+
+// actionFilter reads the action filter query parameters: note_id, repeated
+// or comma separated, completed, and the created_after and created_before
+// RFC 3339 timestamps. It answers the request with a problem when one is
+// malformed.
+func actionFilter(c *gin.Context) (models.ActionFilter, bool) {
+	var filter models.ActionFilter
+	for _, value := range c.QueryArray("note_id") {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				abortWithError(c, invalidParameter("note_id", "must be integers"))
+				return filter, false
+			}
+			filter.NoteIDs = append(filter.NoteIDs, id)
+		}
+	}
+	if value, ok := c.GetQuery("completed"); ok {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			abortWithError(c, invalidParameter("completed", "must be true or false"))
+			return filter, false
+		}
+		filter.Completed = &completed
+	}
+	bounds := []struct {
+		name string
+		time *time.Time
+	}{{"created_after", &filter.CreatedAfter}, {"created_before", &filter.CreatedBefore}}
+	for _, bound := range bounds {
+		value, ok := c.GetQuery(bound.name)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			abortWithError(c, invalidParameter(bound.name, "must be an RFC 3339 timestamp"))
+			return filter, false
+		}
+		*bound.time = t
+	}
+	return filter, true
+}
@@ -2,29 +2,42 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tehsis/logmeup-api/internal/models"
 	"github.com/tehsis/logmeup-api/internal/repository"
-	"github.com/tehsis/logmeup-api/internal/testutil"
 )
 
-func setupActionTestRouter(t *testing.T) (*gin.Engine, *repository.ActionRepository, *repository.NoteRepository) {
+// nopHub discards broadcasts so handlers can be tested without a hub
+type nopHub struct{}
+
+func (nopHub) BroadcastActionCreated(ctx context.Context, action *models.Action) {}
+func (nopHub) BroadcastActionUpdated(ctx context.Context, action *models.Action) {}
+func (nopHub) BroadcastActionDeleted(ctx context.Context, actionID int64)        {}
+func (nopHub) BroadcastNoteCreated(ctx context.Context, note *models.Note)       {}
+func (nopHub) BroadcastNoteUpdated(ctx context.Context, note *models.Note)       {}
+func (nopHub) BroadcastNoteDeleted(ctx context.Context, noteID int64)            {}
+
+func setupActionTestRouter(t *testing.T) (*gin.Engine, repository.ActionStore, repository.NoteStore) {
 	gin.SetMode(gin.TestMode)
-	db := testutil.SetupTestDB(t)
-	testutil.SetupTestSchema(t, db)
+	store := repository.NewMemoryStore()
 
-	noteRepo := repository.NewNoteRepository(db)
-	actionRepo := repository.NewActionRepository(db)
-	actionHandler := NewActionHandler(actionRepo)
+	noteRepo := repository.NewMemoryNoteRepository(store)
+	actionRepo := repository.NewMemoryActionRepository(store)
+	actionHandler := NewActionHandler(actionRepo, nopHub{})
 
 	r := gin.Default()
 	r.POST("/api/actions", actionHandler.Create)
+	r.GET("/api/actions", actionHandler.GetAll)
 	r.GET("/api/actions/:id", actionHandler.GetByID)
 	r.GET("/api/actions/note/:note_id", actionHandler.GetByNoteID)
 	r.PUT("/api/actions/:id", actionHandler.Update)
@@ -42,7 +55,7 @@ func TestActionHandler(t *testing.T) {
 			Content: "Test note for action",
 			Date:    time.Now(),
 		}
-		createdNote, err := noteRepo.Create(note)
+		createdNote, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -84,7 +97,7 @@ func TestActionHandler(t *testing.T) {
 			Content: "Test note for action",
 			Date:    time.Now(),
 		}
-		createdNote, err := noteRepo.Create(note)
+		createdNote, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -93,12 +106,12 @@ func TestActionHandler(t *testing.T) {
 			NoteID:      createdNote.ID,
 			Description: "Test action for GetByID",
 		}
-		createdAction, err := actionRepo.Create(action)
+		createdAction, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
 
-		req := httptest.NewRequest(http.MethodGet, "/api/actions/"+string(createdAction.ID), nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/actions/"+strconv.FormatInt(createdAction.ID, 10), nil)
 		w := httptest.NewRecorder()
 
 		r.ServeHTTP(w, req)
@@ -125,7 +138,7 @@ func TestActionHandler(t *testing.T) {
 			Content: "Test note for actions",
 			Date:    time.Now(),
 		}
-		createdNote, err := noteRepo.Create(note)
+		createdNote, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -137,13 +150,13 @@ func TestActionHandler(t *testing.T) {
 		}
 
 		for _, action := range actions {
-			_, err := actionRepo.Create(action)
+			_, err := actionRepo.Create(context.Background(), action)
 			if err != nil {
 				t.Fatalf("Failed to create test action: %v", err)
 			}
 		}
 
-		req := httptest.NewRequest(http.MethodGet, "/api/actions/note/"+string(createdNote.ID), nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/actions/note/"+strconv.FormatInt(createdNote.ID, 10), nil)
 		w := httptest.NewRecorder()
 
 		r.ServeHTTP(w, req)
@@ -162,6 +175,50 @@ func TestActionHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("GetAllFiltered", func(t *testing.T) {
+		r, actionRepo, noteRepo := setupActionTestRouter(t)
+		first, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "First", Date: time.Now()})
+		second, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Second", Date: time.Now()})
+		open, _ := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: first.ID, Description: "Open"})
+		done, _ := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: second.ID, Description: "Done"})
+		actionRepo.Update(context.Background(), done.ID, &models.UpdateActionRequest{Completed: true})
+		other, _ := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: second.ID, Description: "Other"})
+
+		tests := []struct {
+			name  string
+			query string
+			ids   []int64
+		}{
+			{"note", "?note_id=" + strconv.FormatInt(first.ID, 10), []int64{open.ID}},
+			{"notes", "?note_id=" + strconv.FormatInt(first.ID, 10) + "," + strconv.FormatInt(second.ID, 10) + "&completed=true", []int64{done.ID}},
+			{"created after", "?completed=false&created_after=" + open.CreatedAt.Format(time.RFC3339Nano), []int64{other.ID}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/actions"+tt.query, nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				var actions []models.Action
+				json.Unmarshal(w.Body.Bytes(), &actions)
+				var ids []int64
+				for _, action := range actions {
+					ids = append(ids, action.ID)
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+					t.Errorf("Expected actions %v, got %v", tt.ids, ids)
+				}
+			})
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/actions?completed=maybe", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for a malformed filter, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
 	t.Run("Update", func(t *testing.T) {
 		r, actionRepo, noteRepo := setupActionTestRouter(t)
 
@@ -170,7 +227,7 @@ func TestActionHandler(t *testing.T) {
 			Content: "Test note for action",
 			Date:    time.Now(),
 		}
-		createdNote, err := noteRepo.Create(note)
+		createdNote, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -179,7 +236,7 @@ func TestActionHandler(t *testing.T) {
 			NoteID:      createdNote.ID,
 			Description: "Test action for Update",
 		}
-		createdAction, err := actionRepo.Create(action)
+		createdAction, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
@@ -189,7 +246,7 @@ func TestActionHandler(t *testing.T) {
 		}
 		body, _ := json.Marshal(update)
 
-		req := httptest.NewRequest(http.MethodPut, "/api/actions/"+string(createdAction.ID), bytes.NewBuffer(body))
+		req := httptest.NewRequest(http.MethodPut, "/api/actions/"+strconv.FormatInt(createdAction.ID, 10), bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
@@ -217,7 +274,7 @@ func TestActionHandler(t *testing.T) {
 			Content: "Test note for action",
 			Date:    time.Now(),
 		}
-		createdNote, err := noteRepo.Create(note)
+		createdNote, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -226,12 +283,12 @@ func TestActionHandler(t *testing.T) {
 			NoteID:      createdNote.ID,
 			Description: "Test action for Delete",
 		}
-		createdAction, err := actionRepo.Create(action)
+		createdAction, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
 
-		req := httptest.NewRequest(http.MethodDelete, "/api/actions/"+string(createdAction.ID), nil)
+		req := httptest.NewRequest(http.MethodDelete, "/api/actions/"+strconv.FormatInt(createdAction.ID, 10), nil)
 		w := httptest.NewRecorder()
 
 		r.ServeHTTP(w, req)
@@ -241,9 +298,49 @@ func TestActionHandler(t *testing.T) {
 		}
 
 		// Verify action is deleted
-		_, err = actionRepo.GetByID(createdAction.ID)
+		_, err = actionRepo.GetByID(context.Background(), createdAction.ID)
 		if err == nil {
 			t.Error("Expected error when getting deleted action")
 		}
 	})
+	t.Run("RepositoryErrors", func(t *testing.T) {
+		r, _, _ := setupActionTestRouter(t)
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   string
+			status int
+			code   string
+		}{
+			{"missing action", http.MethodGet, "/api/actions/999", "", http.StatusNotFound, "NOT_FOUND"},
+			{"delete missing action", http.MethodDelete, "/api/actions/999", "", http.StatusNotFound, "NOT_FOUND"},
+			{"missing note", http.MethodPost, "/api/actions", `{"note_id":999,"description":"Orphan"}`, http.StatusUnprocessableEntity, "INVALID_REFERENCE"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+
+				r.ServeHTTP(w, req)
+
+				if w.Code != tt.status {
+					t.Errorf("Expected status code %d, got %d", tt.status, w.Code)
+				}
+				if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+					t.Errorf("Expected a problem response, got content type %q", ct)
+				}
+				var problem Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if problem.Code != tt.code || problem.Status != tt.status || problem.Instance != tt.path {
+					t.Errorf("Unexpected problem %+v", problem)
+				}
+			})
+		}
+	})
 }
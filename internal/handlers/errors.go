@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// Error codes returned in the "code" member of every problem response.
+// Clients should switch on these rather than on the human readable detail.
+const (
+	// CodeInvalidJSON means the request body could not be decoded
+	CodeInvalidJSON = "INVALID_JSON"
+	// CodeInvalidCSV means an uploaded CSV file could not be read
+	CodeInvalidCSV = "INVALID_CSV"
+	// CodeValidation means the body decoded but some fields are invalid; the
+	// problem lists them in "errors"
+	CodeValidation = "VALIDATION_ERROR"
+	// CodeInvalidParameter means a path or query parameter is malformed
+	CodeInvalidParameter = "INVALID_PARAMETER"
+	// CodeNotFound means the record or route does not exist
+	CodeNotFound = "NOT_FOUND"
+	// CodeBodyTooLarge means the request body is over the size limit
+	CodeBodyTooLarge = "BODY_TOO_LARGE"
+	// CodeRateLimited means the client made too many requests; retry after
+	// the number of seconds in the Retry-After header
+	CodeRateLimited = "RATE_LIMITED"
+	// CodeMethodNotAllowed means the route exists but not for this method
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	// CodeInvalidReference means the request points at a record that does
+	// not exist, such as an action for a missing note
+	CodeInvalidReference = "INVALID_REFERENCE"
+	// CodeConflict means the record clashes with an existing one
+	CodeConflict = "CONFLICT"
+	// CodeTimeout means the database did not answer in time; retrying may work
+	CodeTimeout = "DATABASE_TIMEOUT"
+	// CodeCanceled means the client gave up on the request before it finished
+	CodeCanceled = "REQUEST_CANCELED"
+	// CodeDatabaseError means the database failed in an unexpected way
+	CodeDatabaseError = "DATABASE_ERROR"
+	// CodeInternal means the server failed for any other reason
+	CodeInternal = "INTERNAL_ERROR"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix turns a code into the problem "type" URI
+const problemTypePrefix = "urn:logmeup:problem:"
+
+// Problem is the body of every error response
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// apiError is what a client is told about a failed request
+type apiError struct {
+	status  int
+	code    string
+	message string
+	fields  []FieldError
+	// err is the underlying failure, kept for the request log
+	err error
+}
+
+// abortWithError ends the request with a problem response
+func abortWithError(c *gin.Context, apiErr apiError) {
+	problem := Problem{
+		Type:      problemTypePrefix + strings.ToLower(strings.ReplaceAll(apiErr.code, "_", "-")),
+		Title:     http.StatusText(apiErr.status),
+		Status:    apiErr.status,
+		Detail:    apiErr.message,
+		Instance:  c.Request.URL.Path,
+		Code:      apiErr.code,
+		RequestID: RequestIDFrom(c),
+		Errors:    apiErr.fields,
+	}
+	if apiErr.err != nil {
+		c.Error(apiErr.err)
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(apiErr.status, problem)
+}
+
+// invalidParameter is the error for a malformed path or query parameter
+func invalidParameter(name, message string) apiError {
+	return apiError{
+		status:  http.StatusBadRequest,
+		code:    CodeInvalidParameter,
+		message: "invalid " + name,
+		fields:  []FieldError{{Field: name, Rule: "format", Message: message}},
+	}
+}
+
+// StoreFailure is what a client is told about a failed store call, whatever
+// the transport. Each transport maps Code to its own status.
+type StoreFailure struct {
+	Code    string
+	Message string
+}
+
+// ClassifyStoreError maps an error from a store to the code and message for
+// it. The message never includes database details; resource names the kind
+// of record the call was about. Errors it does not recognise are
+// CodeDatabaseError, which callers should log.
+func ClassifyStoreError(err error, resource string) StoreFailure {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return StoreFailure{CodeNotFound, resource + " not found"}
+	case errors.Is(err, repository.ErrForeignKey):
+		return StoreFailure{CodeInvalidReference, "referenced record does not exist"}
+	case errors.Is(err, repository.ErrConflict):
+		return StoreFailure{CodeConflict, resource + " already exists"}
+	case errors.Is(err, repository.ErrValidation):
+		return StoreFailure{CodeValidation, "invalid " + resource}
+	case errors.Is(err, context.DeadlineExceeded):
+		return StoreFailure{CodeTimeout, "database timeout"}
+	case errors.Is(err, context.Canceled):
+		return StoreFailure{CodeCanceled, "request canceled"}
+	}
+	return StoreFailure{CodeDatabaseError, "internal server error"}
+}
+
+// storeStatus is the HTTP status for each code ClassifyStoreError returns
+var storeStatus = map[string]int{
+	CodeNotFound:         http.StatusNotFound,
+	CodeInvalidReference: http.StatusUnprocessableEntity,
+	CodeConflict:         http.StatusConflict,
+	CodeValidation:       http.StatusBadRequest,
+	CodeTimeout:          http.StatusGatewayTimeout,
+	// The client has gone, so this status only reaches the request log
+	CodeCanceled:      http.StatusRequestTimeout,
+	CodeDatabaseError: http.StatusInternalServerError,
+}
+
+// AbortInvalidParameter ends the request with the problem for a malformed
+// path or query parameter or header, for handlers outside this package
+func AbortInvalidParameter(c *gin.Context, name, message string) {
+	abortWithError(c, invalidParameter(name, message))
+}
+
+// repositoryError maps an error from a store to the response for it
+func repositoryError(err error, resource string) apiError {
+	failure := ClassifyStoreError(err, resource)
+	return apiError{status: storeStatus[failure.Code], code: failure.Code, message: failure.Message, err: err}
+}
+
+// ValidationFields lists the fields a validator error reports, each with
+// its failed rule in plain words; name gives the name a client knows the
+// field by. ok is false for any other error.
+func ValidationFields(err error, name func(validator.FieldError) string) (fields []FieldError, ok bool) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, false
+	}
+	fields = make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, FieldError{Field: name(fe), Rule: fe.Tag(), Message: fieldMessage(fe)})
+	}
+	return fields, true
+}
+
+// bindingError maps a ShouldBindJSON failure to a response listing the
+// offending fields by their JSON names, without exposing validator internals.
+func bindingError(err error) apiError {
+	apiErr := decodeError(err)
+	apiErr.err = err
+	return apiErr
+}
+
+func decodeError(err error) apiError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+	var sizeErr *http.MaxBytesError
+
+	if errors.As(err, &sizeErr) {
+		return bodyTooLarge(sizeErr.Limit)
+	}
+	if fields, ok := ValidationFields(err, validator.FieldError.Field); ok {
+		return apiError{status: http.StatusBadRequest, code: CodeValidation, message: "request body has invalid fields", fields: fields}
+	}
+	switch {
+	case errors.As(err, &typeErr):
+		field := FieldError{Field: typeErr.Field, Rule: "type", Message: "must be a " + typeErr.Type.String()}
+		return apiError{status: http.StatusBadRequest, code: CodeValidation, message: "request body has invalid fields", fields: []FieldError{field}}
+	case errors.As(err, &timeErr):
+		return apiError{status: http.StatusBadRequest, code: CodeValidation, message: "timestamps must be in RFC 3339 format"}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return apiError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "request body is not valid JSON"}
+	}
+	return apiError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "request body could not be decoded"}
+}
+
+// fieldMessage describes a failed validation rule in plain words
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return "must be at most " + fe.Param() + " characters"
+	case "min":
+		return "must be at least " + fe.Param() + " characters"
+	}
+	return "failed the " + fe.Tag() + " rule"
+}
+
+// jsonFieldName makes validation errors report the JSON name of a field
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+func TestClassifyStoreError(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    string
+		message string
+		status  int
+	}{
+		{repository.ErrNotFound, CodeNotFound, "note not found", http.StatusNotFound},
+		{repository.ErrForeignKey, CodeInvalidReference, "referenced record does not exist", http.StatusUnprocessableEntity},
+		{repository.ErrConflict, CodeConflict, "note already exists", http.StatusConflict},
+		{repository.ErrValidation, CodeValidation, "invalid note", http.StatusBadRequest},
+		{context.DeadlineExceeded, CodeTimeout, "database timeout", http.StatusGatewayTimeout},
+		{context.Canceled, CodeCanceled, "request canceled", http.StatusRequestTimeout},
+		{errors.New("connection reset"), CodeDatabaseError, "internal server error", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := fmt.Errorf("query note: %w", tt.err)
+			failure := ClassifyStoreError(err, "note")
+			if failure.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, failure.Code)
+			}
+			if failure.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, failure.Message)
+			}
+			if apiErr := repositoryError(err, "note"); apiErr.status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, apiErr.status)
+			}
+		})
+	}
+}
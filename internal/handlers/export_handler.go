@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/backup"
+	"github.com/tehsis/logmeup-api/internal/export"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// ExportHandler serves exports of notes and their actions, and restores
+// backups. Actions imported from CSV are broadcast through hub like those
+// created one at a time.
+type ExportHandler struct {
+	notes   repository.NoteStore
+	actions repository.ActionStore
+	backups repository.BackupStore
+	hub     WebSocketHub
+}
+
+func NewExportHandler(notes repository.NoteStore, actions repository.ActionStore, backups repository.BackupStore, hub WebSocketHub) *ExportHandler {
+	return &ExportHandler{notes: notes, actions: actions, backups: backups, hub: hub}
+}
+
+// Markdown streams a zip archive with a Markdown file for each day from the
+// from query date to the to one, inclusive. Days are read and written one at
+// a time; a failure before the first day is written gets a problem response.
+func (h *ExportHandler) Markdown(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	var archive *export.ZipWriter
+	start := func() {
+		name := "logmeup-" + from.Format("2006-01-02") + "-" + to.Format("2006-01-02") + ".zip"
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+		c.Status(http.StatusOK)
+		archive = export.NewZipWriter(c.Writer)
+	}
+	err := export.EachDay(c.Request.Context(), h.notes, h.actions, from, to, func(day export.Day) error {
+		if archive == nil {
+			start()
+		}
+		return archive.Add(day)
+	})
+	if archive == nil {
+		if err != nil {
+			abortWithError(c, repositoryError(err, "note"))
+			return
+		}
+		start()
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		// The status is already sent, so the client is left with a
+		// truncated archive
+		logging.FromContext(c.Request.Context()).Error("writing markdown export failed", "error", err)
+		c.Error(err)
+	}
+}
+
+// JSON streams a backup of every note and action
+func (h *ExportHandler) JSON(c *gin.Context) {
+	name := "logmeup-backup-" + time.Now().UTC().Format("2006-01-02") + ".json"
+	c.Header("Content-Type", "application/json")
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Status(http.StatusOK)
+	if err := backup.Write(c.Request.Context(), c.Writer, h.backups); err != nil {
+		// As with Markdown, the client is left with a truncated document
+		logging.FromContext(c.Request.Context()).Error("writing json backup failed", "error", err)
+		c.Error(err)
+	}
+}
+
+// ImportJSON restores a backup sent as the body. The mode query parameter
+// picks how records already stored are treated; the import happens in one
+// transaction and is reported as a backup.Report.
+func (h *ExportHandler) ImportJSON(c *gin.Context) {
+	mode := backup.Mode(c.DefaultQuery("mode", string(backup.ModeMerge)))
+	if !mode.Valid() {
+		abortWithError(c, invalidParameter("mode", "must be merge, replace or dry-run"))
+		return
+	}
+
+	var doc backup.Document
+	if err := json.NewDecoder(c.Request.Body).Decode(&doc); err != nil {
+		abortWithError(c, bindingError(err))
+		return
+	}
+
+	report, err := backup.Import(c.Request.Context(), h.backups, &doc, mode)
+	var invalid *backup.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]FieldError, len(invalid.Fields))
+		for i, field := range invalid.Fields {
+			fields[i] = FieldError{Field: field.Field, Rule: field.Rule, Message: field.Message}
+		}
+		abortWithError(c, apiError{
+			status:  http.StatusBadRequest,
+			code:    CodeValidation,
+			message: "backup has invalid fields",
+			fields:  fields,
+			err:     err,
+		})
+		return
+	case err != nil:
+		abortWithError(c, repositoryError(err, "backup"))
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("backup imported",
+		"mode", mode,
+		"notes_created", report.Notes.Created,
+		"actions_created", report.Actions.Created,
+		"conflicts", len(report.Conflicts),
+	)
+	c.JSON(http.StatusOK, report)
+}
+
+// CSV downloads the actions matching the filter query parameters of the
+// action list as CSV, with the date and an excerpt of each one's note
+func (h *ExportHandler) CSV(c *gin.Context) {
+	filter, ok := actionFilter(c)
+	if !ok {
+		return
+	}
+
+	actions, err := h.actions.Find(c.Request.Context(), filter)
+	if err != nil {
+		abortWithError(c, repositoryError(err, "action"))
+		return
+	}
+	notes, err := export.LoadActionNotes(c.Request.Context(), h.notes, actions)
+	if err != nil {
+		abortWithError(c, repositoryError(err, "note"))
+		return
+	}
+
+	name := "logmeup-actions-" + time.Now().UTC().Format("2006-01-02") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Status(http.StatusOK)
+	if err := export.WriteActionsCSV(c.Writer, actions, notes); err != nil {
+		logging.FromContext(c.Request.Context()).Error("writing actions csv failed", "error", err)
+		c.Error(err)
+	}
+}
+
+// ImportCSV creates actions from a CSV body, matching them to notes by
+// date. With preview=true it only reports the rows it would create and the
+// invalid ones; otherwise any invalid row fails the whole import.
+func (h *ExportHandler) ImportCSV(c *gin.Context) {
+	preview := false
+	if value, ok := c.GetQuery("preview"); ok {
+		var err error
+		if preview, err = strconv.ParseBool(value); err != nil {
+			abortWithError(c, invalidParameter("preview", "must be true or false"))
+			return
+		}
+	}
+
+	result, err := export.ImportActionsCSV(c.Request.Context(), c.Request.Body, h.notes, h.actions, preview)
+	var csvErr *export.CSVError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &csvErr):
+		abortWithError(c, apiError{status: http.StatusBadRequest, code: CodeInvalidCSV, message: csvErr.Error(), err: err})
+		return
+	case errors.As(err, &sizeErr):
+		abortWithError(c, bodyTooLarge(sizeErr.Limit))
+		return
+	case err != nil:
+		abortWithError(c, repositoryError(err, "action"))
+		return
+	}
+
+	if !preview && len(result.Errors) > 0 {
+		fields := make([]FieldError, len(result.Errors))
+		for i, rowErr := range result.Errors {
+			field := fmt.Sprintf("rows[%d]", rowErr.Line)
+			if rowErr.Column != "" {
+				field += "." + rowErr.Column
+			}
+			fields[i] = FieldError{Field: field, Rule: rowErr.Rule, Message: rowErr.Message}
+		}
+		abortWithError(c, apiError{
+			status:  http.StatusBadRequest,
+			code:    CodeValidation,
+			message: "CSV has invalid rows; nothing was imported",
+			fields:  fields,
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if result.Created > 0 {
+		status = http.StatusCreated
+		logging.FromContext(c.Request.Context()).Info("actions imported", "created", result.Created)
+	}
+	for _, action := range result.Actions {
+		h.hub.BroadcastActionCreated(c.Request.Context(), action)
+	}
+	c.JSON(status, result)
+}
+
+// dateRange reads the from and to query dates, answering the request with a
+// problem when they are missing, malformed or reversed
+func dateRange(c *gin.Context) (from, to time.Time, ok bool) {
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		abortWithError(c, invalidParameter("from", "must be a date in YYYY-MM-DD format"))
+		return from, to, false
+	}
+	to, err = time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		abortWithError(c, invalidParameter("to", "must be a date in YYYY-MM-DD format"))
+		return from, to, false
+	}
+	if to.Before(from) {
+		abortWithError(c, invalidParameter("to", "must not be before from"))
+		return from, to, false
+	}
+	return from, to, true
+}
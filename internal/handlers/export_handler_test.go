@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/backup"
+	"github.com/tehsis/logmeup-api/internal/export"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// createdHub records the actions broadcast as created
+type createdHub struct {
+	nopHub
+	created []*models.Action
+}
+
+func (h *createdHub) BroadcastActionCreated(ctx context.Context, action *models.Action) {
+	h.created = append(h.created, action)
+}
+
+// slowNoteStore times out listing notes by date
+type slowNoteStore struct {
+	repository.NoteStore
+}
+
+func (slowNoteStore) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	return nil, context.DeadlineExceeded
+}
+
+func setupExportRouter(t *testing.T) (*gin.Engine, repository.NoteStore, repository.ActionStore) {
+	r, noteRepo, actionRepo, _ := setupExportRouterWithHub(t)
+	return r, noteRepo, actionRepo
+}
+
+func setupExportRouterWithHub(t *testing.T) (*gin.Engine, repository.NoteStore, repository.ActionStore, *createdHub) {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	noteRepo := repository.NewMemoryNoteRepository(store)
+	actionRepo := repository.NewMemoryActionRepository(store)
+	hub := &createdHub{}
+	exportHandler := NewExportHandler(noteRepo, actionRepo, repository.NewMemoryBackupRepository(store), hub)
+
+	r := gin.New()
+	r.GET("/api/export/markdown", exportHandler.Markdown)
+	r.GET("/api/export/json", exportHandler.JSON)
+	r.POST("/api/import/json", exportHandler.ImportJSON)
+	r.GET("/api/export/csv", exportHandler.CSV)
+	r.POST("/api/import/csv", exportHandler.ImportCSV)
+	return r, noteRepo, actionRepo, hub
+}
+
+func TestExportHandler(t *testing.T) {
+	t.Run("Markdown", func(t *testing.T) {
+		r, noteRepo, actionRepo := setupExportRouter(t)
+		day := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+		note, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Note", Date: day})
+		actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Act"})
+		noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Earlier", Date: day.AddDate(0, 0, -2)})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/markdown?from=2026-10-16&to=2026-10-18", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/zip" {
+			t.Errorf("Expected a zip, got %s", got)
+		}
+		if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="logmeup-2026-10-16-2026-10-18.zip"` {
+			t.Errorf("Expected the archive to be named after the range, got %s", got)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("Failed to read zip: %v", err)
+		}
+		if len(archive.File) != 2 || archive.File[0].Name != "2026-10-16.md" {
+			t.Errorf("Expected a file for each of the 2 days with notes, got %d", len(archive.File))
+		}
+	})
+
+	t.Run("MarkdownEmptyRange", func(t *testing.T) {
+		r, _, _ := setupExportRouter(t)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/markdown?from=2026-10-16&to=2026-10-18", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil || len(archive.File) != 0 {
+			t.Errorf("Expected an empty archive, got %v", err)
+		}
+	})
+
+	t.Run("MarkdownStoreFailure", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		store := repository.NewMemoryStore()
+		notes := slowNoteStore{repository.NewMemoryNoteRepository(store)}
+		exportHandler := NewExportHandler(notes, repository.NewMemoryActionRepository(store), nil, nopHub{})
+		r := gin.New()
+		r.GET("/api/export/markdown", exportHandler.Markdown)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/markdown?from=2026-10-16&to=2026-10-18", nil))
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status code %d before anything is written, got %d", http.StatusGatewayTimeout, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != ProblemContentType {
+			t.Errorf("Expected a problem response, got %s", got)
+		}
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		r, _, _ := setupExportRouter(t)
+		tests := []struct {
+			name  string
+			query string
+			field string
+		}{
+			{"missing from", "?to=2026-10-18", "from"},
+			{"malformed to", "?from=2026-10-18&to=tomorrow", "to"},
+			{"reversed", "?from=2026-10-18&to=2026-10-17", "to"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/markdown"+tt.query, nil))
+
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+				}
+				var problem Problem
+				json.Unmarshal(w.Body.Bytes(), &problem)
+				if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field {
+					t.Errorf("Expected the %s parameter to be reported, got %+v", tt.field, problem.Errors)
+				}
+			})
+		}
+	})
+	t.Run("JSONRoundTrip", func(t *testing.T) {
+		r, noteRepo, actionRepo := setupExportRouter(t)
+		note, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Backed up", Date: time.Now()})
+		actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Act"})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="logmeup-backup-`) {
+			t.Errorf("Expected a backup attachment, got %s", got)
+		}
+		dump := w.Body.Bytes()
+
+		target, targetNotes, _ := setupExportRouter(t)
+		w = httptest.NewRecorder()
+		target.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/import/json?mode=replace", bytes.NewReader(dump)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var report backup.Report
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if report.Mode != backup.ModeReplace || report.Notes.Created != 1 || report.Actions.Created != 1 {
+			t.Errorf("Expected the note and action to be restored, got %+v", report)
+		}
+		restored, err := targetNotes.GetByID(context.Background(), report.NoteIDs[note.ID])
+		if err != nil || restored.Content != "Backed up" {
+			t.Errorf("Expected the note to be restored, got %v, %v", restored, err)
+		}
+	})
+
+	t.Run("ImportErrors", func(t *testing.T) {
+		r, _, _ := setupExportRouter(t)
+		tests := []struct {
+			name  string
+			query string
+			body  string
+			code  string
+			field string
+		}{
+			{"unknown mode", "?mode=overwrite", `{}`, CodeInvalidParameter, "mode"},
+			{"malformed", "", `{"format":`, CodeInvalidJSON, ""},
+			{"wrong format", "", `{"format":"other","version":1,"notes":[],"actions":[]}`, CodeValidation, "format"},
+			{"invalid record", "?mode=dry-run", `{"format":"logmeup-backup","version":1,"notes":[{"id":1,"content":"","date":"2026-10-18T00:00:00Z","created_at":"2026-10-18T09:00:00Z"}],"actions":[]}`, CodeValidation, "notes[0].content"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/import/json"+tt.query, strings.NewReader(tt.body)))
+
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+				}
+				var problem Problem
+				json.Unmarshal(w.Body.Bytes(), &problem)
+				if problem.Code != tt.code {
+					t.Errorf("Expected code %s, got %s", tt.code, problem.Code)
+				}
+				if tt.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field) {
+					t.Errorf("Expected the %s field to be reported, got %+v", tt.field, problem.Errors)
+				}
+			})
+		}
+	})
+	t.Run("CSV", func(t *testing.T) {
+		r, noteRepo, actionRepo := setupExportRouter(t)
+		note, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Planning", Date: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)})
+		done, _ := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Done"})
+		actionRepo.Update(context.Background(), done.ID, &models.UpdateActionRequest{Completed: true})
+		actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Open"})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/export/csv?completed=true", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+			t.Errorf("Expected CSV, got %s", got)
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[1], ",2026-10-18,Planning,Done,true,") {
+			t.Errorf("Expected the completed action with its note, got %q", lines)
+		}
+	})
+
+	t.Run("ImportCSV", func(t *testing.T) {
+		r, noteRepo, actionRepo, hub := setupExportRouterWithHub(t)
+		note, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Planning", Date: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)})
+		file := "note_date,description,completed\n2026-10-18,Call Ana,false\n2026-10-18,Book flights,true\n"
+
+		send := func(query, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/api/import/csv"+query, strings.NewReader(body))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send("?preview=true", file)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var preview export.ActionImport
+		json.Unmarshal(w.Body.Bytes(), &preview)
+		if !preview.Preview || len(preview.Rows) != 2 || preview.Created != 0 {
+			t.Errorf("Expected 2 rows previewed, got %+v", preview)
+		}
+
+		w = send("", file)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		actions, _ := actionRepo.GetByNoteID(context.Background(), note.ID)
+		if len(actions) != 2 {
+			t.Errorf("Expected 2 actions on the note, got %d", len(actions))
+		}
+		if len(hub.created) != 2 || hub.created[0].Description != "Call Ana" {
+			t.Errorf("Expected both actions to be broadcast in order, got %v", hub.created)
+		}
+
+		w = send("", "note_date,description\n2026-10-19,No note\n")
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusBadRequest || problem.Code != CodeValidation {
+			t.Fatalf("Expected a %s problem, got %d: %s", CodeValidation, w.Code, w.Body.String())
+		}
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != "rows[2].note_date" {
+			t.Errorf("Expected the row to be reported, got %+v", problem.Errors)
+		}
+
+		w = send("", "description\nNo date\n")
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusBadRequest || problem.Code != CodeInvalidCSV {
+			t.Errorf("Expected a %s problem, got %d: %s", CodeInvalidCSV, w.Code, w.Body.String())
+		}
+	})
+}
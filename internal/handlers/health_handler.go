@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/logging"
+)
+
+// HealthCheck probes one dependency the service needs to serve requests
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	// Error is "timeout" or "unavailable"; the cause is only logged
+	Error string `json:"error,omitempty"`
+}
+
+// HealthReport is the body of /healthz and /readyz
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+// NewHealthHandler creates a handler running checks for readiness. Each
+// check is given timeout to answer.
+func NewHealthHandler(timeout time.Duration, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: timeout}
+}
+
+// Live reports that the process is up and serving HTTP. It checks nothing
+// else so a slow database does not get the process restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthReport{Status: "ok"})
+}
+
+// Ready answers 503 unless every check passes
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.Report(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Report runs every check concurrently. Its status is "ok" only if all
+// of them pass.
+func (h *HealthHandler) Report(ctx context.Context) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := HealthReport{Status: "ok", Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != "ok" {
+				report.Status = "unavailable"
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// runCheck runs a check, giving up when ctx ends even if the check ignores
+// it. Failures are logged, since their errors may name hosts or credentials
+// that the unauthenticated readiness endpoint must not show.
+func runCheck(ctx context.Context, check HealthCheck) CheckResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: "ok", Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = "failing"
+		result.Error = "unavailable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timeout"
+		}
+		logging.FromContext(ctx).Warn("readiness check failed", "check", check.Name, "error", err)
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ok := HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := HealthCheck{Name: "migrations", Check: func(ctx context.Context) error { return errors.New("schema is at version 1, expected 2") }}
+	// hung ignores its context, like a check blocked on a dead connection
+	hung := HealthCheck{Name: "hub", Check: func(ctx context.Context) error { time.Sleep(time.Second); return nil }}
+
+	serve := func(h *HealthHandler, path string) (*httptest.ResponseRecorder, HealthReport) {
+		r := gin.New()
+		r.GET("/healthz", h.Live)
+		r.GET("/readyz", h.Ready)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return w, report
+	}
+
+	t.Run("Live", func(t *testing.T) {
+		w, report := serve(NewHealthHandler(time.Second, failing), "/healthz")
+		if w.Code != http.StatusOK || report.Status != "ok" {
+			t.Errorf("Expected liveness to ignore dependencies, got %d %q", w.Code, report.Status)
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		w, report := serve(NewHealthHandler(time.Second, ok), "/readyz")
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if report.Checks["database"].Status != "ok" {
+			t.Errorf("Expected the database check to pass, got %+v", report.Checks["database"])
+		}
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		w, report := serve(NewHealthHandler(time.Second, ok, failing), "/readyz")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if report.Status != "unavailable" {
+			t.Errorf("Expected status unavailable, got %q", report.Status)
+		}
+		if got := report.Checks["migrations"]; got.Status != "failing" || got.Error != "unavailable" {
+			t.Errorf("Expected the migrations check to fail without its cause, got %+v", got)
+		}
+		if report.Checks["database"].Status != "ok" {
+			t.Errorf("Expected the database check to pass, got %+v", report.Checks["database"])
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		start := time.Now()
+		w, report := serve(NewHealthHandler(50*time.Millisecond, hung), "/readyz")
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected the check to be abandoned at the timeout, took %v", elapsed)
+		}
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if got := report.Checks["hub"].Error; got != "timeout" {
+			t.Errorf("Expected a timeout, got %q", got)
+		}
+	})
+}
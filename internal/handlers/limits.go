@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+)
+
+// Rate limit response headers, from the IETF RateLimit header fields draft
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimit limits each client to the rate policy gives the matched route,
+// whatever the API version. Clients are told apart by IP address, taken
+// from X-Forwarded-For only when the request comes through one of the
+// engine's trusted proxies. Limited responses carry RateLimit-* headers, and
+// rejected ones a 429 problem with Retry-After.
+func RateLimit(limiter *ratelimit.Limiter, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, rate := policy.For(c.Request.Method, unversioned(c.FullPath()))
+		if rate.Unlimited() {
+			c.Next()
+			return
+		}
+
+		result := limiter.Allow(clientKey(c)+" "+name, rate)
+		c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		c.Header(RateLimitResetHeader, seconds(result.Reset))
+		c.Header(RateLimitPolicyHeader, fmt.Sprintf("%d;w=%s", rate.Limit, seconds(rate.Period)))
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			abortWithError(c, apiError{
+				status:  http.StatusTooManyRequests,
+				code:    CodeRateLimited,
+				message: "rate limit exceeded, retry in " + seconds(result.RetryAfter) + "s",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// unversioned drops the version segment from an API route, so /api/v1/notes
+// and its deprecated /api/notes alias share the budget configured for
+// /api/notes
+func unversioned(route string) string {
+	rest, ok := strings.CutPrefix(route, "/api/v")
+	if !ok {
+		return route
+	}
+	version, tail, _ := strings.Cut(rest, "/")
+	if _, err := strconv.Atoi(version); err != nil {
+		return route
+	}
+	return "/api/" + tail
+}
+
+// clientKey identifies the client a request counts against. ClientIP only
+// believes X-Forwarded-For from the engine's trusted proxies.
+func clientKey(c *gin.Context) string {
+	return ratelimit.ClientKey(c.ClientIP())
+}
+
+// seconds rounds d up to whole seconds, as the headers expect
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// MaxBodySize rejects request bodies over limit bytes with a 413 problem:
+// at once when Content-Length says so, otherwise when binding reads past
+// the limit. routes gives particular routes, such as /api/import/json, a
+// limit of their own for every API version. A limit of zero disables the
+// check.
+func MaxBodySize(limit int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := limit
+		if routeLimit, ok := routes[unversioned(c.FullPath())]; ok {
+			limit = routeLimit
+		}
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			abortWithError(c, bodyTooLarge(limit))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+func bodyTooLarge(limit int64) apiError {
+	return apiError{
+		status:  http.StatusRequestEntityTooLarge,
+		code:    CodeBodyTooLarge,
+		message: "request body is larger than " + strconv.FormatInt(limit, 10) + " bytes",
+	}
+}
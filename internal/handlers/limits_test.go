@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetTrustedProxies([]string{"10.0.0.1"})
+	r.Use(RateLimit(ratelimit.NewLimiter(), ratelimit.Policy{
+		Default: ratelimit.Rate{Limit: 5, Period: time.Minute},
+		Routes:  map[string]ratelimit.Rate{"POST /api/limited": {Limit: 2, Period: time.Minute}},
+	}))
+	r.POST("/api/v1/limited", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.POST("/api/limited", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.GET("/open", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if addr, ok := headers["RemoteAddr"]; ok {
+			req.RemoteAddr = addr
+			delete(headers, "RemoteAddr")
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/v1/limited", nil)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if got := w.Header().Get(RateLimitLimitHeader); got != "2" {
+			t.Errorf("Expected limit 2, got %q", got)
+		}
+		if got := w.Header().Get(RateLimitRemainingHeader); got != "1" {
+			t.Errorf("Expected 1 remaining, got %q", got)
+		}
+		if got := w.Header().Get(RateLimitPolicyHeader); got != "2;w=60" {
+			t.Errorf("Expected policy 2;w=60, got %q", got)
+		}
+	})
+
+	t.Run("TooManyRequests", func(t *testing.T) {
+		// The unversioned alias shares the versioned route's budget
+		send(http.MethodPost, "/api/limited", nil)
+		w := send(http.MethodPost, "/api/v1/limited", nil)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "30" {
+			t.Errorf("Expected Retry-After 30, got %q", got)
+		}
+		var problem Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Failed to unmarshal problem: %v", err)
+		}
+		if problem.Code != CodeRateLimited {
+			t.Errorf("Expected code %s, got %s", CodeRateLimited, problem.Code)
+		}
+
+		// Other routes draw from their own bucket
+		if w := send(http.MethodGet, "/open", nil); w.Code != http.StatusOK {
+			t.Errorf("Expected the default bucket to be untouched, got %d", w.Code)
+		}
+	})
+
+	t.Run("Clients", func(t *testing.T) {
+		// httptest requests come from 192.0.2.1, whose budget is spent
+		spoofed := map[string]map[string]string{
+			"user":            {UserHeader: "alice"},
+			"api key":         {"X-API-Key": "secret"},
+			"forwarded for":   {"X-Forwarded-For": "203.0.113.7"},
+			"forwarded again": {"X-Forwarded-For": "203.0.113.8, 10.0.0.1"},
+		}
+		for name, headers := range spoofed {
+			if w := send(http.MethodPost, "/api/v1/limited", headers); w.Code != http.StatusTooManyRequests {
+				t.Errorf("Expected a new %s header not to reset the budget, got %d", name, w.Code)
+			}
+		}
+
+		if w := send(http.MethodPost, "/api/v1/limited", map[string]string{"RemoteAddr": "198.51.100.2:1234"}); w.Code != http.StatusCreated {
+			t.Errorf("Expected other addresses to have their own bucket, got %d", w.Code)
+		}
+		proxied := map[string]string{"RemoteAddr": "10.0.0.1:1234", "X-Forwarded-For": "203.0.113.7"}
+		if w := send(http.MethodPost, "/api/v1/limited", proxied); w.Code != http.StatusCreated {
+			t.Errorf("Expected a trusted proxy's client to have its own bucket, got %d", w.Code)
+		}
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MaxBodySize(16, map[string]int64{"/api/import": 64}))
+	echo := func(c *gin.Context) {
+		var body map[string]string
+		if err := c.ShouldBindJSON(&body); err != nil {
+			abortWithError(c, bindingError(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.POST("/echo", echo)
+	r.POST("/api/v1/import", echo)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   io.Reader
+		status int
+	}{
+		{"small", "/echo", strings.NewReader(`{"a":"b"}`), http.StatusOK},
+		{"content length", "/echo", strings.NewReader(`{"a":"` + strings.Repeat("x", 32) + `"}`), http.StatusRequestEntityTooLarge},
+		// A reader of unknown length is only stopped while it is decoded
+		{"streamed", "/echo", io.MultiReader(strings.NewReader(`{"a":"` + strings.Repeat("x", 32) + `"}`)), http.StatusRequestEntityTooLarge},
+		{"route limit", "/api/v1/import", strings.NewReader(`{"a":"` + strings.Repeat("x", 32) + `"}`), http.StatusOK},
+		{"over route limit", "/api/v1/import", strings.NewReader(`{"a":"` + strings.Repeat("x", 64) + `"}`), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, tt.body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status != http.StatusOK {
+				var problem Problem
+				json.Unmarshal(w.Body.Bytes(), &problem)
+				if problem.Code != CodeBodyTooLarge {
+					t.Errorf("Expected code %s, got %s", CodeBodyTooLarge, problem.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the gin context
+const requestIDKey = "request_id"
+
+// maxRequestIDLength bounds IDs supplied by clients
+const maxRequestIDLength = 128
+
+// UserHeader identifies the user making a request, as for WebSockets
+const UserHeader = "X-User-ID"
+
+// RequestID gives every request an ID, reusing the client's X-Request-ID
+// when it is reasonable, and echoes it in the response. The request context
+// gets a logger tagged with the ID, route, user and trace ID, so repository
+// logs can be tied back to the request.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		attrs := []any{"request_id", id, "route", c.FullPath(), "user", requestUser(c)}
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			attrs = append(attrs, "trace_id", span.TraceID().String())
+		}
+		ctx := logging.WithAttrs(c.Request.Context(), attrs...)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requestUser names the user behind a request for logs
+func requestUser(c *gin.Context) string {
+	if user := c.GetHeader(UserHeader); user != "" {
+		return user
+	}
+	return "anonymous"
+}
+
+// RequestLogger writes one line per request once it has been handled.
+// Server errors are logged at error level with the errors handlers recorded,
+// client errors at warn level and everything else at info level.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.Errors())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// validRequestID accepts short IDs made of printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFrom returns the ID the RequestID middleware assigned, if any
+func RequestIDFrom(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Recovery turns a panic in a handler into a problem response
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		logging.FromContext(c.Request.Context()).Error("handler panicked", "panic", recovered, "stack", string(debug.Stack()))
+		abortWithError(c, apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "internal server error"})
+	})
+}
+
+// RouteNotFound answers requests no route matches
+func RouteNotFound(c *gin.Context) {
+	abortWithError(c, apiError{status: http.StatusNotFound, code: CodeNotFound, message: "no route for " + c.Request.URL.Path})
+}
+
+// MethodNotAllowed answers requests whose path matches a route registered
+// for other methods
+func MethodNotAllowed(c *gin.Context) {
+	abortWithError(c, apiError{status: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed, message: c.Request.Method + " is not allowed here"})
+}
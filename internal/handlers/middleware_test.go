@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/logging"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(), RequestID())
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(RouteNotFound)
+	r.NoMethod(MethodNotAllowed)
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	t.Run("RequestID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+			t.Errorf("Expected the client's request ID to be echoed, got %q", got)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/ok", nil)
+		req.Header.Set(RequestIDHeader, "has spaces")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get(RequestIDHeader); got == "" || got == "has spaces" {
+			t.Errorf("Expected a generated request ID, got %q", got)
+		}
+	})
+
+	t.Run("Problems", func(t *testing.T) {
+		tests := []struct {
+			method string
+			path   string
+			status int
+			code   string
+		}{
+			{http.MethodGet, "/missing", http.StatusNotFound, CodeNotFound},
+			{http.MethodPost, "/ok", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+			{http.MethodGet, "/panic", http.StatusInternalServerError, CodeInternal},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.path, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code != tt.status {
+					t.Errorf("Expected status code %d, got %d", tt.status, w.Code)
+				}
+				var problem Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if problem.Code != tt.code || problem.RequestID != w.Header().Get(RequestIDHeader) {
+					t.Errorf("Unexpected problem %+v", problem)
+				}
+			})
+		}
+	})
+	t.Run("RequestLogger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, _ := logging.New(&buf, logging.Options{Format: "json"})
+		defer slog.SetDefault(slog.Default())
+		slog.SetDefault(logger)
+
+		r := gin.New()
+		r.Use(RequestID(), RequestLogger())
+		r.GET("/notes/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+		req := httptest.NewRequest(http.MethodGet, "/notes/7", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		req.Header.Set(UserHeader, "alice")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		var line map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("Expected one JSON log line, got %q", buf.String())
+		}
+		want := map[string]interface{}{
+			"level":      "WARN",
+			"request_id": "req-1",
+			"route":      "/notes/:id",
+			"path":       "/notes/7",
+			"user":       "alice",
+			"status":     float64(http.StatusTeapot),
+		}
+		for key, value := range want {
+			if line[key] != value {
+				t.Errorf("Expected %s %v, got %v", key, value, line[key])
+			}
+		}
+		if _, ok := line["latency"]; !ok {
+			t.Error("Expected the latency to be logged")
+		}
+	})
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,96 +12,269 @@ import (
 	"github.com/tehsis/logmeup-api/internal/repository"
 )
 
+// NoteHub broadcasts note changes to WebSocket clients
+type NoteHub interface {
+	BroadcastNoteCreated(ctx context.Context, note *models.Note)
+	BroadcastNoteUpdated(ctx context.Context, note *models.Note)
+	BroadcastNoteDeleted(ctx context.Context, noteID int64)
+}
+
 type NoteHandler struct {
-	repo *repository.NoteRepository
+	repo    repository.NoteStore
+	actions repository.ActionStore
+	hub     NoteHub
+	present Presenter
+}
+
+// NewNoteHandler creates a note handler. actions is read for the actions
+// requests include and for days.
+func NewNoteHandler(repo repository.NoteStore, actions repository.ActionStore, hub NoteHub) *NoteHandler {
+	return &NoteHandler{repo: repo, actions: actions, hub: hub, present: V1{}}
 }
 
-func NewNoteHandler(repo *repository.NoteRepository) *NoteHandler {
-	return &NoteHandler{repo: repo}
+// WithPresenter returns a handler sharing h's store that shapes responses
+// with p, for another API version
+func (h *NoteHandler) WithPresenter(p Presenter) *NoteHandler {
+	copied := *h
+	copied.present = p
+	return &copied
 }
 
 func (h *NoteHandler) Create(c *gin.Context) {
 	var req models.CreateNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, bindingError(err))
 		return
 	}
 
-	note, err := h.repo.Create(&req)
+	note, err := h.repo.Create(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, repositoryError(err, "note"))
 		return
 	}
 
-	c.JSON(http.StatusCreated, note)
+	h.hub.BroadcastNoteCreated(c.Request.Context(), note)
+	h.present.Record(c, http.StatusCreated, note)
 }
 
 func (h *NoteHandler) GetByID(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
+		return
+	}
+	include, ok := parseInclude(c.Query("include"))
+	if !ok {
+		abortWithError(c, invalidInclude)
 		return
 	}
 
-	note, err := h.repo.GetByID(id)
+	note, err := h.repo.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+		abortWithError(c, repositoryError(err, "note"))
+		return
+	}
+	if include.none() {
+		h.present.Record(c, http.StatusOK, note)
 		return
 	}
 
-	c.JSON(http.StatusOK, note)
+	details, err := h.detail(c.Request.Context(), []*models.Note{note}, include)
+	if err != nil {
+		abortWithError(c, repositoryError(err, "action"))
+		return
+	}
+	h.present.Record(c, http.StatusOK, details[0])
 }
 
 func (h *NoteHandler) GetByDate(c *gin.Context) {
 	dateStr := c.Query("date")
 	date, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format"})
+		abortWithError(c, invalidParameter("date", "must be a date in YYYY-MM-DD format"))
+		return
+	}
+	include, ok := parseInclude(c.Query("include"))
+	if !ok {
+		abortWithError(c, invalidInclude)
 		return
 	}
 
-	notes, err := h.repo.GetByDate(date)
+	notes, err := h.repo.GetByDate(c.Request.Context(), date)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, repositoryError(err, "note"))
+		return
+	}
+	if include.none() {
+		h.present.List(c, notes)
 		return
 	}
 
-	c.JSON(http.StatusOK, notes)
+	details, err := h.detail(c.Request.Context(), notes, include)
+	if err != nil {
+		abortWithError(c, repositoryError(err, "action"))
+		return
+	}
+	h.present.List(c, details)
+}
+
+// GetDay returns a day's notes with all their actions and the day's totals
+func (h *NoteHandler) GetDay(c *gin.Context) {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		abortWithError(c, invalidParameter("date", "must be a date in YYYY-MM-DD format"))
+		return
+	}
+
+	notes, err := h.repo.GetByDate(c.Request.Context(), date)
+	if err != nil {
+		abortWithError(c, repositoryError(err, "note"))
+		return
+	}
+	details, err := h.detail(c.Request.Context(), notes, noteIncludes{actions: true, actionCount: true})
+	if err != nil {
+		abortWithError(c, repositoryError(err, "action"))
+		return
+	}
+
+	day := models.Day{Date: date.Format("2006-01-02"), Notes: details}
+	day.Totals.Notes = len(details)
+	for _, note := range details {
+		day.Totals.Actions += note.ActionCount.Total
+		day.Totals.CompletedActions += note.ActionCount.Completed
+	}
+	h.present.Record(c, http.StatusOK, day)
 }
 
 func (h *NoteHandler) Update(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
 		return
 	}
 
 	var req models.UpdateNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, bindingError(err))
 		return
 	}
 
-	note, err := h.repo.Update(id, &req)
+	note, err := h.repo.Update(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, repositoryError(err, "note"))
 		return
 	}
 
-	c.JSON(http.StatusOK, note)
+	h.hub.BroadcastNoteUpdated(c.Request.Context(), note)
+	h.present.Record(c, http.StatusOK, note)
 }
 
 func (h *NoteHandler) Delete(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		abortWithError(c, invalidParameter("id", "must be an integer"))
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		abortWithError(c, repositoryError(err, "note"))
 		return
 	}
 
+	h.hub.BroadcastNoteDeleted(c.Request.Context(), id)
 	c.Status(http.StatusNoContent)
-} 
\ No newline at end of file
+}
+
+// noteIncludes are the related records a request asked to embed in notes
+type noteIncludes struct {
+	actions     bool
+	actionCount bool
+}
+
+func (i noteIncludes) none() bool {
+	return !i.actions && !i.actionCount
+}
+
+var invalidInclude = invalidParameter("include", "must be a comma separated list of actions and actions.count")
+
+// parseInclude reads the include query parameter, such as
+// "actions,actions.count"
+func parseInclude(value string) (noteIncludes, bool) {
+	var include noteIncludes
+	if value == "" {
+		return include, true
+	}
+	for _, name := range strings.Split(value, ",") {
+		switch strings.TrimSpace(name) {
+		case "actions":
+			include.actions = true
+		case "actions.count":
+			include.actionCount = true
+		default:
+			return include, false
+		}
+	}
+	return include, true
+}
+
+// noteBatch bounds the note IDs sent in one query, keeping large ranges
+// under the databases' limits on query parameters
+const noteBatch = 500
+
+// detail embeds what include asks for in notes, loading the actions or
+// counts of up to noteBatch notes per query
+func (h *NoteHandler) detail(ctx context.Context, notes []*models.Note, include noteIncludes) ([]models.NoteDetail, error) {
+	details := make([]models.NoteDetail, len(notes))
+	if len(notes) == 0 {
+		return details, nil
+	}
+	ids := make([]int64, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+		details[i].Note = *note
+	}
+
+	counts := make(map[int64]models.ActionCount, len(notes))
+	if include.actions {
+		byNote := make(map[int64][]*models.Action, len(notes))
+		for start := 0; start < len(ids); start += noteBatch {
+			actions, err := h.actions.Find(ctx, models.ActionFilter{NoteIDs: ids[start:min(start+noteBatch, len(ids))]})
+			if err != nil {
+				return nil, err
+			}
+			for _, action := range actions {
+				byNote[action.NoteID] = append(byNote[action.NoteID], action)
+				count := counts[action.NoteID]
+				count.Total++
+				if action.Completed {
+					count.Completed++
+				}
+				counts[action.NoteID] = count
+			}
+		}
+		for i := range details {
+			details[i].Actions = byNote[details[i].ID]
+			if details[i].Actions == nil {
+				details[i].Actions = []*models.Action{}
+			}
+		}
+	} else {
+		for start := 0; start < len(ids); start += noteBatch {
+			batch, err := h.actions.CountByNote(ctx, models.ActionFilter{NoteIDs: ids[start:min(start+noteBatch, len(ids))]})
+			if err != nil {
+				return nil, err
+			}
+			for id, count := range batch {
+				counts[id] = count
+			}
+		}
+	}
+
+	if include.actionCount {
+		for i := range details {
+			count := counts[details[i].ID]
+			details[i].ActionCount = &count
+		}
+	}
+	return details, nil
+}
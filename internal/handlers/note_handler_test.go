@@ -2,25 +2,26 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tehsis/logmeup-api/internal/models"
 	"github.com/tehsis/logmeup-api/internal/repository"
-	"github.com/tehsis/logmeup-api/internal/testutil"
 )
 
-func setupTestRouter(t *testing.T) (*gin.Engine, *repository.NoteRepository) {
+func setupTestRouter(t *testing.T) (*gin.Engine, repository.NoteStore) {
 	gin.SetMode(gin.TestMode)
-	db := testutil.SetupTestDB(t)
-	testutil.SetupTestSchema(t, db)
 
-	noteRepo := repository.NewNoteRepository(db)
-	noteHandler := NewNoteHandler(noteRepo)
+	store := repository.NewMemoryStore()
+	noteRepo := repository.NewMemoryNoteRepository(store)
+	noteHandler := NewNoteHandler(noteRepo, repository.NewMemoryActionRepository(store), nopHub{})
 
 	r := gin.Default()
 	r.POST("/api/notes", noteHandler.Create)
@@ -70,12 +71,12 @@ func TestNoteHandler(t *testing.T) {
 			Content: "Test note for GetByID",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
 
-		req := httptest.NewRequest(http.MethodGet, "/api/notes/"+string(created.ID), nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/notes/"+strconv.FormatInt(created.ID, 10), nil)
 		w := httptest.NewRecorder()
 
 		r.ServeHTTP(w, req)
@@ -105,7 +106,7 @@ func TestNoteHandler(t *testing.T) {
 		}
 
 		for _, note := range notes {
-			_, err := repo.Create(note)
+			_, err := repo.Create(context.Background(), note)
 			if err != nil {
 				t.Fatalf("Failed to create test note: %v", err)
 			}
@@ -138,7 +139,7 @@ func TestNoteHandler(t *testing.T) {
 			Content: "Test note for Update",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -148,7 +149,7 @@ func TestNoteHandler(t *testing.T) {
 		}
 		body, _ := json.Marshal(update)
 
-		req := httptest.NewRequest(http.MethodPut, "/api/notes/"+string(created.ID), bytes.NewBuffer(body))
+		req := httptest.NewRequest(http.MethodPut, "/api/notes/"+strconv.FormatInt(created.ID, 10), bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
@@ -176,12 +177,12 @@ func TestNoteHandler(t *testing.T) {
 			Content: "Test note for Delete",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
 
-		req := httptest.NewRequest(http.MethodDelete, "/api/notes/"+string(created.ID), nil)
+		req := httptest.NewRequest(http.MethodDelete, "/api/notes/"+strconv.FormatInt(created.ID, 10), nil)
 		w := httptest.NewRecorder()
 
 		r.ServeHTTP(w, req)
@@ -191,9 +192,207 @@ func TestNoteHandler(t *testing.T) {
 		}
 
 		// Verify note is deleted
-		_, err = repo.GetByID(created.ID)
+		_, err = repo.GetByID(context.Background(), created.ID)
 		if err == nil {
 			t.Error("Expected error when getting deleted note")
 		}
 	})
+	t.Run("ValidationErrors", func(t *testing.T) {
+		r, _ := setupTestRouter(t)
+
+		tests := []struct {
+			name   string
+			body   string
+			code   string
+			fields []string
+		}{
+			{"missing fields", `{}`, CodeValidation, []string{"content", "date"}},
+			{"wrong type", `{"content":42,"date":"2024-01-01T00:00:00Z"}`, CodeValidation, []string{"content"}},
+			{"malformed", `{"content":`, CodeInvalidJSON, nil},
+			{"too long", `{"content":"` + strings.Repeat("x", models.MaxNoteContentLength+1) + `","date":"2024-01-01T00:00:00Z"}`, CodeValidation, []string{"content"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/api/notes", bytes.NewBufferString(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+
+				r.ServeHTTP(w, req)
+
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+				}
+				var problem Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if problem.Code != tt.code {
+					t.Errorf("Expected error code %q, got %q", tt.code, problem.Code)
+				}
+				var fields []string
+				for _, field := range problem.Errors {
+					fields = append(fields, field.Field)
+				}
+				if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+					t.Errorf("Expected invalid fields %v, got %v", tt.fields, fields)
+				}
+			})
+		}
+	})
+}
+
+// countingActionStore counts the queries loading included actions
+type countingActionStore struct {
+	repository.ActionStore
+	queries int
+}
+
+func (s *countingActionStore) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	s.queries++
+	return s.ActionStore.Find(ctx, filter)
+}
+
+func (s *countingActionStore) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	s.queries++
+	return s.ActionStore.CountByNote(ctx, filter)
+}
+
+func TestNoteIncludes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryStore()
+	noteRepo := repository.NewMemoryNoteRepository(store)
+	actionRepo := &countingActionStore{ActionStore: repository.NewMemoryActionRepository(store)}
+	noteHandler := NewNoteHandler(noteRepo, actionRepo, nopHub{})
+
+	r := gin.New()
+	r.GET("/api/notes/:id", noteHandler.GetByID)
+	r.GET("/api/notes", noteHandler.GetByDate)
+	r.GET("/api/days/:date", noteHandler.GetDay)
+
+	day := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	busy, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Busy", Date: day})
+	quiet, _ := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Quiet", Date: day})
+	for _, description := range []string{"Open", "Done"} {
+		action, _ := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: busy.ID, Description: description})
+		if description == "Done" {
+			actionRepo.Update(context.Background(), action.ID, &models.UpdateActionRequest{Completed: true})
+		}
+	}
+
+	get := func(t *testing.T, path string, v any) *httptest.ResponseRecorder {
+		t.Helper()
+		actionRepo.queries = 0
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if v != nil && w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w
+	}
+	byID := func(notes []map[string]json.RawMessage, id int64) map[string]json.RawMessage {
+		for _, note := range notes {
+			if string(note["id"]) == strconv.FormatInt(id, 10) {
+				return note
+			}
+		}
+		t.Fatalf("Expected note %d in the response", id)
+		return nil
+	}
+
+	t.Run("Actions", func(t *testing.T) {
+		var notes []map[string]json.RawMessage
+		w := get(t, "/api/notes?date=2026-10-18&include=actions", &notes)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var actions []models.Action
+		json.Unmarshal(byID(notes, busy.ID)["actions"], &actions)
+		if len(actions) != 2 {
+			t.Errorf("Expected the busy note's 2 actions, got %v", actions)
+		}
+		if got := string(byID(notes, quiet.ID)["actions"]); got != "[]" {
+			t.Errorf("Expected an empty list for a note without actions, got %s", got)
+		}
+		if _, ok := byID(notes, busy.ID)["action_count"]; ok {
+			t.Error("Expected no action_count unless it is included")
+		}
+		if actionRepo.queries != 1 {
+			t.Errorf("Expected the actions of every note to be loaded in 1 query, got %d", actionRepo.queries)
+		}
+	})
+
+	t.Run("ActionCount", func(t *testing.T) {
+		var note models.NoteDetail
+		get(t, "/api/notes/"+strconv.FormatInt(busy.ID, 10)+"?include=actions.count", &note)
+		if note.ActionCount == nil || *note.ActionCount != (models.ActionCount{Total: 2, Completed: 1}) {
+			t.Errorf("Expected 2 actions with 1 completed, got %+v", note.ActionCount)
+		}
+		if note.Actions != nil {
+			t.Errorf("Expected no actions unless they are included, got %v", note.Actions)
+		}
+		if actionRepo.queries != 1 {
+			t.Errorf("Expected the counts to be loaded in 1 query, got %d", actionRepo.queries)
+		}
+	})
+
+	t.Run("Without", func(t *testing.T) {
+		var note map[string]json.RawMessage
+		get(t, "/api/notes/"+strconv.FormatInt(busy.ID, 10), &note)
+		if _, ok := note["actions"]; ok || actionRepo.queries != 0 {
+			t.Errorf("Expected a bare note without querying actions, got %v", note)
+		}
+	})
+
+	t.Run("UnknownInclude", func(t *testing.T) {
+		w := get(t, "/api/notes/"+strconv.FormatInt(busy.ID, 10)+"?include=comments", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Day", func(t *testing.T) {
+		var result models.Day
+		w := get(t, "/api/days/2026-10-18", &result)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if result.Date != "2026-10-18" || len(result.Notes) != 2 {
+			t.Errorf("Expected the day's 2 notes, got %+v", result)
+		}
+		if result.Totals != (models.DayTotals{Notes: 2, Actions: 2, CompletedActions: 1}) {
+			t.Errorf("Expected totals of 2 notes and 2 actions with 1 completed, got %+v", result.Totals)
+		}
+		if actionRepo.queries != 1 {
+			t.Errorf("Expected the day's actions to be loaded in 1 query, got %d", actionRepo.queries)
+		}
+
+		var empty models.Day
+		get(t, "/api/days/2026-10-19", &empty)
+		if empty.Notes == nil || len(empty.Notes) != 0 || empty.Totals.Notes != 0 {
+			t.Errorf("Expected an empty day, got %+v", empty)
+		}
+
+		if w := get(t, "/api/days/yesterday", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for a malformed date, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		busyDay := day.AddDate(0, 0, 7)
+		for i := 0; i <= noteBatch; i++ {
+			noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Many", Date: busyDay})
+		}
+
+		var notes []models.NoteDetail
+		get(t, "/api/notes?date=2026-10-25&include=actions", &notes)
+		if len(notes) != noteBatch+1 {
+			t.Fatalf("Expected %d notes, got %d", noteBatch+1, len(notes))
+		}
+		if actionRepo.queries != 2 {
+			t.Errorf("Expected the actions to be loaded %d notes at a time in 2 queries, got %d", noteBatch, actionRepo.queries)
+		}
+	})
 }
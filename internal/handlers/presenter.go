@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Presenter writes the body of successful responses. Each API version has
+// its own, so a later version can wrap records and lists differently, for
+// example in paginated envelopes, while sharing the handlers' parsing,
+// validation and repository calls.
+type Presenter interface {
+	// Record writes a single record such as a note
+	Record(c *gin.Context, status int, record any)
+	// List writes a slice of records
+	List(c *gin.Context, items any)
+}
+
+// V1 presents records and lists as bare JSON objects and arrays
+type V1 struct{}
+
+func (V1) Record(c *gin.Context, status int, record any) {
+	c.JSON(status, record)
+}
+
+func (V1) List(c *gin.Context, items any) {
+	c.JSON(http.StatusOK, items)
+}
+
+// Deprecated marks responses from an old route prefix as deprecated (RFC
+// 9745) with the date it stops working (RFC 8594), and links the same
+// request under successor, its replacement: with prefix /api and successor
+// /api/v1, /api/notes/1 links to /api/v1/notes/1.
+func Deprecated(prefix, successor string, since, sunset time.Time) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunsetDate)
+		}
+		link := successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		if c.Request.URL.RawQuery != "" {
+			link += "?" + c.Request.URL.RawQuery
+		}
+		c.Header("Link", "<"+link+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// envelope is a presenter as a later API version might use
+type envelope struct{}
+
+func (envelope) Record(c *gin.Context, status int, record any) {
+	c.JSON(status, gin.H{"data": record})
+}
+
+func (envelope) List(c *gin.Context, items any) {
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+func TestPresenter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	noteRepo := repository.NewMemoryNoteRepository(repository.NewMemoryStore())
+	created, err := noteRepo.Create(context.Background(), &models.CreateNoteRequest{Content: "Shared", Date: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	v1 := NewNoteHandler(noteRepo, nil, nopHub{})
+	r := gin.New()
+	r.GET("/v1/notes/:id", v1.GetByID)
+	r.GET("/v2/notes/:id", v1.WithPresenter(envelope{}).GetByID)
+
+	t.Run("V1", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/notes/1", nil))
+
+		var note models.Note
+		if err := json.Unmarshal(w.Body.Bytes(), &note); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if note.ID != created.ID {
+			t.Errorf("Expected ID %d, got %d", created.ID, note.ID)
+		}
+	})
+
+	t.Run("WithPresenter", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/notes/1", nil))
+
+		var body struct {
+			Data models.Note `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if body.Data.ID != created.ID {
+			t.Errorf("Expected the note in an envelope, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	since := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+	r := gin.New()
+	r.Use(Deprecated("/api", "/api/v1", since, sunset))
+	r.GET("/api/notes", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/notes?date=2026-10-18", nil))
+
+	if got, want := w.Header().Get("Deprecation"), "@1792281600"; got != want {
+		t.Errorf("Expected Deprecation %q, got %q", want, got)
+	}
+	if got, want := w.Header().Get("Sunset"), "Fri, 30 Apr 2027 00:00:00 GMT"; got != want {
+		t.Errorf("Expected Sunset %q, got %q", want, got)
+	}
+	if got, want := w.Header().Get("Link"), `</api/v1/notes?date=2026-10-18>; rel="successor-version"`; got != want {
+		t.Errorf("Expected Link %q, got %q", want, got)
+	}
+}
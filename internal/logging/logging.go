@@ -0,0 +1,73 @@
+// Package logging configures the process-wide slog logger and carries
+// request-scoped loggers through contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// logContent controls whether user content such as note text is logged
+var logContent atomic.Bool
+
+// Options selects how log lines are written
+type Options struct {
+	// Format is "json" or "text"
+	Format string
+	// Level is the minimum level logged
+	Level slog.Level
+	// Content logs user content verbatim instead of redacting it
+	Content bool
+}
+
+// New returns a logger writing to w
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	switch strings.ToLower(opts.Format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q: expected json or text", opts.Format)
+}
+
+// Setup makes a logger writing to w the default for slog and the log
+// package, and sets whether content is redacted
+func Setup(w io.Writer, opts Options) error {
+	logger, err := New(w, opts)
+	if err != nil {
+		return err
+	}
+	logContent.Store(opts.Content)
+	slog.SetDefault(logger)
+	return nil
+}
+
+type loggerKey struct{}
+
+// WithAttrs returns a context whose logger adds args to every line
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger stored in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Content is an attribute for user-written text. Unless content logging is
+// enabled only its length is logged.
+func Content(key, value string) slog.Attr {
+	if logContent.Load() {
+		return slog.String(key, value)
+	}
+	return slog.String(key, fmt.Sprintf("[redacted %d bytes]", len(value)))
+}
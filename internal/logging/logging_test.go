@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	t.Run("Format", func(t *testing.T) {
+		if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+			t.Error("Expected error for an unknown format")
+		}
+
+		var buf bytes.Buffer
+		logger, err := New(&buf, Options{Format: "json", Level: slog.LevelWarn})
+		if err != nil {
+			t.Fatalf("Failed to create logger: %v", err)
+		}
+		logger.Info("skipped")
+		logger.Warn("kept")
+
+		var line map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("Expected a single JSON line, got %q", buf.String())
+		}
+		if line["msg"] != "kept" {
+			t.Errorf("Expected only the warning to be logged, got %v", line)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, _ := New(&buf, Options{Format: "json"})
+		ctx := context.WithValue(context.Background(), loggerKey{}, logger)
+		ctx = WithAttrs(ctx, "request_id", "abc")
+
+		FromContext(ctx).Info("hello")
+
+		var line map[string]interface{}
+		json.Unmarshal(buf.Bytes(), &line)
+		if line["request_id"] != "abc" {
+			t.Errorf("Expected the request ID on the line, got %v", line)
+		}
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		defer logContent.Store(false)
+
+		if got := Content("content", "secret").Value.String(); got != "[redacted 6 bytes]" {
+			t.Errorf("Expected content to be redacted, got %q", got)
+		}
+		logContent.Store(true)
+		if got := Content("content", "secret").Value.String(); got != "secret" {
+			t.Errorf("Expected content to be logged, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+// Package metrics collects Prometheus metrics for the API and serves them
+// in the text exposition format.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "logmeup"
+
+// Registry holds every metric the service exports
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls handled, by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Time taken to handle gRPC calls, by method and status code. Streams are timed until they end.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by repository methods, by repository, method and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "method", "outcome"})
+
+	wsClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "WebSocket clients currently connected.",
+	})
+
+	wsMessagesSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_messages_sent_total",
+		Help:      "Messages handed to WebSocket clients.",
+	})
+
+	wsMessagesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_messages_dropped_total",
+		Help:      "Messages not delivered, by reason: slow_client or hub_stopped.",
+	}, []string{"reason"})
+
+	notesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notes_created_total",
+		Help:      "Notes created.",
+	})
+
+	actionsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "actions_created_total",
+		Help:      "Actions created.",
+	})
+
+	actionsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "actions_completed_total",
+		Help:      "Actions changed from open to completed.",
+	})
+)
+
+// broadcastQueue reports the number of messages waiting in the hub
+var broadcastQueue atomic.Pointer[func() int]
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, grpcRequests, grpcDuration, queryDuration,
+		wsClients, wsMessagesSent, wsMessagesDropped,
+		notesCreated, actionsCreated, actionsCompleted,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_broadcast_queue_depth",
+			Help:      "Messages queued for delivery by the WebSocket hub.",
+		}, func() float64 {
+			if depth := broadcastQueue.Load(); depth != nil {
+				return float64((*depth)())
+			}
+			return 0
+		}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// HTTP records the count and duration of requests. Requests that match no
+// route are grouped under "unmatched" so arbitrary paths cannot create
+// unbounded series.
+func HTTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// GRPCUnary and GRPCStream record the count and duration of gRPC calls.
+// Calls to unknown methods never reach interceptors, so the method label
+// only takes the names of registered methods.
+func GRPCUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+func GRPCStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observeCall(info.FullMethod, start, err)
+	return err
+}
+
+func observeCall(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	grpcRequests.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDB exports the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// SetBroadcastQueue sets the function reporting the hub's queue depth
+func SetBroadcastQueue(depth func() int) {
+	broadcastQueue.Store(&depth)
+}
+
+// ClientConnected and ClientDisconnected track connected WebSocket clients
+func ClientConnected()    { wsClients.Inc() }
+func ClientDisconnected() { wsClients.Dec() }
+
+// MessageSent counts a message handed to a WebSocket client
+func MessageSent() { wsMessagesSent.Inc() }
+
+// MessageDropped counts a message that was not delivered
+func MessageDropped(reason string) { wsMessagesDropped.WithLabelValues(reason).Inc() }
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("HTTP", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(HTTP())
+		r.GET("/api/notes/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/notes/:id", "200"))
+		unmatched := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404"))
+		for _, path := range []string{"/api/notes/1", "/api/notes/2", "/nowhere"} {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/notes/:id", "200")) - before; got != 2 {
+			t.Errorf("Expected 2 requests counted under the route, got %v", got)
+		}
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")) - unmatched; got != 1 {
+			t.Errorf("Expected 1 unmatched request, got %v", got)
+		}
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		const method = "/logmeup.v1.NoteService/GetNote"
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		okBefore := testutil.ToFloat64(grpcRequests.WithLabelValues(method, "OK"))
+		notFoundBefore := testutil.ToFloat64(grpcRequests.WithLabelValues(method, "NotFound"))
+
+		GRPCUnary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		GRPCUnary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "note not found")
+		})
+
+		if got := testutil.ToFloat64(grpcRequests.WithLabelValues(method, "OK")) - okBefore; got != 1 {
+			t.Errorf("Expected 1 successful call, got %v", got)
+		}
+		if got := testutil.ToFloat64(grpcRequests.WithLabelValues(method, "NotFound")) - notFoundBefore; got != 1 {
+			t.Errorf("Expected 1 failed call, got %v", got)
+		}
+	})
+
+	t.Run("Stores", func(t *testing.T) {
+		ctx := context.Background()
+		store := repository.NewMemoryStore()
+		notes := NoteStore(repository.NewMemoryNoteRepository(store))
+		actions := ActionStore(repository.NewMemoryActionRepository(store))
+
+		createdBefore := testutil.ToFloat64(notesCreated)
+		completedBefore := testutil.ToFloat64(actionsCompleted)
+
+		note, err := notes.Create(ctx, &models.CreateNoteRequest{Content: "Note", Date: time.Now()})
+		if err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+		action, err := actions.Create(ctx, &models.CreateActionRequest{NoteID: note.ID, Description: "Action"})
+		if err != nil {
+			t.Fatalf("Failed to create action: %v", err)
+		}
+		// Completing an action already completed is not counted again
+		actions.Update(ctx, action.ID, &models.UpdateActionRequest{Completed: true})
+		actions.Update(ctx, action.ID, &models.UpdateActionRequest{Completed: true})
+		actions.Update(ctx, action.ID, &models.UpdateActionRequest{Completed: false})
+		actions.Toggle(ctx, action.ID)
+		notes.GetByID(ctx, 999)
+
+		if got := testutil.ToFloat64(notesCreated) - createdBefore; got != 1 {
+			t.Errorf("Expected 1 note created, got %v", got)
+		}
+		if got := testutil.ToFloat64(actionsCompleted) - completedBefore; got != 2 {
+			t.Errorf("Expected 2 completions, got %v", got)
+		}
+		if testutil.CollectAndCount(queryDuration) == 0 {
+			t.Error("Expected query durations to be recorded")
+		}
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		SetBroadcastQueue(func() int { return 3 })
+
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body := w.Body.String()
+		for _, want := range []string{
+			"logmeup_websocket_broadcast_queue_depth 3",
+			`logmeup_db_query_duration_seconds_count{method="GetByID",outcome="not_found",repository="notes"}`,
+			"go_goroutines",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected the output to contain %q", want)
+			}
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// observe records how long a repository method took and how it ended
+func observe(name, method string, start time.Time, err error) {
+	outcome := "success"
+	switch {
+	case err == nil:
+	case errors.Is(err, repository.ErrNotFound):
+		outcome = "not_found"
+	case errors.Is(err, context.DeadlineExceeded):
+		outcome = "timeout"
+	default:
+		outcome = "error"
+	}
+	queryDuration.WithLabelValues(name, method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// noteStore times every call to a NoteStore and counts created notes
+type noteStore struct {
+	next repository.NoteStore
+}
+
+// NoteStore wraps store so its methods are timed
+func NoteStore(store repository.NoteStore) repository.NoteStore {
+	return &noteStore{next: store}
+}
+
+func (s *noteStore) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	start := time.Now()
+	created, err := s.next.Create(ctx, note)
+	observe("notes", "Create", start, err)
+	if err == nil {
+		notesCreated.Inc()
+	}
+	return created, err
+}
+
+func (s *noteStore) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	start := time.Now()
+	note, err := s.next.GetByID(ctx, id)
+	observe("notes", "GetByID", start, err)
+	return note, err
+}
+
+func (s *noteStore) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	start := time.Now()
+	notes, err := s.next.GetByDate(ctx, date)
+	observe("notes", "GetByDate", start, err)
+	return notes, err
+}
+
+func (s *noteStore) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	start := time.Now()
+	notes, err := s.next.GetByDateRange(ctx, from, to)
+	observe("notes", "GetByDateRange", start, err)
+	return notes, err
+}
+
+func (s *noteStore) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	start := time.Now()
+	notes, err := s.next.GetByIDs(ctx, ids)
+	observe("notes", "GetByIDs", start, err)
+	return notes, err
+}
+
+func (s *noteStore) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	start := time.Now()
+	notes, err := s.next.GetByDates(ctx, dates)
+	observe("notes", "GetByDates", start, err)
+	return notes, err
+}
+
+func (s *noteStore) Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+	start := time.Now()
+	updated, err := s.next.Update(ctx, id, note)
+	observe("notes", "Update", start, err)
+	return updated, err
+}
+
+func (s *noteStore) Delete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, id)
+	observe("notes", "Delete", start, err)
+	return err
+}
+
+// actionStore times every call to an ActionStore and counts created and
+// completed actions
+type actionStore struct {
+	next repository.ActionStore
+}
+
+// ActionStore wraps store so its methods are timed
+func ActionStore(store repository.ActionStore) repository.ActionStore {
+	return &actionStore{next: store}
+}
+
+func (s *actionStore) Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error) {
+	start := time.Now()
+	created, err := s.next.Create(ctx, action)
+	observe("actions", "Create", start, err)
+	if err == nil {
+		actionsCreated.Inc()
+	}
+	return created, err
+}
+
+func (s *actionStore) CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error) {
+	start := time.Now()
+	created, err := s.next.CreateMany(ctx, actions)
+	observe("actions", "CreateMany", start, err)
+	if err == nil {
+		actionsCreated.Add(float64(len(created)))
+	}
+	return created, err
+}
+
+func (s *actionStore) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	start := time.Now()
+	action, err := s.next.GetByID(ctx, id)
+	observe("actions", "GetByID", start, err)
+	return action, err
+}
+
+func (s *actionStore) GetAll(ctx context.Context) ([]*models.Action, error) {
+	start := time.Now()
+	actions, err := s.next.GetAll(ctx)
+	observe("actions", "GetAll", start, err)
+	return actions, err
+}
+
+func (s *actionStore) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	start := time.Now()
+	actions, err := s.next.GetByNoteID(ctx, noteID)
+	observe("actions", "GetByNoteID", start, err)
+	return actions, err
+}
+
+func (s *actionStore) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	start := time.Now()
+	actions, err := s.next.Find(ctx, filter)
+	observe("actions", "Find", start, err)
+	return actions, err
+}
+
+func (s *actionStore) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	start := time.Now()
+	counts, err := s.next.CountByNote(ctx, filter)
+	observe("actions", "CountByNote", start, err)
+	return counts, err
+}
+
+func (s *actionStore) Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error) {
+	start := time.Now()
+	updated, changed, err := s.next.Update(ctx, id, action)
+	observe("actions", "Update", start, err)
+	if err == nil && changed && updated.Completed {
+		actionsCompleted.Inc()
+	}
+	return updated, changed, err
+}
+
+func (s *actionStore) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	start := time.Now()
+	toggled, err := s.next.Toggle(ctx, id)
+	observe("actions", "Toggle", start, err)
+	if err == nil && toggled.Completed {
+		actionsCompleted.Inc()
+	}
+	return toggled, err
+}
+
+func (s *actionStore) Delete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, id)
+	observe("actions", "Delete", start, err)
+	return err
+}
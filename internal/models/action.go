@@ -18,4 +18,21 @@ type CreateActionRequest struct {
 
 type UpdateActionRequest struct {
 	Completed bool `json:"completed"`
-} 
\ No newline at end of file
+}
+
+// ActionCount tallies a note's actions
+type ActionCount struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+}
+
+// ActionFilter narrows a search for actions. Zero fields match every action.
+type ActionFilter struct {
+	// NoteIDs keeps actions on any of these notes
+	NoteIDs []int64
+	// Completed keeps actions in this state
+	Completed *bool
+	// CreatedAfter and CreatedBefore keep actions created strictly between them
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
@@ -2,6 +2,10 @@ package models
 
 import "time"
 
+// MaxNoteContentLength is the longest note content accepted, in characters.
+// The max rules on the request types below must match it.
+const MaxNoteContentLength = 50000
+
 type Note struct {
 	ID        int64     `json:"id"`
 	Content   string    `json:"content"`
@@ -11,10 +15,32 @@ type Note struct {
 }
 
 type CreateNoteRequest struct {
-	Content string    `json:"content" binding:"required"`
+	Content string    `json:"content" binding:"required,max=50000"`
 	Date    time.Time `json:"date" binding:"required"`
 }
 
 type UpdateNoteRequest struct {
-	Content string `json:"content" binding:"required"`
-} 
\ No newline at end of file
+	Content string `json:"content" binding:"required,max=50000"`
+}
+
+// NoteDetail is a note with the related records a request asked to include
+type NoteDetail struct {
+	Note
+	// Actions is set, possibly empty, when actions were included
+	Actions     []*Action    `json:"actions,omitzero"`
+	ActionCount *ActionCount `json:"action_count,omitempty"`
+}
+
+// Day is a day's notes with their actions and completion totals
+type Day struct {
+	Date   string       `json:"date"`
+	Notes  []NoteDetail `json:"notes"`
+	Totals DayTotals    `json:"totals"`
+}
+
+// DayTotals sums up a day
+type DayTotals struct {
+	Notes            int `json:"notes"`
+	Actions          int `json:"actions"`
+	CompletedActions int `json:"completed_actions"`
+}
@@ -0,0 +1,225 @@
+// Package openapi builds the OpenAPI 3.1 description of the API, deriving
+// request and response schemas from the Go types the handlers bind and
+// return, and serves it with a browsable docs page.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+)
+
+// Document is an OpenAPI 3.1 document, limited to the parts this API uses
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema, as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas turns Go types into schemas, adding named structs to components
+type schemas map[string]*Schema
+
+// of returns the schema for the type of v. Structs are described once under
+// components and referenced by name.
+func (s schemas) of(v any) *Schema {
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s schemas) forType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		if _, ok := s[t.Name()]; !ok {
+			// Reserve the name first so self-referencing types terminate
+			s[t.Name()] = nil
+			s[t.Name()] = s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() == reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
+
+// object describes a struct from its json tags and binding rules
+func (s schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := s.object(field.Type)
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.forType(field.Type)
+		required := !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") && field.Type.Kind() != reflect.Pointer
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			rule, param, _ := strings.Cut(rule, "=")
+			switch rule {
+			case "required":
+				required = true
+			case "max":
+				if n, err := strconv.Atoi(param); err == nil && property.Type == "string" {
+					property.MaxLength = &n
+				}
+			}
+		}
+		// Request fields without a binding rule are optional to send
+		if strings.HasSuffix(t.Name(), "Request") && !strings.Contains(field.Tag.Get("binding"), "required") {
+			required = false
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// Handler serves the document as JSON
+func Handler(c *gin.Context) {
+	specOnce.Do(func() {
+		var err error
+		if specJSON, err = json.Marshal(Spec()); err != nil {
+			panic(err)
+		}
+	})
+	c.Data(http.StatusOK, "application/json", specJSON)
+}
+
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI holds the scripts and styles of swagger-ui-dist 5.18.2, copied
+// unchanged from its dist directory together with its Apache 2.0 license
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// Docs serves a Swagger UI page rendering /openapi.json. The page and the
+// Swagger UI files it loads are part of the binary, so the docs work
+// offline and load nothing from third parties.
+func Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
+
+// DocsAsset serves a Swagger UI file loaded by the docs page
+func DocsAsset(c *gin.Context) {
+	name := c.Param("file")
+	data, err := swaggerUI.ReadFile("swagger-ui/" + name)
+	if err != nil {
+		handlers.RouteNotFound(c)
+		return
+	}
+	c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(name)), data)
+}
@@ -0,0 +1,406 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tehsis/logmeup-api/internal/backup"
+	"github.com/tehsis/logmeup-api/internal/export"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+)
+
+// Version is the version of the API contract
+const Version = "1.0.0"
+
+// builder collects operations and the schemas they refer to
+type builder struct {
+	doc     *Document
+	schemas schemas
+}
+
+// add registers an operation. Paths use OpenAPI templates such as
+// /api/v1/notes/{id}; every {name} the operation does not declare itself
+// becomes a required integer path parameter. Operations under /api get the
+// rate limit response, and those under /api/v1 are also added, deprecated,
+// at their unversioned alias.
+func (b *builder) add(method, path string, op *Operation) {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			name := strings.Trim(segment, "{}")
+			if !declares(op, name) {
+				op.Parameters = append([]Parameter{{
+					Name:     name,
+					In:       "path",
+					Required: true,
+					Schema:   &Schema{Type: "integer", Format: "int64"},
+				}}, op.Parameters...)
+			}
+			op.Responses["400"] = b.problem("Malformed " + name)
+		}
+	}
+	if op.RequestBody != nil {
+		op.Responses["400"] = b.problem("Invalid body; the problem lists the invalid fields")
+		op.Responses["413"] = b.problem("Body larger than the configured limit")
+	}
+	if strings.HasPrefix(path, "/api/") {
+		op.Responses["429"] = b.rateLimited()
+	}
+	b.put(method, path, op)
+
+	if rest, ok := strings.CutPrefix(path, "/api/v1/"); ok {
+		legacy := *op
+		legacy.OperationID += "Unversioned"
+		legacy.Summary += " (deprecated alias of " + path + ")"
+		legacy.Deprecated = true
+		b.put(method, "/api/"+rest, &legacy)
+	}
+}
+
+// declares reports whether op lists the path parameter name
+func declares(op *Operation, name string) bool {
+	for _, p := range op.Parameters {
+		if p.In == "path" && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) put(method, path string, op *Operation) {
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = make(map[string]*Operation)
+	}
+	b.doc.Paths[path][strings.ToLower(method)] = op
+}
+
+func (b *builder) json(description string, v any) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: b.schemas.of(v)}},
+	}
+}
+
+func (b *builder) body(v any) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": {Schema: b.schemas.of(v)}},
+	}
+}
+
+func (b *builder) problem(description string) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{handlers.ProblemContentType: {Schema: b.schemas.of(handlers.Problem{})}},
+	}
+}
+
+func (b *builder) rateLimited() Response {
+	response := b.problem("Rate limit exceeded")
+	integer := &Schema{Type: "integer"}
+	response.Headers = map[string]Header{
+		"Retry-After":                     {Description: "Seconds until a request will be allowed", Schema: integer},
+		handlers.RateLimitLimitHeader:     {Description: "Requests allowed per window", Schema: integer},
+		handlers.RateLimitRemainingHeader: {Description: "Requests left now", Schema: integer},
+		handlers.RateLimitResetHeader:     {Description: "Seconds until the full budget is back", Schema: integer},
+	}
+	return response
+}
+
+// responses builds a response map, adding the error responses every
+// operation touching the database can return
+func (b *builder) responses(database bool, rs map[string]Response) map[string]Response {
+	if database {
+		rs["500"] = b.problem("Database or server failure")
+		rs["504"] = b.problem("The database did not answer in time")
+	}
+	return rs
+}
+
+// Spec describes every route registered by routes.SetupRoutes
+func Spec() *Document {
+	b := &builder{
+		doc: &Document{
+			OpenAPI: "3.1.0",
+			Info: Info{
+				Title:   "LogMeUp API",
+				Version: Version,
+				Description: "Daily notes and the actions that come out of them. Errors are RFC 7807 problems; switch on their code. " +
+					"The unversioned /api routes are deprecated aliases of /api/v1 and announce their removal in a Sunset header.",
+			},
+			Paths: make(map[string]map[string]*Operation),
+		},
+		schemas: make(schemas),
+	}
+	notFound := func(resource string) Response { return b.problem(resource + " not found") }
+	include := Parameter{
+		Name: "include", In: "query",
+		Description: "Comma separated records to embed in each note: actions, which adds its actions, and actions.count, " +
+			"which adds its action_count; both are loaded in a single query",
+		Schema: &Schema{Type: "string"},
+	}
+
+	// Notes
+	b.add(http.MethodPost, "/api/v1/notes", &Operation{
+		OperationID: "createNote", Summary: "Create a note", Tags: []string{"notes"},
+		RequestBody: b.body(models.CreateNoteRequest{}),
+		Responses:   b.responses(true, map[string]Response{"201": b.json("The created note", models.Note{})}),
+	})
+	b.add(http.MethodGet, "/api/v1/notes", &Operation{
+		OperationID: "listNotesByDate", Summary: "List the notes of a day, newest first", Tags: []string{"notes"},
+		Parameters: []Parameter{{
+			Name: "date", In: "query", Required: true, Description: "Day in YYYY-MM-DD format",
+			Schema: &Schema{Type: "string", Format: "date"},
+		}, include},
+		Responses: b.responses(true, map[string]Response{
+			"200": b.json("The day's notes", []models.NoteDetail{}),
+			"400": b.problem("Missing or malformed date or include"),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/notes/{id}", &Operation{
+		OperationID: "getNote", Summary: "Get a note", Tags: []string{"notes"},
+		Parameters: []Parameter{include},
+		Responses:  b.responses(true, map[string]Response{"200": b.json("The note", models.NoteDetail{}), "404": notFound("Note")}),
+	})
+	b.add(http.MethodPut, "/api/v1/notes/{id}", &Operation{
+		OperationID: "updateNote", Summary: "Replace a note's content", Tags: []string{"notes"},
+		RequestBody: b.body(models.UpdateNoteRequest{}),
+		Responses:   b.responses(true, map[string]Response{"200": b.json("The updated note", models.Note{}), "404": notFound("Note")}),
+	})
+	b.add(http.MethodDelete, "/api/v1/notes/{id}", &Operation{
+		OperationID: "deleteNote", Summary: "Delete a note and its actions", Tags: []string{"notes"},
+		Responses: b.responses(true, map[string]Response{"204": {Description: "Deleted"}, "404": notFound("Note")}),
+	})
+
+	b.add(http.MethodGet, "/api/v1/days/{date}", &Operation{
+		OperationID: "getDay", Summary: "Get a day's notes with their actions and totals", Tags: []string{"notes"},
+		Parameters: []Parameter{{
+			Name: "date", In: "path", Required: true, Description: "Day in YYYY-MM-DD format",
+			Schema: &Schema{Type: "string", Format: "date"},
+		}},
+		Responses: b.responses(true, map[string]Response{"200": b.json("The day", models.Day{})}),
+	})
+
+	// Actions
+	b.add(http.MethodPost, "/api/v1/actions", &Operation{
+		OperationID: "createAction", Summary: "Create an action on a note", Tags: []string{"actions"},
+		RequestBody: b.body(models.CreateActionRequest{}),
+		Responses: b.responses(true, map[string]Response{
+			"201": b.json("The created action", models.Action{}),
+			"422": b.problem("The note does not exist"),
+		}),
+	})
+	actionFilter := []Parameter{
+		{
+			Name: "note_id", In: "query", Description: "Keep actions on any of these notes; repeat the parameter or separate IDs with commas",
+			Schema: &Schema{Type: "array", Items: &Schema{Type: "integer", Format: "int64"}},
+		},
+		{Name: "completed", In: "query", Description: "Keep completed or open actions", Schema: &Schema{Type: "boolean"}},
+		{Name: "created_after", In: "query", Description: "Keep actions created strictly after this time", Schema: &Schema{Type: "string", Format: "date-time"}},
+		{Name: "created_before", In: "query", Description: "Keep actions created strictly before this time", Schema: &Schema{Type: "string", Format: "date-time"}},
+	}
+	b.add(http.MethodGet, "/api/v1/actions", &Operation{
+		OperationID: "listActions", Summary: "List every action or those matching the filters, newest first", Tags: []string{"actions"},
+		Parameters: actionFilter,
+		Responses: b.responses(true, map[string]Response{
+			"200": b.json("The actions", []models.Action{}),
+			"400": b.problem("A malformed filter"),
+		}),
+	})
+	b.add(http.MethodHead, "/api/v1/actions", &Operation{
+		OperationID: "checkActions", Summary: "Health check; use /healthz", Tags: []string{"actions"}, Deprecated: true,
+		Responses: map[string]Response{"200": {Description: "The API is up"}},
+	})
+	b.add(http.MethodGet, "/api/v1/actions/{id}", &Operation{
+		OperationID: "getAction", Summary: "Get an action", Tags: []string{"actions"},
+		Responses: b.responses(true, map[string]Response{"200": b.json("The action", models.Action{}), "404": notFound("Action")}),
+	})
+	b.add(http.MethodGet, "/api/v1/actions/note/{note_id}", &Operation{
+		OperationID: "listNoteActions", Summary: "List a note's actions, newest first", Tags: []string{"actions"},
+		Responses: b.responses(true, map[string]Response{"200": b.json("The note's actions", []models.Action{})}),
+	})
+	b.add(http.MethodPut, "/api/v1/actions/{id}", &Operation{
+		OperationID: "updateAction", Summary: "Mark an action completed or not", Tags: []string{"actions"},
+		RequestBody: b.body(models.UpdateActionRequest{}),
+		Responses:   b.responses(true, map[string]Response{"200": b.json("The updated action", models.Action{}), "404": notFound("Action")}),
+	})
+	b.add(http.MethodDelete, "/api/v1/actions/{id}", &Operation{
+		OperationID: "deleteAction", Summary: "Delete an action", Tags: []string{"actions"},
+		Responses: b.responses(true, map[string]Response{"204": {Description: "Deleted"}, "404": notFound("Action")}),
+	})
+
+	// Exports
+	dateQuery := func(name, description string) Parameter {
+		return Parameter{Name: name, In: "query", Required: true, Description: description, Schema: &Schema{Type: "string", Format: "date"}}
+	}
+	b.add(http.MethodGet, "/api/v1/export/markdown", &Operation{
+		OperationID: "exportMarkdown", Summary: "Download a zip with a Markdown file per day", Tags: []string{"export"},
+		Parameters: []Parameter{dateQuery("from", "First day, YYYY-MM-DD"), dateQuery("to", "Last day, inclusive")},
+		Responses: b.responses(true, map[string]Response{
+			"200": {
+				Description: "A zip of YYYY-MM-DD.md files with YAML front matter holding IDs and timestamps; days without notes are left out",
+				Content:     map[string]MediaType{"application/zip": {Schema: &Schema{Type: "string", Format: "binary"}}},
+			},
+			"400": b.problem("Missing, malformed or reversed dates"),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/export/json", &Operation{
+		OperationID: "exportJSON", Summary: "Download a backup of every note and action", Tags: []string{"export"},
+		Responses: b.responses(true, map[string]Response{
+			"200": b.json("A versioned document with every record, its ID and its timestamps, streamed as it is read", backup.Document{}),
+		}),
+	})
+	b.add(http.MethodPost, "/api/v1/import/json", &Operation{
+		OperationID: "importJSON", Summary: "Restore a backup in a single transaction", Tags: []string{"export"},
+		Parameters: []Parameter{{
+			Name: "mode", In: "query",
+			Description: "merge (default) adds missing records and reports conflicting ones, replace deletes everything first, dry-run reports what a merge would do",
+			Schema:      &Schema{Type: "string"},
+		}},
+		RequestBody: b.body(backup.Document{}),
+		Responses: b.responses(true, map[string]Response{
+			"200": b.json("What was created, left unchanged, in conflict or deleted, with the IDs records were given", backup.Report{}),
+			"400": b.problem("Invalid mode, malformed JSON or invalid records; nothing was written"),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/export/csv", &Operation{
+		OperationID: "exportActionsCSV", Summary: "Download actions as CSV, filtered like the action list", Tags: []string{"export"},
+		Parameters: actionFilter,
+		Responses: b.responses(true, map[string]Response{
+			"200": {
+				Description: "A header row of " + strings.Join(export.ActionColumns, ", ") + ", then an action per row, newest first",
+				Content:     map[string]MediaType{"text/csv": {Schema: &Schema{Type: "string"}}},
+			},
+			"400": b.problem("A malformed filter"),
+		}),
+	})
+	b.add(http.MethodPost, "/api/v1/import/csv", &Operation{
+		OperationID: "importActionsCSV", Summary: "Create actions from CSV rows, matching notes by date", Tags: []string{"export"},
+		Parameters: []Parameter{{
+			Name: "preview", In: "query", Description: "Only report the rows that would be created and the invalid ones",
+			Schema: &Schema{Type: "boolean"},
+		}},
+		RequestBody: &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{"text/csv": {Schema: &Schema{
+				Type:        "string",
+				Description: "A header row with note_date and description columns, and optionally completed and note_id, which picks the note when the date has several",
+			}}},
+		},
+		Responses: b.responses(true, map[string]Response{
+			"200": b.json("The preview, listing invalid rows in errors", export.ActionImport{}),
+			"201": b.json("The rows created, each with its action_id", export.ActionImport{}),
+			"400": b.problem("Invalid preview, an unreadable file or, without preview, invalid rows; nothing was written"),
+			"422": b.problem("A matched note was deleted during the import"),
+		}),
+	})
+
+	// Realtime
+	b.add(http.MethodGet, "/api/v1/presence", &Operation{
+		OperationID: "getPresence", Summary: "Who is online and which notes they are viewing", Tags: []string{"realtime"},
+		Responses: map[string]Response{"200": b.json("The presence snapshot", websocket.PresenceSnapshot{})},
+	})
+	maxUserLength := websocket.MaxUserLength
+	b.add(http.MethodGet, "/ws", &Operation{
+		OperationID: "connectWebSocket", Summary: "Open the WebSocket for live updates, presence and collaborative editing", Tags: []string{"realtime"},
+		Parameters: []Parameter{{
+			Name: "user", In: "query",
+			Description: "User name shown to other clients, replaced by the X-User-ID header when set; " +
+				`letters, digits, ".", "_", "-" or "@", not starting with "guest-". It is not authenticated.`,
+			Schema: &Schema{Type: "string", MaxLength: &maxUserLength},
+		}},
+		Responses: map[string]Response{
+			"101": {Description: "Switching to the WebSocket protocol"},
+			"400": b.problem("Invalid user name"),
+		},
+	})
+
+	// GraphQL
+	b.add(http.MethodPost, "/graphql", &Operation{
+		OperationID: "graphql", Summary: "Run a GraphQL query or mutation; errors are reported in the response", Tags: []string{"graphql"},
+		RequestBody: &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{"application/json": {Schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"query":         {Type: "string"},
+					"operationName": {Type: "string"},
+					"variables":     {Type: "object"},
+				},
+				Required: []string{"query"},
+			}}},
+		},
+		Responses: map[string]Response{
+			"200": {
+				Description: "The data and the errors of the fields that failed, each with an extensions.code",
+				Content:     map[string]MediaType{"application/json": {Schema: &Schema{Type: "object"}}},
+			},
+			"400": {Description: "The body is not a GraphQL request"},
+			"413": {Description: "Body larger than the configured limit"},
+			"429": b.rateLimited(),
+		},
+	})
+	b.add(http.MethodGet, "/graphql", &Operation{
+		OperationID: "graphqlSubscriptions", Summary: "Open a WebSocket for GraphQL subscriptions (graphql-transport-ws)", Tags: []string{"graphql"},
+		Responses: map[string]Response{
+			"101": {Description: "Switching to the WebSocket protocol"},
+			"429": b.rateLimited(),
+		},
+	})
+
+	// Operations
+	b.add(http.MethodGet, "/healthz", &Operation{
+		OperationID: "live", Summary: "Liveness probe", Tags: []string{"operations"},
+		Responses: map[string]Response{"200": b.json("The process is serving HTTP", handlers.HealthReport{})},
+	})
+	b.add(http.MethodGet, "/readyz", &Operation{
+		OperationID: "ready", Summary: "Readiness probe checking the database, migrations and hub", Tags: []string{"operations"},
+		Responses: map[string]Response{
+			"200": b.json("Every dependency is available", handlers.HealthReport{}),
+			"503": b.json("A dependency is failing", handlers.HealthReport{}),
+		},
+	})
+	b.add(http.MethodGet, "/metrics", &Operation{
+		OperationID: "metrics", Summary: "Prometheus metrics", Tags: []string{"operations"},
+		Responses: map[string]Response{"200": {
+			Description: "Metrics in the Prometheus text format",
+			Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+		}},
+	})
+	b.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "openapi", Summary: "This document", Tags: []string{"operations"},
+		Responses: map[string]Response{"200": {
+			Description: "The OpenAPI document",
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Type: "object"}}},
+		}},
+	})
+	b.add(http.MethodGet, "/docs", &Operation{
+		OperationID: "docs", Summary: "Browsable API documentation", Tags: []string{"operations"},
+		Responses: map[string]Response{"200": {
+			Description: "Swagger UI",
+			Content:     map[string]MediaType{"text/html": {Schema: &Schema{Type: "string"}}},
+		}},
+	})
+	b.add(http.MethodGet, "/docs/{file}", &Operation{
+		OperationID: "docsAsset", Summary: "Swagger UI script or stylesheet loaded by the docs page", Tags: []string{"operations"},
+		Parameters: []Parameter{{
+			Name: "file", In: "path", Required: true,
+			Description: "swagger-ui.css or swagger-ui-bundle.js",
+			Schema:      &Schema{Type: "string"},
+		}},
+		Responses: map[string]Response{
+			"200": {
+				Description: "The file",
+				Content: map[string]MediaType{
+					"text/css":        {Schema: &Schema{Type: "string"}},
+					"text/javascript": {Schema: &Schema{Type: "string"}},
+				},
+			},
+			"404": b.problem("No such file"),
+		},
+	})
+
+	b.doc.Components.Schemas = b.schemas
+	return b.doc
+}
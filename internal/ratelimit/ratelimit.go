@@ -0,0 +1,194 @@
+// Package ratelimit limits how often each client may call the API using
+// token buckets kept in memory.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Limit requests per Period. A client may spend the whole limit
+// at once; tokens then come back evenly over the period.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Unlimited reports whether r places no limit on requests
+func (r Rate) Unlimited() bool {
+	return r.Limit <= 0
+}
+
+func (r Rate) String() string {
+	if r.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", r.Limit, r.Period)
+}
+
+// ParseRate reads a rate such as "60/m", "10/s", "1000/h" or "100/30s".
+// "off" and "0" mean unlimited.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return Rate{}, nil
+	}
+
+	count, period, ok := strings.Cut(s, "/")
+	limit, err := strconv.Atoi(count)
+	if !ok || err != nil || limit <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected requests/period such as 60/m", s)
+	}
+
+	switch period {
+	case "s":
+		return Rate{limit, time.Second}, nil
+	case "m":
+		return Rate{limit, time.Minute}, nil
+	case "h":
+		return Rate{limit, time.Hour}, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: period must be s, m, h or a duration such as 30s", s)
+	}
+	return Rate{limit, d}, nil
+}
+
+// ClientKey identifies a client by its IP address, so every API counts a
+// client's requests against the same budget. Headers naming an API key or
+// user are not used: nothing authenticates them, so a client could send a
+// new one with each request for a fresh bucket.
+func ClientKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Result describes a client's bucket after a request
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests the client can still make now
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// Allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// period refills an empty bucket completely
+	period time.Duration
+}
+
+// Limiter holds a token bucket for every key it has seen recently
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter returns an empty limiter
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token from key's bucket, which holds up to rate.Limit
+// tokens and refills at rate.Limit per rate.Period
+func (l *Limiter) Allow(key string, rate Rate) Result {
+	if rate.Unlimited() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(rate.Limit)
+	perToken := rate.Period / time.Duration(rate.Limit)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now, period: rate.Period}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	result := Result{Limit: rate.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+	return result
+}
+
+// sweep drops, at most once a minute, buckets that have been idle long
+// enough to be full again, since a new bucket would be identical
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= b.period {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Policy picks the rate for each route
+type Policy struct {
+	// Default applies to routes without their own rate
+	Default Rate
+	// Routes maps "METHOD /route" keys, such as "POST /api/actions", to the
+	// rate replacing Default for that route
+	Routes map[string]Rate
+}
+
+// For returns the rate for a request to route and the name of the bucket it
+// draws from: the route for routes with their own rate, "default" otherwise
+func (p Policy) For(method, route string) (string, Rate) {
+	name := method + " " + route
+	if rate, ok := p.Routes[name]; ok {
+		return name, rate
+	}
+	return "default", p.Default
+}
+
+// ParseRoutes reads per-route rates such as
+// "POST /api/notes=60/m, POST /api/actions=60/m"
+func ParseRoutes(s string) (map[string]Rate, error) {
+	routes := make(map[string]Rate)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(entry, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route rate %q: expected METHOD /route=rate", entry)
+		}
+		rate, err := ParseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		routes[method+" "+path] = rate
+	}
+	return routes, nil
+}
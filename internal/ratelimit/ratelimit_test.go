@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	rate := Rate{Limit: 3, Period: 3 * time.Second}
+
+	t.Run("Burst", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			result := l.Allow("client", rate)
+			if !result.Allowed || result.Remaining != i {
+				t.Errorf("Expected request allowed with %d remaining, got %+v", i, result)
+			}
+		}
+
+		result := l.Allow("client", rate)
+		if result.Allowed {
+			t.Error("Expected the fourth request to be rejected")
+		}
+		if result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+			t.Errorf("Expected retry after 1s and reset after 3s, got %v and %v", result.RetryAfter, result.Reset)
+		}
+
+		if other := l.Allow("other", rate); !other.Allowed {
+			t.Error("Expected other clients to have their own bucket")
+		}
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		now = now.Add(time.Second)
+		if result := l.Allow("client", rate); !result.Allowed || result.Remaining != 0 {
+			t.Errorf("Expected one token back after a second, got %+v", result)
+		}
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		l.Allow("new", rate)
+		if _, ok := l.buckets["client"]; ok {
+			t.Error("Expected idle buckets to be dropped")
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"60/m", Rate{60, time.Minute}},
+		{"10/s", Rate{10, time.Second}},
+		{"1000/h", Rate{1000, time.Hour}},
+		{"5/30s", Rate{5, 30 * time.Second}},
+		{"off", Rate{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Expected %q to be %v, got %v (%v)", tt.in, tt.want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "60", "x/m", "-1/m", "60/week"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+
+	routes, err := ParseRoutes("POST /api/notes=60/m, GET /api/actions=off")
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	policy := Policy{Default: Rate{600, time.Minute}, Routes: routes}
+	if name, rate := policy.For("POST", "/api/notes"); name != "POST /api/notes" || rate != (Rate{60, time.Minute}) {
+		t.Errorf("Expected the route's own rate, got %s %v", name, rate)
+	}
+	if _, rate := policy.For("GET", "/api/actions"); !rate.Unlimited() {
+		t.Errorf("Expected GET /api/actions to be unlimited, got %v", rate)
+	}
+	if name, _ := policy.For("GET", "/api/notes"); name != "default" {
+		t.Errorf("Expected the default rate, got %s", name)
+	}
+	if _, err := ParseRoutes("/api/notes=60/m"); err == nil {
+		t.Error("Expected an error for a route without a method")
+	}
+}
@@ -1,45 +1,29 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/tehsis/logmeup-api/internal/models"
 )
 
 type ActionRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	timeout time.Duration
 }
 
-func NewActionRepository(db *sql.DB) *ActionRepository {
-	log.Printf("[ActionRepository] Initializing action repository")
-	return &ActionRepository{db: db}
+// NewActionRepository creates an action repository. Each query is cancelled
+// after timeout; zero means queries are only bound by the caller's context.
+func NewActionRepository(db *sql.DB, timeout time.Duration) *ActionRepository {
+	return &ActionRepository{db: db, timeout: timeout}
 }
 
-// Helper function to log database operations
-func logDBOperation(operation string, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	log.Printf("[ActionRepository-%s] %s | Details: %v", operation, timestamp, details)
-}
-
-// Helper function to log database errors
-func logDBError(operation string, err error, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	log.Printf("[ActionRepository-%s-ERROR] %s | Error: %v | Details: %v", operation, timestamp, err, details)
-}
-
-// Helper function to log successful database operations
-func logDBSuccess(operation string, details ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	log.Printf("[ActionRepository-%s-SUCCESS] %s | Details: %v", operation, timestamp, details)
-}
-
-func (r *ActionRepository) Create(action *models.CreateActionRequest) (*models.Action, error) {
-	logDBOperation("Create", "Starting action creation", map[string]interface{}{
-		"note_id":     action.NoteID,
-		"description": action.Description,
-	})
+func (r *ActionRepository) Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
 	query := `
 		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
@@ -50,9 +34,8 @@ func (r *ActionRepository) Create(action *models.CreateActionRequest) (*models.A
 	now := time.Now()
 	var createdAction models.Action
 
-	logDBOperation("Create", "Executing SQL query", query)
-
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		action.NoteID,
 		action.Description,
@@ -69,24 +52,42 @@ func (r *ActionRepository) Create(action *models.CreateActionRequest) (*models.A
 	)
 
 	if err != nil {
-		logDBError("Create", err, "Failed to create action", map[string]interface{}{
-			"note_id":     action.NoteID,
-			"description": action.Description,
-		})
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	logDBSuccess("Create", "Action created successfully", map[string]interface{}{
-		"action_id":   createdAction.ID,
-		"note_id":     createdAction.NoteID,
-		"description": createdAction.Description,
-	})
-
 	return &createdAction, nil
 }
 
-func (r *ActionRepository) GetByID(id int64) (*models.Action, error) {
-	logDBOperation("GetByID", "Fetching action by ID", id)
+// CreateMany inserts the actions in one transaction. The query timeout
+// bounds each insert rather than the whole batch.
+func (r *ActionRepository) CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error) {
+	query := `
+		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+
+	now := time.Now()
+	created := make([]*models.Action, 0, len(actions))
+	err := inTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		for _, action := range actions {
+			stored, err := insertAction(ctx, r.timeout, tx, scanAction, query, action.NoteID, action.Description, action.Completed, now, now)
+			if err != nil {
+				return err
+			}
+			created = append(created, stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *ActionRepository) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
 	query := `
 		SELECT id, note_id, description, completed, created_at, updated_at
@@ -96,9 +97,7 @@ func (r *ActionRepository) GetByID(id int64) (*models.Action, error) {
 
 	var action models.Action
 
-	logDBOperation("GetByID", "Executing SQL query", query, "ID:", id)
-
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&action.ID,
 		&action.NoteID,
 		&action.Description,
@@ -108,25 +107,15 @@ func (r *ActionRepository) GetByID(id int64) (*models.Action, error) {
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			logDBError("GetByID", err, "Action not found", id)
-		} else {
-			logDBError("GetByID", err, "Database error while fetching action", id)
-		}
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	logDBSuccess("GetByID", "Action retrieved successfully", map[string]interface{}{
-		"action_id":   action.ID,
-		"description": action.Description,
-		"completed":   action.Completed,
-	})
-
 	return &action, nil
 }
 
-func (r *ActionRepository) GetAll() ([]*models.Action, error) {
-	logDBOperation("GetAll", "Fetching all actions")
+func (r *ActionRepository) GetAll(ctx context.Context) ([]*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
 	query := `
 		SELECT id, note_id, description, completed, created_at, updated_at
@@ -134,12 +123,9 @@ func (r *ActionRepository) GetAll() ([]*models.Action, error) {
 		ORDER BY created_at DESC
 	`
 
-	logDBOperation("GetAll", "Executing SQL query", query)
-
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
-		logDBError("GetAll", err, "Failed to execute query")
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 	defer rows.Close()
 
@@ -155,26 +141,21 @@ func (r *ActionRepository) GetAll() ([]*models.Action, error) {
 			&action.UpdatedAt,
 		)
 		if err != nil {
-			logDBError("GetAll", err, "Failed to scan action row")
-			return nil, err
+			return nil, queryError(ctx, err)
 		}
 		actions = append(actions, &action)
 	}
 
 	if err = rows.Err(); err != nil {
-		logDBError("GetAll", err, "Error occurred during row iteration")
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	logDBSuccess("GetAll", "Actions retrieved successfully", map[string]interface{}{
-		"count": len(actions),
-	})
-
 	return actions, nil
 }
 
-func (r *ActionRepository) GetByNoteID(noteID int64) ([]*models.Action, error) {
-	logDBOperation("GetByNoteID", "Fetching actions by note ID", noteID)
+func (r *ActionRepository) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
 	query := `
 		SELECT id, note_id, description, completed, created_at, updated_at
@@ -183,12 +164,9 @@ func (r *ActionRepository) GetByNoteID(noteID int64) ([]*models.Action, error) {
 		ORDER BY created_at DESC
 	`
 
-	logDBOperation("GetByNoteID", "Executing SQL query", query, "Note ID:", noteID)
-
-	rows, err := r.db.Query(query, noteID)
+	rows, err := r.db.QueryContext(ctx, query, noteID)
 	if err != nil {
-		logDBError("GetByNoteID", err, "Failed to execute query", noteID)
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 	defer rows.Close()
 
@@ -204,105 +182,164 @@ func (r *ActionRepository) GetByNoteID(noteID int64) ([]*models.Action, error) {
 			&action.UpdatedAt,
 		)
 		if err != nil {
-			logDBError("GetByNoteID", err, "Failed to scan action row", noteID)
-			return nil, err
+			return nil, queryError(ctx, err)
 		}
 		actions = append(actions, &action)
 	}
 
 	if err = rows.Err(); err != nil {
-		logDBError("GetByNoteID", err, "Error occurred during row iteration", noteID)
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	logDBSuccess("GetByNoteID", "Actions retrieved successfully", map[string]interface{}{
-		"note_id": noteID,
-		"count":   len(actions),
-	})
+	return actions, nil
+}
+
+func (r *ActionRepository) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	where, args := actionWhere(filter,
+		func(n int) string { return "$" + strconv.Itoa(n) },
+		func(t time.Time) interface{} { return t },
+	)
+	query := `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		` + where + `
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var actions []*models.Action
+	for rows.Next() {
+		var action models.Action
+		err := rows.Scan(
+			&action.ID,
+			&action.NoteID,
+			&action.Description,
+			&action.Completed,
+			&action.CreatedAt,
+			&action.UpdatedAt,
+		)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		actions = append(actions, &action)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
 
 	return actions, nil
 }
 
-func (r *ActionRepository) Update(id int64, action *models.UpdateActionRequest) (*models.Action, error) {
-	logDBOperation("Update", "Updating action", map[string]interface{}{
-		"action_id": id,
-		"completed": action.Completed,
-	})
+func (r *ActionRepository) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
+	where, args := actionWhere(filter,
+		func(n int) string { return "$" + strconv.Itoa(n) },
+		func(t time.Time) interface{} { return t },
+	)
 	query := `
+		SELECT note_id, COUNT(*), COUNT(*) FILTER (WHERE completed)
+		FROM actions
+		` + where + `
+		GROUP BY note_id
+	`
+	return countActions(ctx, r.db, query, args...)
+}
+
+func (r *ActionRepository) Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Only an action in the other state matches the first statement, so of
+	// concurrent updates to the same state exactly one reports the change
+	change := `
 		UPDATE actions
 		SET completed = $1, updated_at = $2
-		WHERE id = $3
+		WHERE id = $3 AND completed <> $1
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+	touch := `
+		UPDATE actions
+		SET updated_at = $1
+		WHERE id = $2
 		RETURNING id, note_id, description, completed, created_at, updated_at
 	`
 
 	now := time.Now()
-	var updatedAction models.Action
+	updatedAction, err := scanAction(r.db.QueryRowContext(ctx, change, action.Completed, now, id))
+	if err == nil {
+		return updatedAction, true, nil
+	}
+	if err = queryError(ctx, err); !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
 
-	logDBOperation("Update", "Executing SQL query", query)
+	updatedAction, err = scanAction(r.db.QueryRowContext(ctx, touch, now, id))
+	if err != nil {
+		return nil, false, queryError(ctx, err)
+	}
 
-	err := r.db.QueryRow(
-		query,
-		action.Completed,
-		now,
-		id,
-	).Scan(
-		&updatedAction.ID,
-		&updatedAction.NoteID,
-		&updatedAction.Description,
-		&updatedAction.Completed,
-		&updatedAction.CreatedAt,
-		&updatedAction.UpdatedAt,
-	)
+	return updatedAction, false, nil
+}
+
+// Toggle flips an action's completed state in a single statement, so
+// concurrent toggles never read the same state
+func (r *ActionRepository) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE actions
+		SET completed = NOT completed, updated_at = $1
+		WHERE id = $2
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
 
+	toggled, err := scanAction(r.db.QueryRowContext(ctx, query, time.Now(), id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			logDBError("Update", err, "Action not found for update", id)
-		} else {
-			logDBError("Update", err, "Database error while updating action", map[string]interface{}{
-				"action_id": id,
-				"completed": action.Completed,
-			})
-		}
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	logDBSuccess("Update", "Action updated successfully", map[string]interface{}{
-		"action_id":  updatedAction.ID,
-		"completed":  updatedAction.Completed,
-		"updated_at": updatedAction.UpdatedAt,
-	})
-
-	return &updatedAction, nil
+	return toggled, nil
 }
 
-func (r *ActionRepository) Delete(id int64) error {
-	logDBOperation("Delete", "Deleting action", id)
+func (r *ActionRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
 
 	query := `DELETE FROM actions WHERE id = $1`
 
-	logDBOperation("Delete", "Executing SQL query", query, "ID:", id)
-
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		logDBError("Delete", err, "Database error while deleting action", id)
-		return err
+		return queryError(ctx, err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		logDBError("Delete", err, "Error checking rows affected", id)
-		return err
+		return queryError(ctx, err)
 	}
 
 	if rowsAffected == 0 {
-		logDBOperation("Delete", "No action found to delete", id)
-	} else {
-		logDBSuccess("Delete", "Action deleted successfully", map[string]interface{}{
-			"action_id":     id,
-			"rows_affected": rowsAffected,
-		})
+		return ErrNotFound
 	}
 
 	return nil
 }
+
+func scanAction(row rowScanner) (*models.Action, error) {
+	var action models.Action
+	if err := row.Scan(&action.ID, &action.NoteID, &action.Description, &action.Completed, &action.CreatedAt, &action.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
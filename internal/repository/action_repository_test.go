@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -14,8 +16,21 @@ func TestActionRepository(t *testing.T) {
 	defer testutil.CleanupTestDB(t, db)
 	testutil.SetupTestSchema(t, db)
 
-	noteRepo := NewNoteRepository(db)
-	actionRepo := NewActionRepository(db)
+	testActionStore(t, NewNoteRepository(db, 5*time.Second), NewActionRepository(db, 5*time.Second))
+}
+
+func TestSQLiteActionRepository(t *testing.T) {
+	db := testutil.SetupSQLiteTestDB(t)
+	testActionStore(t, NewSQLiteNoteRepository(db, 5*time.Second), NewSQLiteActionRepository(db, 5*time.Second))
+}
+
+func TestMemoryActionRepository(t *testing.T) {
+	store := NewMemoryStore()
+	testActionStore(t, NewMemoryNoteRepository(store), NewMemoryActionRepository(store))
+}
+
+// testActionStore checks the behaviour every ActionStore must share
+func testActionStore(t *testing.T, noteRepo NoteStore, actionRepo ActionStore) {
 
 	// Helper function to create a test note
 	createTestNote := func(t *testing.T) *models.Note {
@@ -23,7 +38,7 @@ func TestActionRepository(t *testing.T) {
 			Content: "Test note for actions",
 			Date:    time.Now(),
 		}
-		created, err := noteRepo.Create(note)
+		created, err := noteRepo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -37,7 +52,7 @@ func TestActionRepository(t *testing.T) {
 			Description: "Test action",
 		}
 
-		created, err := actionRepo.Create(action)
+		created, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create action: %v", err)
 		}
@@ -56,18 +71,45 @@ func TestActionRepository(t *testing.T) {
 		}
 	})
 
+	t.Run("CreateMany", func(t *testing.T) {
+		note := createTestNote(t)
+		created, err := actionRepo.CreateMany(context.Background(), []*models.Action{
+			{NoteID: note.ID, Description: "Imported open"},
+			{NoteID: note.ID, Description: "Imported done", Completed: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create actions: %v", err)
+		}
+		if len(created) != 2 || created[0].Description != "Imported open" || !created[1].Completed || created[0].ID == 0 {
+			t.Fatalf("Expected both actions in order, got %v", created)
+		}
+
+		// A missing note fails the whole batch
+		_, err = actionRepo.CreateMany(context.Background(), []*models.Action{
+			{NoteID: note.ID, Description: "Rolled back"},
+			{NoteID: 999999, Description: "Orphan"},
+		})
+		if !errors.Is(err, ErrForeignKey) {
+			t.Errorf("Expected ErrForeignKey, got %v", err)
+		}
+		actions, err := actionRepo.GetByNoteID(context.Background(), note.ID)
+		if err != nil || len(actions) != 2 {
+			t.Errorf("Expected only the first batch to be stored, got %v, %v", actions, err)
+		}
+	})
+
 	t.Run("GetByID", func(t *testing.T) {
 		note := createTestNote(t)
 		action := &models.CreateActionRequest{
 			NoteID:      note.ID,
 			Description: "Test action for GetByID",
 		}
-		created, err := actionRepo.Create(action)
+		created, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
 
-		retrieved, err := actionRepo.GetByID(created.ID)
+		retrieved, err := actionRepo.GetByID(context.Background(), created.ID)
 		if err != nil {
 			t.Fatalf("Failed to get action: %v", err)
 		}
@@ -90,13 +132,13 @@ func TestActionRepository(t *testing.T) {
 
 		// Create test actions
 		for _, action := range actions {
-			_, err := actionRepo.Create(action)
+			_, err := actionRepo.Create(context.Background(), action)
 			if err != nil {
 				t.Fatalf("Failed to create test action: %v", err)
 			}
 		}
 
-		retrieved, err := actionRepo.GetByNoteID(note.ID)
+		retrieved, err := actionRepo.GetByNoteID(context.Background(), note.ID)
 		if err != nil {
 			t.Fatalf("Failed to get actions by note ID: %v", err)
 		}
@@ -112,7 +154,7 @@ func TestActionRepository(t *testing.T) {
 			NoteID:      note.ID,
 			Description: "Test action for Update",
 		}
-		created, err := actionRepo.Create(action)
+		created, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
@@ -120,14 +162,44 @@ func TestActionRepository(t *testing.T) {
 		update := &models.UpdateActionRequest{
 			Completed: true,
 		}
-		updated, err := actionRepo.Update(created.ID, update)
+		updated, changed, err := actionRepo.Update(context.Background(), created.ID, update)
 		if err != nil {
 			t.Fatalf("Failed to update action: %v", err)
 		}
 
-		if !updated.Completed {
+		if !updated.Completed || !changed {
 			t.Error("Expected action to be completed")
 		}
+
+		// Completing it again changes nothing
+		if _, changed, err := actionRepo.Update(context.Background(), created.ID, update); err != nil || changed {
+			t.Errorf("Expected a repeated update not to report a change, got %v, %v", changed, err)
+		}
+		if _, _, err := actionRepo.Update(context.Background(), 999999, update); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Toggle", func(t *testing.T) {
+		note := createTestNote(t)
+		created, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Test action for Toggle"})
+		if err != nil {
+			t.Fatalf("Failed to create test action: %v", err)
+		}
+
+		for _, expected := range []bool{true, false} {
+			toggled, err := actionRepo.Toggle(context.Background(), created.ID)
+			if err != nil {
+				t.Fatalf("Failed to toggle action: %v", err)
+			}
+			if toggled.Completed != expected {
+				t.Errorf("Expected completed to be %v, got %v", expected, toggled.Completed)
+			}
+		}
+
+		if _, err := actionRepo.Toggle(context.Background(), 999999); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
 	})
 
 	t.Run("Delete", func(t *testing.T) {
@@ -136,19 +208,128 @@ func TestActionRepository(t *testing.T) {
 			NoteID:      note.ID,
 			Description: "Test action for Delete",
 		}
-		created, err := actionRepo.Create(action)
+		created, err := actionRepo.Create(context.Background(), action)
 		if err != nil {
 			t.Fatalf("Failed to create test action: %v", err)
 		}
 
-		err = actionRepo.Delete(created.ID)
+		err = actionRepo.Delete(context.Background(), created.ID)
 		if err != nil {
 			t.Fatalf("Failed to delete action: %v", err)
 		}
 
-		_, err = actionRepo.GetByID(created.ID)
+		_, err = actionRepo.GetByID(context.Background(), created.ID)
 		if err == nil {
 			t.Error("Expected error when getting deleted action")
 		}
 	})
+
+	t.Run("NewestFirst", func(t *testing.T) {
+		note := createTestNote(t)
+		for _, description := range []string{"First", "Second"} {
+			if _, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: description}); err != nil {
+				t.Fatalf("Failed to create test action: %v", err)
+			}
+		}
+
+		retrieved, err := actionRepo.GetByNoteID(context.Background(), note.ID)
+		if err != nil {
+			t.Fatalf("Failed to get actions by note ID: %v", err)
+		}
+		if len(retrieved) != 2 || retrieved[0].Description != "Second" {
+			t.Errorf("Expected the newest action first, got %v", retrieved)
+		}
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		first, second, other := createTestNote(t), createTestNote(t), createTestNote(t)
+		var created []*models.Action
+		for _, noteID := range []int64{first.ID, second.ID, second.ID, other.ID} {
+			action, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: noteID, Description: "Found"})
+			if err != nil {
+				t.Fatalf("Failed to create test action: %v", err)
+			}
+			created = append(created, action)
+		}
+		if _, _, err := actionRepo.Update(context.Background(), created[1].ID, &models.UpdateActionRequest{Completed: true}); err != nil {
+			t.Fatalf("Failed to update test action: %v", err)
+		}
+
+		retrieved, err := actionRepo.Find(context.Background(), models.ActionFilter{NoteIDs: []int64{first.ID, second.ID}})
+		if err != nil {
+			t.Fatalf("Failed to find actions: %v", err)
+		}
+		if len(retrieved) != 3 || retrieved[0].ID != created[2].ID {
+			t.Errorf("Expected the 3 actions of both notes, newest first, got %v", retrieved)
+		}
+
+		completed := true
+		retrieved, err = actionRepo.Find(context.Background(), models.ActionFilter{NoteIDs: []int64{second.ID}, Completed: &completed})
+		if err != nil {
+			t.Fatalf("Failed to find actions: %v", err)
+		}
+		if len(retrieved) != 1 || retrieved[0].ID != created[1].ID {
+			t.Errorf("Expected the completed action, got %v", retrieved)
+		}
+
+		retrieved, err = actionRepo.Find(context.Background(), models.ActionFilter{
+			NoteIDs:      []int64{first.ID, second.ID, other.ID},
+			CreatedAfter: created[0].CreatedAt,
+		})
+		if err != nil {
+			t.Fatalf("Failed to find actions: %v", err)
+		}
+		for _, action := range retrieved {
+			if !action.CreatedAt.After(created[0].CreatedAt) {
+				t.Errorf("Expected actions created after %v, got one from %v", created[0].CreatedAt, action.CreatedAt)
+			}
+		}
+	})
+
+	t.Run("CountByNote", func(t *testing.T) {
+		counted, empty := createTestNote(t), createTestNote(t)
+		for _, description := range []string{"Open", "Done", "Also done"} {
+			action, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: counted.ID, Description: description})
+			if err != nil {
+				t.Fatalf("Failed to create test action: %v", err)
+			}
+			if description != "Open" {
+				actionRepo.Update(context.Background(), action.ID, &models.UpdateActionRequest{Completed: true})
+			}
+		}
+
+		counts, err := actionRepo.CountByNote(context.Background(), models.ActionFilter{NoteIDs: []int64{counted.ID, empty.ID}})
+		if err != nil {
+			t.Fatalf("Failed to count actions: %v", err)
+		}
+		if counts[counted.ID] != (models.ActionCount{Total: 3, Completed: 2}) {
+			t.Errorf("Expected 3 actions with 2 completed, got %+v", counts[counted.ID])
+		}
+		if _, ok := counts[empty.ID]; ok || len(counts) != 1 {
+			t.Errorf("Expected only the note with actions to be counted, got %v", counts)
+		}
+	})
+
+	t.Run("CreateForMissingNote", func(t *testing.T) {
+		_, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: 999999, Description: "Orphan"})
+		if err == nil {
+			t.Error("Expected error when creating an action for a missing note")
+		}
+	})
+
+	t.Run("DeleteNoteCascades", func(t *testing.T) {
+		note := createTestNote(t)
+		created, err := actionRepo.Create(context.Background(), &models.CreateActionRequest{NoteID: note.ID, Description: "Goes with its note"})
+		if err != nil {
+			t.Fatalf("Failed to create test action: %v", err)
+		}
+
+		if err := noteRepo.Delete(context.Background(), note.ID); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+
+		if _, err := actionRepo.GetByID(context.Background(), created.ID); err == nil {
+			t.Error("Expected the action to be deleted with its note")
+		}
+	})
 }
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// BackupRepository dumps and restores a Postgres database
+type BackupRepository struct {
+	db *sql.DB
+}
+
+func NewBackupRepository(db *sql.DB) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+// Dump reads both tables in one read-only repeatable read transaction, so
+// every action in a backup has its note
+func (r *BackupRepository) Dump(ctx context.Context, note func(*models.Note) error, action func(*models.Action) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	return dumpTables(ctx, tx, scanNote, scanAction, note, action)
+}
+
+func (r *BackupRepository) Restore(ctx context.Context, fn func(tx RestoreTx) error) error {
+	return inTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		return fn(&restoreTx{tx: tx})
+	})
+}
+
+// restoreTx is a RestoreTx on a Postgres transaction
+type restoreTx struct {
+	tx *sql.Tx
+}
+
+func (t *restoreTx) Clear(ctx context.Context) (notes, actions int, err error) {
+	return clearTables(ctx, t.tx)
+}
+
+func (t *restoreTx) FindNote(ctx context.Context, date, createdAt time.Time) (*models.Note, error) {
+	note, err := scanNote(t.tx.QueryRowContext(ctx, `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date = $1 AND created_at = $2
+		ORDER BY id
+		LIMIT 1
+	`, date, createdAt))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return note, nil
+}
+
+func (t *restoreTx) FindAction(ctx context.Context, noteID int64, createdAt time.Time) (*models.Action, error) {
+	action, err := scanAction(t.tx.QueryRowContext(ctx, `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		WHERE note_id = $1 AND created_at = $2
+		ORDER BY id
+		LIMIT 1
+	`, noteID, createdAt))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return action, nil
+}
+
+func (t *restoreTx) InsertNote(ctx context.Context, note *models.Note) (int64, error) {
+	var id int64
+	err := t.tx.QueryRowContext(ctx, `
+		INSERT INTO notes (content, date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, note.Content, note.Date, note.CreatedAt, note.UpdatedAt).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+func (t *restoreTx) InsertAction(ctx context.Context, action *models.Action) (int64, error) {
+	var id int64
+	err := t.tx.QueryRowContext(ctx, `
+		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, action.NoteID, action.Description, action.Completed, action.CreatedAt, action.UpdatedAt).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// dumpTables reads every note, then every action, both in ID order, in tx
+// so they come from the same snapshot
+func dumpTables(
+	ctx context.Context,
+	tx *sql.Tx,
+	scanNote func(rowScanner) (*models.Note, error),
+	scanAction func(rowScanner) (*models.Action, error),
+	note func(*models.Note) error,
+	action func(*models.Action) error,
+) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		ORDER BY id
+	`)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	err = eachRow(ctx, rows, func() error {
+		n, err := scanNote(rows)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		return note(n)
+	})
+	if err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		ORDER BY id
+	`)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	return eachRow(ctx, rows, func() error {
+		a, err := scanAction(rows)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		return action(a)
+	})
+}
+
+// eachRow calls fn for every row, closing rows when done
+func eachRow(ctx context.Context, rows *sql.Rows, fn func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// clearTables deletes every action and note
+func clearTables(ctx context.Context, tx *sql.Tx) (notes, actions int, err error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM actions")
+	if err != nil {
+		return 0, 0, queryError(ctx, err)
+	}
+	deletedActions, _ := result.RowsAffected()
+
+	result, err = tx.ExecContext(ctx, "DELETE FROM notes")
+	if err != nil {
+		return 0, 0, queryError(ctx, err)
+	}
+	deletedNotes, _ := result.RowsAffected()
+	return int(deletedNotes), int(deletedActions), nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/testutil"
+)
+
+func TestBackupRepository(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	defer testutil.CleanupTestDB(t, db)
+	testutil.SetupTestSchema(t, db)
+
+	testBackupStore(t, NewNoteRepository(db, 5*time.Second), NewActionRepository(db, 5*time.Second), NewBackupRepository(db))
+}
+
+func TestSQLiteBackupRepository(t *testing.T) {
+	db := testutil.SetupSQLiteTestDB(t)
+	testBackupStore(t, NewSQLiteNoteRepository(db, 5*time.Second), NewSQLiteActionRepository(db, 5*time.Second), NewSQLiteBackupRepository(db))
+}
+
+func TestMemoryBackupRepository(t *testing.T) {
+	store := NewMemoryStore()
+	testBackupStore(t, NewMemoryNoteRepository(store), NewMemoryActionRepository(store), NewMemoryBackupRepository(store))
+}
+
+// testBackupStore checks the behaviour every BackupStore must share. The
+// subtests run in order on one database.
+func testBackupStore(t *testing.T, noteRepo NoteStore, actionRepo ActionStore, backups BackupStore) {
+	ctx := context.Background()
+	day := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2026, time.October, 18, 9, 30, 0, 123456000, time.UTC)
+
+	note, err := noteRepo.Create(ctx, &models.CreateNoteRequest{Content: "Existing", Date: day})
+	if err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+	action, err := actionRepo.Create(ctx, &models.CreateActionRequest{NoteID: note.ID, Description: "Existing action"})
+	if err != nil {
+		t.Fatalf("Failed to create test action: %v", err)
+	}
+
+	t.Run("Dump", func(t *testing.T) {
+		var notes []*models.Note
+		var actions []*models.Action
+		err := backups.Dump(ctx,
+			func(n *models.Note) error {
+				if len(actions) > 0 {
+					t.Error("Expected every note before the actions")
+				}
+				notes = append(notes, n)
+				return nil
+			},
+			func(a *models.Action) error {
+				actions = append(actions, a)
+				return nil
+			},
+		)
+		if err != nil {
+			t.Fatalf("Failed to dump: %v", err)
+		}
+		if len(notes) != 1 || notes[0].ID != note.ID || notes[0].Content != "Existing" {
+			t.Errorf("Expected the test note, got %v", notes)
+		}
+		if len(actions) != 1 || actions[0].ID != action.ID || !actions[0].CreatedAt.Equal(action.CreatedAt) {
+			t.Errorf("Expected the test action with its timestamps, got %v", actions)
+		}
+	})
+
+	t.Run("RestoreCommits", func(t *testing.T) {
+		var noteID, actionID int64
+		err := backups.Restore(ctx, func(tx RestoreTx) error {
+			var err error
+			if noteID, err = tx.InsertNote(ctx, &models.Note{Content: "Restored", Date: day, CreatedAt: created, UpdatedAt: created}); err != nil {
+				return err
+			}
+			actionID, err = tx.InsertAction(ctx, &models.Action{NoteID: noteID, Description: "Restored action", Completed: true, CreatedAt: created, UpdatedAt: created})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+
+		restored, err := noteRepo.GetByID(ctx, noteID)
+		if err != nil {
+			t.Fatalf("Failed to get restored note: %v", err)
+		}
+		if !restored.CreatedAt.Equal(created) || !restored.Date.Equal(day) {
+			t.Errorf("Expected the note to keep its date and timestamps, got %v and %v", restored.Date, restored.CreatedAt)
+		}
+		restoredAction, err := actionRepo.GetByID(ctx, actionID)
+		if err != nil {
+			t.Fatalf("Failed to get restored action: %v", err)
+		}
+		if restoredAction.NoteID != noteID || !restoredAction.Completed {
+			t.Errorf("Expected the completed action on the restored note, got %+v", restoredAction)
+		}
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		backups.Restore(ctx, func(tx RestoreTx) error {
+			found, err := tx.FindNote(ctx, day, created)
+			if err != nil {
+				t.Fatalf("Failed to find note: %v", err)
+			}
+			if found.Content != "Restored" {
+				t.Errorf("Expected the restored note, got %q", found.Content)
+			}
+			if _, err := tx.FindNote(ctx, day.AddDate(0, 0, 1), created); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for another day, got %v", err)
+			}
+
+			foundAction, err := tx.FindAction(ctx, found.ID, created)
+			if err != nil {
+				t.Fatalf("Failed to find action: %v", err)
+			}
+			if foundAction.Description != "Restored action" {
+				t.Errorf("Expected the restored action, got %q", foundAction.Description)
+			}
+			if _, err := tx.FindAction(ctx, note.ID, created); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound on another note, got %v", err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("RestoreRollsBack", func(t *testing.T) {
+		failure := errors.New("failed")
+		err := backups.Restore(ctx, func(tx RestoreTx) error {
+			if _, _, err := tx.Clear(ctx); err != nil {
+				return err
+			}
+			if _, err := tx.InsertNote(ctx, &models.Note{Content: "Rolled back", Date: day, CreatedAt: created, UpdatedAt: created}); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected the error of fn, got %v", err)
+		}
+
+		notes, err := noteRepo.GetByDate(ctx, day)
+		if err != nil {
+			t.Fatalf("Failed to get notes: %v", err)
+		}
+		if len(notes) != 2 {
+			t.Errorf("Expected the 2 notes from before, got %d", len(notes))
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		var deletedNotes, deletedActions int
+		err := backups.Restore(ctx, func(tx RestoreTx) error {
+			var err error
+			deletedNotes, deletedActions, err = tx.Clear(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to clear: %v", err)
+		}
+		if deletedNotes != 2 || deletedActions != 2 {
+			t.Errorf("Expected 2 notes and 2 actions deleted, got %d and %d", deletedNotes, deletedActions)
+		}
+		if _, err := noteRepo.GetByID(ctx, note.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected the note to be gone, got %v", err)
+		}
+	})
+
+	t.Run("InsertActionForMissingNote", func(t *testing.T) {
+		err := backups.Restore(ctx, func(tx RestoreTx) error {
+			_, err := tx.InsertAction(ctx, &models.Action{NoteID: 999999, Description: "Orphan", CreatedAt: created, UpdatedAt: created})
+			return err
+		})
+		if !errors.Is(err, ErrForeignKey) {
+			t.Errorf("Expected ErrForeignKey, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/logging"
+)
+
+// withQueryTimeout derives the context a single query runs under
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// queryError reports the context's error when it ended the query, so callers
+// can tell a timeout or cancellation apart from a database failure whatever
+// the driver returned. Other errors are translated to repository errors.
+// The driver's error is logged at debug level with the request's logger.
+func queryError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	logging.FromContext(ctx).Debug("database query failed", "error", err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return translateError(err)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryError(t *testing.T) {
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	ctx, cancel := withQueryTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	if err := queryError(ctx, driverErr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if err := queryError(ctx, nil); err != nil {
+		t.Errorf("Expected no error for a successful query, got %v", err)
+	}
+	if err := queryError(context.Background(), driverErr); err != driverErr {
+		t.Errorf("Expected the driver error to be kept, got %v", err)
+	}
+}
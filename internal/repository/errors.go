@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors returned by every store. Driver errors are wrapped so the original
+// stays available to errors.As while callers only need errors.Is against
+// these values.
+var (
+	// ErrNotFound means the record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict means the write clashes with an existing record
+	ErrConflict = errors.New("record already exists")
+	// ErrForeignKey means the write references a record that does not exist,
+	// such as an action for a missing note
+	ErrForeignKey = errors.New("referenced record does not exist")
+	// ErrValidation means the database rejected a value
+	ErrValidation = errors.New("invalid value")
+)
+
+// translateError maps sql and driver errors onto the repository errors
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23503": // foreign_key_violation
+			return fmt.Errorf("%w: %w", ErrForeignKey, err)
+		case pqErr.Code == "23505": // unique_violation
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case pqErr.Code == "23502", pqErr.Code == "23514", pqErr.Code.Class() == "22":
+			// not_null_violation, check_violation and data exceptions
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		return err
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return fmt.Errorf("%w: %w", ErrForeignKey, err)
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+	}
+
+	return err
+}
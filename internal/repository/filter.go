@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// actionWhere builds the WHERE clause selecting the actions filter matches,
+// or an empty string when it matches all of them. placeholder returns the
+// marker for the nth argument and timestamp converts a time to the form the
+// database compares.
+func actionWhere(filter models.ActionFilter, placeholder func(n int) string, timestamp func(time.Time) interface{}) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return placeholder(len(args))
+	}
+
+	if len(filter.NoteIDs) > 0 {
+		markers := make([]string, len(filter.NoteIDs))
+		for i, id := range filter.NoteIDs {
+			markers[i] = arg(id)
+		}
+		conditions = append(conditions, "note_id IN ("+strings.Join(markers, ", ")+")")
+	}
+	if filter.Completed != nil {
+		conditions = append(conditions, "completed = "+arg(*filter.Completed))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > "+arg(timestamp(filter.CreatedAfter)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(timestamp(filter.CreatedBefore)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// inList returns the markers and arguments of a query selecting values with
+// an IN list; placeholder returns the marker for the nth argument
+func inList[T any](values []T, placeholder func(n int) string) (string, []interface{}) {
+	markers := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		markers[i] = placeholder(i + 1)
+		args[i] = value
+	}
+	return strings.Join(markers, ", "), args
+}
+
+// countActions runs a query selecting note IDs with their total and
+// completed action counts
+func countActions(ctx context.Context, db *sql.DB, query string, args ...interface{}) (map[int64]models.ActionCount, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]models.ActionCount)
+	for rows.Next() {
+		var noteID int64
+		var count models.ActionCount
+		if err := rows.Scan(&noteID, &count.Total, &count.Completed); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		counts[noteID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return counts, nil
+}
+
+// matchesAction reports whether action passes filter, for the memory store
+func matchesAction(filter models.ActionFilter, action *models.Action) bool {
+	if len(filter.NoteIDs) > 0 {
+		found := false
+		for _, id := range filter.NoteIDs {
+			if id == action.NoteID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.Completed != nil && action.Completed != *filter.Completed {
+		return false
+	}
+	if !filter.CreatedAfter.IsZero() && !action.CreatedAt.After(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !action.CreatedAt.Before(filter.CreatedBefore) {
+		return false
+	}
+	return true
+}
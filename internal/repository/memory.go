@@ -0,0 +1,602 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// MemoryStore keeps notes and actions in memory. It is shared by the memory
+// note and action repositories so deleting a note can drop its actions, as
+// the foreign key does in Postgres.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	notes        map[int64]*models.Note
+	actions      map[int64]*models.Action
+	nextNoteID   int64
+	nextActionID int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		notes:   make(map[int64]*models.Note),
+		actions: make(map[int64]*models.Action),
+	}
+}
+
+// memoryNow returns the current time at the precision Postgres stores timestamps
+func memoryNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// dateOnly drops the time of day the way a DATE column does
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// newestFirst orders by creation time, breaking ties by ID
+func newestFirst(aCreated, bCreated time.Time, aID, bID int64) bool {
+	if !aCreated.Equal(bCreated) {
+		return aCreated.After(bCreated)
+	}
+	return aID > bID
+}
+
+type MemoryNoteRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryNoteRepository(store *MemoryStore) *MemoryNoteRepository {
+	return &MemoryNoteRepository{store: store}
+}
+
+func (r *MemoryNoteRepository) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextNoteID++
+	created := memoryNow()
+	stored := &models.Note{
+		ID:        s.nextNoteID,
+		Content:   note.Content,
+		Date:      dateOnly(note.Date),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	s.notes[stored.ID] = stored
+
+	copied := *stored
+	return &copied, nil
+}
+
+func (r *MemoryNoteRepository) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	note, ok := s.notes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *note
+	return &copied, nil
+}
+
+func (r *MemoryNoteRepository) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day := dateOnly(date)
+	var notes []*models.Note
+	for _, note := range s.notes {
+		if note.Date.Equal(day) {
+			copied := *note
+			notes = append(notes, &copied)
+		}
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return newestFirst(notes[i].CreatedAt, notes[j].CreatedAt, notes[i].ID, notes[j].ID)
+	})
+	return notes, nil
+}
+
+func (r *MemoryNoteRepository) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	first, last := dateOnly(from), dateOnly(to)
+	var notes []*models.Note
+	for _, note := range s.notes {
+		if !note.Date.Before(first) && !note.Date.After(last) {
+			copied := *note
+			notes = append(notes, &copied)
+		}
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].Date.Equal(notes[j].Date) {
+			return notes[i].Date.After(notes[j].Date)
+		}
+		return newestFirst(notes[i].CreatedAt, notes[j].CreatedAt, notes[i].ID, notes[j].ID)
+	})
+	return notes, nil
+}
+
+func (r *MemoryNoteRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notes []*models.Note
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		note, ok := s.notes[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		copied := *note
+		notes = append(notes, &copied)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].Date.Equal(notes[j].Date) {
+			return notes[i].Date.After(notes[j].Date)
+		}
+		return newestFirst(notes[i].CreatedAt, notes[j].CreatedAt, notes[i].ID, notes[j].ID)
+	})
+	return notes, nil
+}
+
+func (r *MemoryNoteRepository) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	days := make(map[time.Time]bool, len(dates))
+	for _, date := range dates {
+		days[dateOnly(date)] = true
+	}
+	var notes []*models.Note
+	for _, note := range s.notes {
+		if days[note.Date] {
+			copied := *note
+			notes = append(notes, &copied)
+		}
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].Date.Equal(notes[j].Date) {
+			return notes[i].Date.After(notes[j].Date)
+		}
+		return newestFirst(notes[i].CreatedAt, notes[j].CreatedAt, notes[i].ID, notes[j].ID)
+	})
+	return notes, nil
+}
+
+func (r *MemoryNoteRepository) Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	stored.Content = note.Content
+	stored.UpdatedAt = memoryNow()
+
+	copied := *stored
+	return &copied, nil
+}
+
+func (r *MemoryNoteRepository) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notes[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.notes, id)
+	for actionID, action := range s.actions {
+		if action.NoteID == id {
+			delete(s.actions, actionID)
+		}
+	}
+	return nil
+}
+
+type MemoryActionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryActionRepository(store *MemoryStore) *MemoryActionRepository {
+	return &MemoryActionRepository{store: store}
+}
+
+func (r *MemoryActionRepository) Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notes[action.NoteID]; !ok {
+		return nil, fmt.Errorf("%w: note %d", ErrForeignKey, action.NoteID)
+	}
+
+	s.nextActionID++
+	created := memoryNow()
+	stored := &models.Action{
+		ID:          s.nextActionID,
+		NoteID:      action.NoteID,
+		Description: action.Description,
+		Completed:   false,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+	s.actions[stored.ID] = stored
+
+	copied := *stored
+	return &copied, nil
+}
+
+func (r *MemoryActionRepository) CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every note first so a missing one leaves nothing created
+	for _, action := range actions {
+		if _, ok := s.notes[action.NoteID]; !ok {
+			return nil, fmt.Errorf("%w: note %d", ErrForeignKey, action.NoteID)
+		}
+	}
+
+	created := memoryNow()
+	stored := make([]*models.Action, 0, len(actions))
+	for _, action := range actions {
+		s.nextActionID++
+		s.actions[s.nextActionID] = &models.Action{
+			ID:          s.nextActionID,
+			NoteID:      action.NoteID,
+			Description: action.Description,
+			Completed:   action.Completed,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+		}
+		copied := *s.actions[s.nextActionID]
+		stored = append(stored, &copied)
+	}
+	return stored, nil
+}
+
+func (r *MemoryActionRepository) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	action, ok := s.actions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	copied := *action
+	return &copied, nil
+}
+
+func (r *MemoryActionRepository) GetAll(ctx context.Context) ([]*models.Action, error) {
+	return r.list(ctx, func(*models.Action) bool { return true })
+}
+
+func (r *MemoryActionRepository) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	return r.list(ctx, func(action *models.Action) bool { return action.NoteID == noteID })
+}
+
+func (r *MemoryActionRepository) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	return r.list(ctx, func(action *models.Action) bool { return matchesAction(filter, action) })
+}
+
+func (r *MemoryActionRepository) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	actions, err := r.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]models.ActionCount)
+	for _, action := range actions {
+		count := counts[action.NoteID]
+		count.Total++
+		if action.Completed {
+			count.Completed++
+		}
+		counts[action.NoteID] = count
+	}
+	return counts, nil
+}
+
+// list returns copies of the actions matching keep, newest first
+func (r *MemoryActionRepository) list(ctx context.Context, keep func(*models.Action) bool) ([]*models.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var actions []*models.Action
+	for _, action := range s.actions {
+		if keep(action) {
+			copied := *action
+			actions = append(actions, &copied)
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		return newestFirst(actions[i].CreatedAt, actions[j].CreatedAt, actions[i].ID, actions[j].ID)
+	})
+	return actions, nil
+}
+
+func (r *MemoryActionRepository) Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.actions[id]
+	if !ok {
+		return nil, false, ErrNotFound
+	}
+	changed := stored.Completed != action.Completed
+	stored.Completed = action.Completed
+	stored.UpdatedAt = memoryNow()
+
+	copied := *stored
+	return &copied, changed, nil
+}
+
+func (r *MemoryActionRepository) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.actions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	stored.Completed = !stored.Completed
+	stored.UpdatedAt = memoryNow()
+
+	copied := *stored
+	return &copied, nil
+}
+
+func (r *MemoryActionRepository) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.actions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.actions, id)
+	return nil
+}
+
+// MemoryBackupRepository dumps and restores a MemoryStore
+type MemoryBackupRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryBackupRepository(store *MemoryStore) *MemoryBackupRepository {
+	return &MemoryBackupRepository{store: store}
+}
+
+func (r *MemoryBackupRepository) Dump(ctx context.Context, note func(*models.Note) error, action func(*models.Action) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Copy under the lock so the callbacks can take their time
+	s := r.store
+	s.mu.RLock()
+	notes := make([]models.Note, 0, len(s.notes))
+	for _, n := range s.notes {
+		notes = append(notes, *n)
+	}
+	actions := make([]models.Action, 0, len(s.actions))
+	for _, a := range s.actions {
+		actions = append(actions, *a)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	sort.Slice(actions, func(i, j int) bool { return actions[i].ID < actions[j].ID })
+	for i := range notes {
+		if err := note(&notes[i]); err != nil {
+			return err
+		}
+	}
+	for i := range actions {
+		if err := action(&actions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore holds the store's lock while fn runs and works on a copy of it,
+// which replaces the store only if fn succeeds
+func (r *MemoryBackupRepository) Restore(ctx context.Context, fn func(tx RestoreTx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryRestoreTx{
+		notes:        make(map[int64]*models.Note, len(s.notes)),
+		actions:      make(map[int64]*models.Action, len(s.actions)),
+		nextNoteID:   s.nextNoteID,
+		nextActionID: s.nextActionID,
+	}
+	for id, note := range s.notes {
+		tx.notes[id] = note
+	}
+	for id, action := range s.actions {
+		tx.actions[id] = action
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.notes, s.actions = tx.notes, tx.actions
+	s.nextNoteID, s.nextActionID = tx.nextNoteID, tx.nextActionID
+	return nil
+}
+
+// memoryRestoreTx is a RestoreTx on copies of a MemoryStore's maps. Stored
+// records are never modified, only added or dropped, so the copies can
+// share them with the store.
+type memoryRestoreTx struct {
+	notes        map[int64]*models.Note
+	actions      map[int64]*models.Action
+	nextNoteID   int64
+	nextActionID int64
+}
+
+func (t *memoryRestoreTx) Clear(ctx context.Context) (notes, actions int, err error) {
+	notes, actions = len(t.notes), len(t.actions)
+	t.notes = make(map[int64]*models.Note)
+	t.actions = make(map[int64]*models.Action)
+	return notes, actions, nil
+}
+
+func (t *memoryRestoreTx) FindNote(ctx context.Context, date, createdAt time.Time) (*models.Note, error) {
+	var found *models.Note
+	day := dateOnly(date)
+	for _, note := range t.notes {
+		if note.Date.Equal(day) && note.CreatedAt.Equal(createdAt) && (found == nil || note.ID < found.ID) {
+			found = note
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	copied := *found
+	return &copied, nil
+}
+
+func (t *memoryRestoreTx) FindAction(ctx context.Context, noteID int64, createdAt time.Time) (*models.Action, error) {
+	var found *models.Action
+	for _, action := range t.actions {
+		if action.NoteID == noteID && action.CreatedAt.Equal(createdAt) && (found == nil || action.ID < found.ID) {
+			found = action
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	copied := *found
+	return &copied, nil
+}
+
+func (t *memoryRestoreTx) InsertNote(ctx context.Context, note *models.Note) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	t.nextNoteID++
+	t.notes[t.nextNoteID] = &models.Note{
+		ID:        t.nextNoteID,
+		Content:   note.Content,
+		Date:      dateOnly(note.Date),
+		CreatedAt: note.CreatedAt.Truncate(time.Microsecond),
+		UpdatedAt: note.UpdatedAt.Truncate(time.Microsecond),
+	}
+	return t.nextNoteID, nil
+}
+
+func (t *memoryRestoreTx) InsertAction(ctx context.Context, action *models.Action) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if _, ok := t.notes[action.NoteID]; !ok {
+		return 0, fmt.Errorf("%w: note %d", ErrForeignKey, action.NoteID)
+	}
+	t.nextActionID++
+	t.actions[t.nextActionID] = &models.Action{
+		ID:          t.nextActionID,
+		NoteID:      action.NoteID,
+		Description: action.Description,
+		Completed:   action.Completed,
+		CreatedAt:   action.CreatedAt.Truncate(time.Microsecond),
+		UpdatedAt:   action.UpdatedAt.Truncate(time.Microsecond),
+	}
+	return t.nextActionID, nil
+}
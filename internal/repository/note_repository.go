@@ -1,21 +1,29 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"strconv"
 	"time"
 
 	"github.com/tehsis/logmeup-api/internal/models"
 )
 
 type NoteRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	timeout time.Duration
 }
 
-func NewNoteRepository(db *sql.DB) *NoteRepository {
-	return &NoteRepository{db: db}
+// NewNoteRepository creates a note repository. Each query is cancelled after
+// timeout; zero means queries are only bound by the caller's context.
+func NewNoteRepository(db *sql.DB, timeout time.Duration) *NoteRepository {
+	return &NoteRepository{db: db, timeout: timeout}
 }
 
-func (r *NoteRepository) Create(note *models.CreateNoteRequest) (*models.Note, error) {
+func (r *NoteRepository) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
 	query := `
 		INSERT INTO notes (content, date, created_at, updated_at)
 		VALUES ($1, $2, $3, $4)
@@ -24,7 +32,8 @@ func (r *NoteRepository) Create(note *models.CreateNoteRequest) (*models.Note, e
 
 	now := time.Now()
 	var createdNote models.Note
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		note.Content,
 		note.Date,
@@ -39,13 +48,16 @@ func (r *NoteRepository) Create(note *models.CreateNoteRequest) (*models.Note, e
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	return &createdNote, nil
 }
 
-func (r *NoteRepository) GetByID(id int64) (*models.Note, error) {
+func (r *NoteRepository) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
 	query := `
 		SELECT id, content, date, created_at, updated_at
 		FROM notes
@@ -53,7 +65,7 @@ func (r *NoteRepository) GetByID(id int64) (*models.Note, error) {
 	`
 
 	var note models.Note
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&note.ID,
 		&note.Content,
 		&note.Date,
@@ -62,23 +74,26 @@ func (r *NoteRepository) GetByID(id int64) (*models.Note, error) {
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	return &note, nil
 }
 
-func (r *NoteRepository) GetByDate(date time.Time) ([]*models.Note, error) {
+func (r *NoteRepository) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
 	query := `
 		SELECT id, content, date, created_at, updated_at
 		FROM notes
 		WHERE date = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 	`
 
-	rows, err := r.db.Query(query, date)
+	rows, err := r.db.QueryContext(ctx, query, date)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 	defer rows.Close()
 
@@ -93,15 +108,62 @@ func (r *NoteRepository) GetByDate(date time.Time) ([]*models.Note, error) {
 			&note.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, queryError(ctx, err)
 		}
 		notes = append(notes, &note)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
 	return notes, nil
 }
 
-func (r *NoteRepository) Update(id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+func (r *NoteRepository) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date BETWEEN $1 AND $2
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		var note models.Note
+		err := rows.Scan(
+			&note.ID,
+			&note.Content,
+			&note.Date,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+		)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, &note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *NoteRepository) Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
 	query := `
 		UPDATE notes
 		SET content = $1, updated_at = $2
@@ -111,7 +173,8 @@ func (r *NoteRepository) Update(id int64, note *models.UpdateNoteRequest) (*mode
 
 	now := time.Now()
 	var updatedNote models.Note
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		note.Content,
 		now,
@@ -125,14 +188,113 @@ func (r *NoteRepository) Update(id int64, note *models.UpdateNoteRequest) (*mode
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	return &updatedNote, nil
 }
 
-func (r *NoteRepository) Delete(id int64) error {
+func (r *NoteRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
 	query := `DELETE FROM notes WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
-} 
\ No newline at end of file
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *NoteRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	markers, args := inList(ids, func(n int) string { return "$" + strconv.Itoa(n) })
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE id IN (` + markers + `)
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.Content, &note.Date, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, &note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *NoteRepository) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	markers, args := inList(dates, func(n int) string { return "$" + strconv.Itoa(n) })
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date IN (` + markers + `)
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func scanNote(row rowScanner) (*models.Note, error) {
+	var note models.Note
+	if err := row.Scan(&note.ID, &note.Content, &note.Date, &note.CreatedAt, &note.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -14,7 +15,19 @@ func TestNoteRepository(t *testing.T) {
 	defer testutil.CleanupTestDB(t, db)
 	testutil.SetupTestSchema(t, db)
 
-	repo := NewNoteRepository(db)
+	testNoteStore(t, NewNoteRepository(db, 5*time.Second))
+}
+
+func TestSQLiteNoteRepository(t *testing.T) {
+	testNoteStore(t, NewSQLiteNoteRepository(testutil.SetupSQLiteTestDB(t), 5*time.Second))
+}
+
+func TestMemoryNoteRepository(t *testing.T) {
+	testNoteStore(t, NewMemoryNoteRepository(NewMemoryStore()))
+}
+
+// testNoteStore checks the behaviour every NoteStore must share
+func testNoteStore(t *testing.T, repo NoteStore) {
 
 	t.Run("Create", func(t *testing.T) {
 		note := &models.CreateNoteRequest{
@@ -22,7 +35,7 @@ func TestNoteRepository(t *testing.T) {
 			Date:    time.Now(),
 		}
 
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create note: %v", err)
 		}
@@ -33,7 +46,8 @@ func TestNoteRepository(t *testing.T) {
 		if created.Content != note.Content {
 			t.Errorf("Expected content %q, got %q", note.Content, created.Content)
 		}
-		if !created.Date.Equal(note.Date) {
+		// Notes are stored by calendar day
+		if created.Date.Format("2006-01-02") != note.Date.Format("2006-01-02") {
 			t.Errorf("Expected date %v, got %v", note.Date, created.Date)
 		}
 	})
@@ -44,13 +58,13 @@ func TestNoteRepository(t *testing.T) {
 			Content: "Test note for GetByID",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
 
 		// Test GetByID
-		retrieved, err := repo.GetByID(created.ID)
+		retrieved, err := repo.GetByID(context.Background(), created.ID)
 		if err != nil {
 			t.Fatalf("Failed to get note: %v", err)
 		}
@@ -64,7 +78,8 @@ func TestNoteRepository(t *testing.T) {
 	})
 
 	t.Run("GetByDate", func(t *testing.T) {
-		date := time.Now()
+		// A day no other subtest uses, at midnight like the handler parses it
+		date := time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC)
 		notes := []*models.CreateNoteRequest{
 			{Content: "Note 1", Date: date},
 			{Content: "Note 2", Date: date},
@@ -73,14 +88,14 @@ func TestNoteRepository(t *testing.T) {
 
 		// Create test notes
 		for _, note := range notes {
-			_, err := repo.Create(note)
+			_, err := repo.Create(context.Background(), note)
 			if err != nil {
 				t.Fatalf("Failed to create test note: %v", err)
 			}
 		}
 
 		// Test GetByDate
-		retrieved, err := repo.GetByDate(date)
+		retrieved, err := repo.GetByDate(context.Background(), date)
 		if err != nil {
 			t.Fatalf("Failed to get notes by date: %v", err)
 		}
@@ -90,13 +105,85 @@ func TestNoteRepository(t *testing.T) {
 		}
 	})
 
+	t.Run("GetByDateRange", func(t *testing.T) {
+		// Days no other subtest uses
+		from := time.Date(2020, time.February, 10, 0, 0, 0, 0, time.UTC)
+		notes := []*models.CreateNoteRequest{
+			{Content: "Before", Date: from.AddDate(0, 0, -1)},
+			{Content: "First day", Date: from},
+			{Content: "Last day", Date: from.AddDate(0, 0, 2)},
+			{Content: "After", Date: from.AddDate(0, 0, 3)},
+		}
+		for _, note := range notes {
+			if _, err := repo.Create(context.Background(), note); err != nil {
+				t.Fatalf("Failed to create test note: %v", err)
+			}
+		}
+
+		retrieved, err := repo.GetByDateRange(context.Background(), from, from.AddDate(0, 0, 2))
+		if err != nil {
+			t.Fatalf("Failed to get notes by date range: %v", err)
+		}
+
+		if len(retrieved) != 2 {
+			t.Fatalf("Expected 2 notes, got %d", len(retrieved))
+		}
+		if retrieved[0].Content != "Last day" || retrieved[1].Content != "First day" {
+			t.Errorf("Expected the latest day first, got %q and %q", retrieved[0].Content, retrieved[1].Content)
+		}
+	})
+
+	t.Run("GetByIDs", func(t *testing.T) {
+		var ids []int64
+		for _, content := range []string{"Older", "Newer"} {
+			created, err := repo.Create(context.Background(), &models.CreateNoteRequest{Content: content, Date: time.Now()})
+			if err != nil {
+				t.Fatalf("Failed to create test note: %v", err)
+			}
+			ids = append(ids, created.ID)
+		}
+
+		retrieved, err := repo.GetByIDs(context.Background(), append(ids, 999999))
+		if err != nil {
+			t.Fatalf("Failed to get notes by IDs: %v", err)
+		}
+		if len(retrieved) != 2 || retrieved[0].ID != ids[1] {
+			t.Errorf("Expected both notes, newest first, got %v", retrieved)
+		}
+
+		if retrieved, err := repo.GetByIDs(context.Background(), nil); err != nil || len(retrieved) != 0 {
+			t.Errorf("Expected no notes for no IDs, got %v, %v", retrieved, err)
+		}
+	})
+
+	t.Run("GetByDates", func(t *testing.T) {
+		first := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+		for i, content := range []string{"First", "Between", "Last"} {
+			if _, err := repo.Create(context.Background(), &models.CreateNoteRequest{Content: content, Date: first.AddDate(0, 0, i)}); err != nil {
+				t.Fatalf("Failed to create test note: %v", err)
+			}
+		}
+
+		retrieved, err := repo.GetByDates(context.Background(), []time.Time{first, first.AddDate(0, 0, 2)})
+		if err != nil {
+			t.Fatalf("Failed to get notes by dates: %v", err)
+		}
+		if len(retrieved) != 2 || retrieved[0].Content != "Last" || retrieved[1].Content != "First" {
+			t.Errorf("Expected the first and last days, latest first, got %v", retrieved)
+		}
+
+		if retrieved, err := repo.GetByDates(context.Background(), nil); err != nil || len(retrieved) != 0 {
+			t.Errorf("Expected no notes for no dates, got %v, %v", retrieved, err)
+		}
+	})
+
 	t.Run("Update", func(t *testing.T) {
 		// Create a test note
 		note := &models.CreateNoteRequest{
 			Content: "Test note for Update",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
@@ -105,7 +192,7 @@ func TestNoteRepository(t *testing.T) {
 		update := &models.UpdateNoteRequest{
 			Content: "Updated content",
 		}
-		updated, err := repo.Update(created.ID, update)
+		updated, err := repo.Update(context.Background(), created.ID, update)
 		if err != nil {
 			t.Fatalf("Failed to update note: %v", err)
 		}
@@ -121,19 +208,19 @@ func TestNoteRepository(t *testing.T) {
 			Content: "Test note for Delete",
 			Date:    time.Now(),
 		}
-		created, err := repo.Create(note)
+		created, err := repo.Create(context.Background(), note)
 		if err != nil {
 			t.Fatalf("Failed to create test note: %v", err)
 		}
 
 		// Test Delete
-		err = repo.Delete(created.ID)
+		err = repo.Delete(context.Background(), created.ID)
 		if err != nil {
 			t.Fatalf("Failed to delete note: %v", err)
 		}
 
 		// Verify note is deleted
-		_, err = repo.GetByID(created.ID)
+		_, err = repo.GetByID(context.Background(), created.ID)
 		if err == nil {
 			t.Error("Expected error when getting deleted note")
 		}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tehsis/logmeup-api/internal/backoff"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// isTransient reports whether err came from a connection that broke or was
+// refused, so running the same read again may succeed. Errors from the
+// query itself, and timeouts, are not transient.
+func isTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// connection_exception, admin_shutdown and cannot_connect_now
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P03"
+	}
+	return false
+}
+
+// retryRead runs read until it succeeds, fails for a reason other than a
+// broken connection, or has been retried retries times
+func retryRead[T any](ctx context.Context, retries int, method string, read func() (T, error)) (T, error) {
+	b := &backoff.Backoff{Initial: 50 * time.Millisecond, Max: time.Second}
+	for attempt := 0; ; attempt++ {
+		result, err := read()
+		if err == nil || attempt == retries || !isTransient(err) {
+			return result, err
+		}
+		logging.FromContext(ctx).Warn("retrying read after a connection error", "method", method, "attempt", attempt+1, "error", err)
+		if b.Wait(ctx) != nil {
+			return result, err
+		}
+	}
+}
+
+// retryingNoteStore retries the reads of a NoteStore
+type retryingNoteStore struct {
+	NoteStore
+	retries int
+}
+
+// RetryNoteReads wraps store so its Get methods are retried up to
+// retries times when the connection breaks. Writes are never retried since
+// the first attempt may have been applied.
+func RetryNoteReads(store NoteStore, retries int) NoteStore {
+	return &retryingNoteStore{NoteStore: store, retries: retries}
+}
+
+func (s *retryingNoteStore) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	return retryRead(ctx, s.retries, "NoteStore.GetByID", func() (*models.Note, error) {
+		return s.NoteStore.GetByID(ctx, id)
+	})
+}
+
+func (s *retryingNoteStore) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	return retryRead(ctx, s.retries, "NoteStore.GetByDate", func() ([]*models.Note, error) {
+		return s.NoteStore.GetByDate(ctx, date)
+	})
+}
+
+func (s *retryingNoteStore) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	return retryRead(ctx, s.retries, "NoteStore.GetByDateRange", func() ([]*models.Note, error) {
+		return s.NoteStore.GetByDateRange(ctx, from, to)
+	})
+}
+
+func (s *retryingNoteStore) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	return retryRead(ctx, s.retries, "NoteStore.GetByIDs", func() ([]*models.Note, error) {
+		return s.NoteStore.GetByIDs(ctx, ids)
+	})
+}
+
+func (s *retryingNoteStore) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	return retryRead(ctx, s.retries, "NoteStore.GetByDates", func() ([]*models.Note, error) {
+		return s.NoteStore.GetByDates(ctx, dates)
+	})
+}
+
+// retryingActionStore retries the reads of an ActionStore
+type retryingActionStore struct {
+	ActionStore
+	retries int
+}
+
+// RetryActionReads wraps store so its Get methods, Find and CountByNote are
+// retried up to retries times when the connection breaks. Writes are never
+// retried.
+func RetryActionReads(store ActionStore, retries int) ActionStore {
+	return &retryingActionStore{ActionStore: store, retries: retries}
+}
+
+func (s *retryingActionStore) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	return retryRead(ctx, s.retries, "ActionStore.GetByID", func() (*models.Action, error) {
+		return s.ActionStore.GetByID(ctx, id)
+	})
+}
+
+func (s *retryingActionStore) GetAll(ctx context.Context) ([]*models.Action, error) {
+	return retryRead(ctx, s.retries, "ActionStore.GetAll", func() ([]*models.Action, error) {
+		return s.ActionStore.GetAll(ctx)
+	})
+}
+
+func (s *retryingActionStore) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	return retryRead(ctx, s.retries, "ActionStore.GetByNoteID", func() ([]*models.Action, error) {
+		return s.ActionStore.GetByNoteID(ctx, noteID)
+	})
+}
+
+func (s *retryingActionStore) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	return retryRead(ctx, s.retries, "ActionStore.Find", func() ([]*models.Action, error) {
+		return s.ActionStore.Find(ctx, filter)
+	})
+}
+
+func (s *retryingActionStore) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	return retryRead(ctx, s.retries, "ActionStore.CountByNote", func() (map[int64]models.ActionCount, error) {
+		return s.ActionStore.CountByNote(ctx, filter)
+	})
+}
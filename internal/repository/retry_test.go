@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// flakyNoteStore fails its first calls with err
+type flakyNoteStore struct {
+	NoteStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyNoteStore) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, s.err
+	}
+	return s.NoteStore.GetByID(ctx, id)
+}
+
+func (s *flakyNoteStore) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	s.calls++
+	return nil, s.err
+}
+
+func TestRetryNoteReads(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryNoteRepository(NewMemoryStore())
+	note, err := memory.Create(ctx, &models.CreateNoteRequest{Content: "retried", Date: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	t.Run("TransientErrors", func(t *testing.T) {
+		for _, transient := range []error{driver.ErrBadConn, io.ErrUnexpectedEOF, &pq.Error{Code: "57P01"}} {
+			flaky := &flakyNoteStore{NoteStore: memory, failures: 2, err: transient}
+			got, err := RetryNoteReads(flaky, 2).GetByID(ctx, note.ID)
+			if err != nil {
+				t.Fatalf("Expected the read to succeed after retrying %v, got %v", transient, err)
+			}
+			if got.Content != "retried" || flaky.calls != 3 {
+				t.Errorf("Expected the note after 3 calls, got %q after %d", got.Content, flaky.calls)
+			}
+		}
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		flaky := &flakyNoteStore{NoteStore: memory, failures: 5, err: driver.ErrBadConn}
+		if _, err := RetryNoteReads(flaky, 2).GetByID(ctx, note.ID); !errors.Is(err, driver.ErrBadConn) {
+			t.Errorf("Expected the connection error, got %v", err)
+		}
+		if flaky.calls != 3 {
+			t.Errorf("Expected 3 calls, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("OtherErrors", func(t *testing.T) {
+		flaky := &flakyNoteStore{NoteStore: memory, failures: 1, err: ErrNotFound}
+		if _, err := RetryNoteReads(flaky, 2).GetByID(ctx, note.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if flaky.calls != 1 {
+			t.Errorf("Expected a single call, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("Writes", func(t *testing.T) {
+		flaky := &flakyNoteStore{NoteStore: memory, err: driver.ErrBadConn}
+		RetryNoteReads(flaky, 2).Create(ctx, &models.CreateNoteRequest{Content: "once", Date: time.Now()})
+		if flaky.calls != 1 {
+			t.Errorf("Expected writes not to be retried, got %d calls", flaky.calls)
+		}
+	})
+}
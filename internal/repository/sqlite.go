@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// SQLite has no date or timestamp types, so dates are stored as YYYY-MM-DD
+// and timestamps as fixed-width UTC text that sorts chronologically
+const (
+	sqliteDateLayout      = "2006-01-02"
+	sqliteTimestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func sqliteDate(t time.Time) string {
+	return t.Format(sqliteDateLayout)
+}
+
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteNow returns the current time at the precision timestamps are stored
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func scanSQLiteNote(row rowScanner) (*models.Note, error) {
+	var note models.Note
+	var date, createdAt, updatedAt string
+	if err := row.Scan(&note.ID, &note.Content, &date, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if note.Date, err = time.Parse(sqliteDateLayout, date); err != nil {
+		return nil, err
+	}
+	if note.CreatedAt, err = time.Parse(sqliteTimestampLayout, createdAt); err != nil {
+		return nil, err
+	}
+	if note.UpdatedAt, err = time.Parse(sqliteTimestampLayout, updatedAt); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func scanSQLiteAction(row rowScanner) (*models.Action, error) {
+	var action models.Action
+	var createdAt, updatedAt string
+	if err := row.Scan(&action.ID, &action.NoteID, &action.Description, &action.Completed, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if action.CreatedAt, err = time.Parse(sqliteTimestampLayout, createdAt); err != nil {
+		return nil, err
+	}
+	if action.UpdatedAt, err = time.Parse(sqliteTimestampLayout, updatedAt); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
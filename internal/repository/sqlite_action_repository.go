@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+type SQLiteActionRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewSQLiteActionRepository creates an action repository backed by SQLite.
+// Each query is cancelled after timeout; zero disables the limit.
+func NewSQLiteActionRepository(db *sql.DB, timeout time.Duration) *SQLiteActionRepository {
+	return &SQLiteActionRepository{db: db, timeout: timeout}
+}
+
+func (r *SQLiteActionRepository) Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+
+	now := sqliteTimestamp(sqliteNow())
+	createdAction, err := scanSQLiteAction(r.db.QueryRowContext(ctx, query, action.NoteID, action.Description, false, now, now))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return createdAction, nil
+}
+
+// CreateMany inserts the actions in one transaction. The query timeout
+// bounds each insert rather than the whole batch.
+func (r *SQLiteActionRepository) CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error) {
+	query := `
+		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+
+	now := sqliteTimestamp(sqliteNow())
+	created := make([]*models.Action, 0, len(actions))
+	err := inTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		for _, action := range actions {
+			stored, err := insertAction(ctx, r.timeout, tx, scanSQLiteAction, query, action.NoteID, action.Description, action.Completed, now, now)
+			if err != nil {
+				return err
+			}
+			created = append(created, stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *SQLiteActionRepository) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		WHERE id = ?
+	`
+
+	action, err := scanSQLiteAction(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return action, nil
+}
+
+func (r *SQLiteActionRepository) GetAll(ctx context.Context) ([]*models.Action, error) {
+	query := `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		ORDER BY created_at DESC, id DESC
+	`
+
+	return r.list(ctx, query)
+}
+
+func (r *SQLiteActionRepository) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	query := `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		WHERE note_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	return r.list(ctx, query, noteID)
+}
+
+func (r *SQLiteActionRepository) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	where, args := actionWhere(filter,
+		func(int) string { return "?" },
+		func(t time.Time) interface{} { return sqliteTimestamp(t) },
+	)
+	query := `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		` + where + `
+		ORDER BY created_at DESC, id DESC
+	`
+
+	return r.list(ctx, query, args...)
+}
+
+func (r *SQLiteActionRepository) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	where, args := actionWhere(filter,
+		func(int) string { return "?" },
+		func(t time.Time) interface{} { return sqliteTimestamp(t) },
+	)
+	query := `
+		SELECT note_id, COUNT(*), SUM(completed)
+		FROM actions
+		` + where + `
+		GROUP BY note_id
+	`
+	return countActions(ctx, r.db, query, args...)
+}
+
+// list runs a query selecting action rows
+func (r *SQLiteActionRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var actions []*models.Action
+	for rows.Next() {
+		action, err := scanSQLiteAction(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		actions = append(actions, action)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return actions, nil
+}
+
+func (r *SQLiteActionRepository) Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// As for Postgres, only a change of state matches the first statement
+	change := `
+		UPDATE actions
+		SET completed = ?, updated_at = ?
+		WHERE id = ? AND completed <> ?
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+	touch := `
+		UPDATE actions
+		SET updated_at = ?
+		WHERE id = ?
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+
+	now := sqliteTimestamp(sqliteNow())
+	updatedAction, err := scanSQLiteAction(r.db.QueryRowContext(ctx, change, action.Completed, now, id, action.Completed))
+	if err == nil {
+		return updatedAction, true, nil
+	}
+	if err = queryError(ctx, err); !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+
+	updatedAction, err = scanSQLiteAction(r.db.QueryRowContext(ctx, touch, now, id))
+	if err != nil {
+		return nil, false, queryError(ctx, err)
+	}
+
+	return updatedAction, false, nil
+}
+
+// Toggle flips an action's completed state in a single statement, so
+// concurrent toggles never read the same state
+func (r *SQLiteActionRepository) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE actions
+		SET completed = NOT completed, updated_at = ?
+		WHERE id = ?
+		RETURNING id, note_id, description, completed, created_at, updated_at
+	`
+
+	toggled, err := scanSQLiteAction(r.db.QueryRowContext(ctx, query, sqliteTimestamp(sqliteNow()), id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return toggled, nil
+}
+
+func (r *SQLiteActionRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `DELETE FROM actions WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
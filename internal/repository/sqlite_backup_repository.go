@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// SQLiteBackupRepository dumps and restores a SQLite database
+type SQLiteBackupRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteBackupRepository(db *sql.DB) *SQLiteBackupRepository {
+	return &SQLiteBackupRepository{db: db}
+}
+
+// Dump reads both tables in one transaction, whose snapshot SQLite takes at
+// the first read, so every action in a backup has its note
+func (r *SQLiteBackupRepository) Dump(ctx context.Context, note func(*models.Note) error, action func(*models.Action) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	return dumpTables(ctx, tx, scanSQLiteNote, scanSQLiteAction, note, action)
+}
+
+func (r *SQLiteBackupRepository) Restore(ctx context.Context, fn func(tx RestoreTx) error) error {
+	return inTransaction(ctx, r.db, func(tx *sql.Tx) error {
+		return fn(&sqliteRestoreTx{tx: tx})
+	})
+}
+
+// sqliteRestoreTx is a RestoreTx on a SQLite transaction
+type sqliteRestoreTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqliteRestoreTx) Clear(ctx context.Context) (notes, actions int, err error) {
+	return clearTables(ctx, t.tx)
+}
+
+func (t *sqliteRestoreTx) FindNote(ctx context.Context, date, createdAt time.Time) (*models.Note, error) {
+	note, err := scanSQLiteNote(t.tx.QueryRowContext(ctx, `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date = ? AND created_at = ?
+		ORDER BY id
+		LIMIT 1
+	`, sqliteDate(date), sqliteTimestamp(createdAt)))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return note, nil
+}
+
+func (t *sqliteRestoreTx) FindAction(ctx context.Context, noteID int64, createdAt time.Time) (*models.Action, error) {
+	action, err := scanSQLiteAction(t.tx.QueryRowContext(ctx, `
+		SELECT id, note_id, description, completed, created_at, updated_at
+		FROM actions
+		WHERE note_id = ? AND created_at = ?
+		ORDER BY id
+		LIMIT 1
+	`, noteID, sqliteTimestamp(createdAt)))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return action, nil
+}
+
+func (t *sqliteRestoreTx) InsertNote(ctx context.Context, note *models.Note) (int64, error) {
+	var id int64
+	err := t.tx.QueryRowContext(ctx, `
+		INSERT INTO notes (content, date, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, note.Content, sqliteDate(note.Date), sqliteTimestamp(note.CreatedAt), sqliteTimestamp(note.UpdatedAt)).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+func (t *sqliteRestoreTx) InsertAction(ctx context.Context, action *models.Action) (int64, error) {
+	var id int64
+	err := t.tx.QueryRowContext(ctx, `
+		INSERT INTO actions (note_id, description, completed, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, action.NoteID, action.Description, action.Completed, sqliteTimestamp(action.CreatedAt), sqliteTimestamp(action.UpdatedAt)).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+type SQLiteNoteRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewSQLiteNoteRepository creates a note repository backed by SQLite. Each
+// query is cancelled after timeout; zero disables the limit.
+func NewSQLiteNoteRepository(db *sql.DB, timeout time.Duration) *SQLiteNoteRepository {
+	return &SQLiteNoteRepository{db: db, timeout: timeout}
+}
+
+func (r *SQLiteNoteRepository) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO notes (content, date, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, content, date, created_at, updated_at
+	`
+
+	now := sqliteTimestamp(sqliteNow())
+	createdNote, err := scanSQLiteNote(r.db.QueryRowContext(ctx, query, note.Content, sqliteDate(note.Date), now, now))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return createdNote, nil
+}
+
+func (r *SQLiteNoteRepository) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE id = ?
+	`
+
+	note, err := scanSQLiteNote(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return note, nil
+}
+
+func (r *SQLiteNoteRepository) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteDate(date))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		note, err := scanSQLiteNote(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *SQLiteNoteRepository) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteDate(from), sqliteDate(to))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		note, err := scanSQLiteNote(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *SQLiteNoteRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	markers, args := inList(ids, func(int) string { return "?" })
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE id IN (` + markers + `)
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		note, err := scanSQLiteNote(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *SQLiteNoteRepository) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	days := make([]string, len(dates))
+	for i, date := range dates {
+		days[i] = sqliteDate(date)
+	}
+	markers, args := inList(days, func(int) string { return "?" })
+	query := `
+		SELECT id, content, date, created_at, updated_at
+		FROM notes
+		WHERE date IN (` + markers + `)
+		ORDER BY date DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var notes []*models.Note
+	for rows.Next() {
+		note, err := scanSQLiteNote(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return notes, nil
+}
+
+func (r *SQLiteNoteRepository) Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes
+		SET content = ?, updated_at = ?
+		WHERE id = ?
+		RETURNING id, content, date, created_at, updated_at
+	`
+
+	updatedNote, err := scanSQLiteNote(r.db.QueryRowContext(ctx, query, note.Content, sqliteTimestamp(sqliteNow()), id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return updatedNote, nil
+}
+
+func (r *SQLiteNoteRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := `DELETE FROM notes WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// NoteStore persists notes. Implementations report failures with the
+// repository errors, such as ErrNotFound for a missing note, and list notes
+// newest first.
+type NoteStore interface {
+	Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error)
+	GetByID(ctx context.Context, id int64) (*models.Note, error)
+	GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error)
+	// GetByDateRange lists the notes dated from one day to another,
+	// inclusive, latest day first
+	GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error)
+	// GetByIDs lists the notes with any of ids in a single query, skipping
+	// IDs without a note
+	GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error)
+	// GetByDates lists the notes dated any of dates in a single query,
+	// latest day first
+	GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error)
+	Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// ActionStore persists actions. Implementations report failures with the
+// repository errors, such as ErrForeignKey for an action on a missing note,
+// list actions newest first and drop a note's actions when the note is
+// deleted.
+type ActionStore interface {
+	Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error)
+	// CreateMany creates actions with the note, description and completed
+	// state of each in one transaction, so either all are created or none,
+	// and returns them in the same order
+	CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error)
+	GetByID(ctx context.Context, id int64) (*models.Action, error)
+	GetAll(ctx context.Context) ([]*models.Action, error)
+	GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error)
+	// Find lists the actions matching filter in a single query
+	Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error)
+	// CountByNote tallies the actions matching filter for each note in a
+	// single query; notes without matching actions are left out
+	CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error)
+	// Update sets an action's completed state; changed reports whether it
+	// was in the other state, which only one of concurrent updates sees
+	Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (updated *models.Action, changed bool, err error)
+	// Toggle flips an action's completed state atomically and returns the
+	// result
+	Toggle(ctx context.Context, id int64) (*models.Action, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// BackupStore reads every note and action for backups and restores them
+// atomically. Neither method is bounded by the query timeout, since both
+// touch the whole database.
+type BackupStore interface {
+	// Dump calls note for every note, then action for every action, both in
+	// ID order, as the rows are read. Both come from one consistent snapshot,
+	// so every action's note is included.
+	Dump(ctx context.Context, note func(*models.Note) error, action func(*models.Action) error) error
+	// Restore runs fn in a transaction, committing its writes when it
+	// returns nil and rolling them back otherwise
+	Restore(ctx context.Context, fn func(tx RestoreTx) error) error
+}
+
+// RestoreTx writes records inside a BackupStore transaction. Records are
+// matched by when they were created rather than by ID, since IDs differ
+// between databases.
+type RestoreTx interface {
+	// Clear deletes every note and action, returning how many there were
+	Clear(ctx context.Context) (notes, actions int, err error)
+	// FindNote returns the note for date created at createdAt, or ErrNotFound
+	FindNote(ctx context.Context, date, createdAt time.Time) (*models.Note, error)
+	// FindAction returns the action on a note created at createdAt, or
+	// ErrNotFound
+	FindAction(ctx context.Context, noteID int64, createdAt time.Time) (*models.Action, error)
+	// InsertNote stores a note with its content, date and timestamps under a
+	// new ID, which it returns
+	InsertNote(ctx context.Context, note *models.Note) (int64, error)
+	// InsertAction stores an action like InsertNote; its NoteID must already
+	// be the ID the note was given
+	InsertAction(ctx context.Context, action *models.Action) (int64, error)
+}
+
+var (
+	_ NoteStore   = (*NoteRepository)(nil)
+	_ ActionStore = (*ActionRepository)(nil)
+	_ NoteStore   = (*MemoryNoteRepository)(nil)
+	_ ActionStore = (*MemoryActionRepository)(nil)
+	_ NoteStore   = (*SQLiteNoteRepository)(nil)
+	_ ActionStore = (*SQLiteActionRepository)(nil)
+	_ BackupStore = (*BackupRepository)(nil)
+	_ BackupStore = (*SQLiteBackupRepository)(nil)
+	_ BackupStore = (*MemoryBackupRepository)(nil)
+)
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+// inTransaction runs fn in a transaction, committing when it returns nil
+func inTransaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// insertAction runs an INSERT … RETURNING of an action in tx, bounded by
+// timeout
+func insertAction(ctx context.Context, timeout time.Duration, tx *sql.Tx, scan func(rowScanner) (*models.Action, error), query string, args ...interface{}) (*models.Action, error) {
+	ctx, cancel := withQueryTimeout(ctx, timeout)
+	defer cancel()
+
+	action, err := scan(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return action, nil
+}
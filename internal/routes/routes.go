@@ -1,21 +1,82 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/metrics"
+	"github.com/tehsis/logmeup-api/internal/openapi"
 )
 
 // WebSocketHub interface for the hub
 type WebSocketHub interface {
 	HandleWebSocket(c *gin.Context)
+	HandlePresence(c *gin.Context)
+}
+
+// GraphQLHandler serves the GraphQL endpoint
+type GraphQLHandler interface {
+	Query(c *gin.Context)
+	Subscribe(c *gin.Context)
+}
+
+// legacyDeprecated is when the unversioned /api routes were deprecated in
+// favour of /api/v1
+var legacyDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// Options configures SetupRoutes
+type Options struct {
+	// APIMiddleware runs for every route under /api and for /graphql, such
+	// as rate limiting
+	APIMiddleware []gin.HandlerFunc
+	// LegacySunset is when the unversioned /api routes will be removed,
+	// announced in their Sunset header
+	LegacySunset time.Time
 }
 
-func SetupRoutes(r *gin.Engine, noteHandler *handlers.NoteHandler, actionHandler *handlers.ActionHandler, wsHub WebSocketHub) {
+func SetupRoutes(r *gin.Engine, noteHandler *handlers.NoteHandler, actionHandler *handlers.ActionHandler, exportHandler *handlers.ExportHandler, healthHandler *handlers.HealthHandler, wsHub WebSocketHub, graphQL GraphQLHandler, opts Options) {
+	// Unknown routes get the same problem responses as handler errors
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(handlers.RouteNotFound)
+	r.NoMethod(handlers.MethodNotAllowed)
+
+	// Liveness and readiness probes
+	r.GET("/healthz", healthHandler.Live)
+	r.GET("/readyz", healthHandler.Ready)
+
+	// API description and docs
+	r.GET("/openapi.json", openapi.Handler)
+	r.GET("/docs", openapi.Docs)
+	r.GET("/docs/:file", openapi.DocsAsset)
+
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// WebSocket endpoint
 	r.GET("/ws", wsHub.HandleWebSocket)
 
+	// GraphQL queries and mutations, and subscriptions over a WebSocket
+	gql := r.Group("/graphql", opts.APIMiddleware...)
+	gql.POST("", graphQL.Query)
+	gql.GET("", graphQL.Subscribe)
+
+	// Each API version gets its own group. A later version registers its
+	// routes the same way, with handlers from WithPresenter when its
+	// response shapes differ, so the stores behind them stay shared.
+	registerV1(r.Group("/api/v1", opts.APIMiddleware...), noteHandler, actionHandler, exportHandler, wsHub)
+
+	// The routes from before versioning stay as deprecated aliases of v1
+	legacy := append([]gin.HandlerFunc{handlers.Deprecated("/api", "/api/v1", legacyDeprecated, opts.LegacySunset)}, opts.APIMiddleware...)
+	registerV1(r.Group("/api", legacy...), noteHandler, actionHandler, exportHandler, wsHub)
+}
+
+// registerV1 adds the version 1 routes to api
+func registerV1(api *gin.RouterGroup, noteHandler *handlers.NoteHandler, actionHandler *handlers.ActionHandler, exportHandler *handlers.ExportHandler, wsHub WebSocketHub) {
+	api.GET("/presence", wsHub.HandlePresence)
+
 	// Notes routes
-	notes := r.Group("/api/notes")
+	notes := api.Group("/notes")
 	{
 		notes.POST("", noteHandler.Create)
 		notes.GET("/:id", noteHandler.GetByID)
@@ -24,8 +85,11 @@ func SetupRoutes(r *gin.Engine, noteHandler *handlers.NoteHandler, actionHandler
 		notes.DELETE("/:id", noteHandler.Delete)
 	}
 
+	// A day's notes with their actions
+	api.GET("/days/:date", noteHandler.GetDay)
+
 	// Actions routes
-	actions := r.Group("/api/actions")
+	actions := api.Group("/actions")
 	{
 		actions.POST("", actionHandler.Create)
 		actions.GET("", actionHandler.GetAll)
@@ -35,4 +99,19 @@ func SetupRoutes(r *gin.Engine, noteHandler *handlers.NoteHandler, actionHandler
 		actions.DELETE("/:id", actionHandler.Delete)
 		actions.HEAD("", actionHandler.Health)
 	}
+
+	// Export routes
+	exports := api.Group("/export")
+	{
+		exports.GET("/markdown", exportHandler.Markdown)
+		exports.GET("/json", exportHandler.JSON)
+		exports.GET("/csv", exportHandler.CSV)
+	}
+
+	// Import routes
+	imports := api.Group("/import")
+	{
+		imports.POST("/json", exportHandler.ImportJSON)
+		imports.POST("/csv", exportHandler.ImportCSV)
+	}
 }
@@ -0,0 +1,162 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/openapi"
+)
+
+type stubHub struct{}
+
+func (stubHub) HandleWebSocket(c *gin.Context) {}
+func (stubHub) HandlePresence(c *gin.Context)  {}
+
+type stubGraphQL struct{}
+
+func (stubGraphQL) Query(c *gin.Context)     {}
+func (stubGraphQL) Subscribe(c *gin.Context) {}
+
+// openAPIPath turns a Gin route such as /api/notes/:id into /api/notes/{id}
+func openAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, handlers.NewNoteHandler(nil, nil, nil), handlers.NewActionHandler(nil, nil), handlers.NewExportHandler(nil, nil, nil, nil), handlers.NewHealthHandler(time.Second), stubHub{}, stubGraphQL{}, Options{})
+
+	spec := openapi.Spec()
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		path, method := openAPIPath(route.Path), strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		if spec.Paths[path][method] == nil {
+			t.Errorf("Expected %s %s to be described in the OpenAPI document", route.Method, path)
+		}
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !registered[method+" "+path] {
+				t.Errorf("Expected %s %s from the OpenAPI document to be a registered route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestLegacyAlias(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	SetupRoutes(r, handlers.NewNoteHandler(nil, nil, nil), handlers.NewActionHandler(nil, nil), handlers.NewExportHandler(nil, nil, nil, nil), handlers.NewHealthHandler(time.Second), stubHub{}, stubGraphQL{}, Options{LegacySunset: sunset})
+
+	t.Run("Versioned", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/api/v1/actions", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("Deprecation"); got != "" {
+			t.Errorf("Expected no Deprecation header, got %q", got)
+		}
+	})
+
+	t.Run("Unversioned", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/api/actions", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("Deprecation"); got == "" {
+			t.Error("Expected a Deprecation header")
+		}
+		if got, want := w.Header().Get("Sunset"), "Fri, 30 Apr 2027 00:00:00 GMT"; got != want {
+			t.Errorf("Expected Sunset %q, got %q", want, got)
+		}
+		if got, want := w.Header().Get("Link"), `</api/v1/actions>; rel="successor-version"`; got != want {
+			t.Errorf("Expected Link %q, got %q", want, got)
+		}
+	})
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	spec := openapi.Spec()
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got %s", spec.OpenAPI)
+	}
+
+	create := spec.Components.Schemas["CreateNoteRequest"]
+	if create == nil {
+		t.Fatal("Expected a CreateNoteRequest schema")
+	}
+	if strings.Join(create.Required, ",") != "content,date" {
+		t.Errorf("Expected content and date to be required, got %v", create.Required)
+	}
+	if content := create.Properties["content"]; content.MaxLength == nil || *content.MaxLength != 50000 {
+		t.Errorf("Expected the content length limit from the binding rules, got %+v", content)
+	}
+	if date := create.Properties["date"]; date.Format != "date-time" {
+		t.Errorf("Expected date to be a date-time, got %+v", date)
+	}
+
+	getNote := spec.Paths["/api/v1/notes/{id}"][strings.ToLower(http.MethodGet)]
+	if getNote.Responses["404"].Content[handlers.ProblemContentType].Schema.Ref != "#/components/schemas/Problem" {
+		t.Errorf("Expected errors to be described as problems, got %+v", getNote.Responses["404"])
+	}
+	if _, ok := getNote.Responses["429"]; !ok {
+		t.Error("Expected API routes to document the rate limit response")
+	}
+}
+
+func TestDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, handlers.NewNoteHandler(nil, nil, nil), handlers.NewActionHandler(nil, nil), handlers.NewExportHandler(nil, nil, nil, nil), handlers.NewHealthHandler(time.Second), stubHub{}, stubGraphQL{}, Options{})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	page := get("/docs")
+	if page.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, page.Code)
+	}
+	if strings.Contains(page.Body.String(), "https://") {
+		t.Error("Expected the docs page to load nothing from other sites")
+	}
+
+	for path, contentType := range map[string]string{
+		"/docs/swagger-ui.css":       "text/css",
+		"/docs/swagger-ui-bundle.js": "text/javascript",
+	} {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected %s to be served, got status %d", path, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, contentType) {
+			t.Errorf("Expected %s to be %s, got %s", path, contentType, got)
+		}
+		if !strings.Contains(page.Body.String(), path) {
+			t.Errorf("Expected the docs page to load %s", path)
+		}
+	}
+
+	if w := get("/docs/LICENSE"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected files the page does not load to be missing, got status %d", w.Code)
+	}
+}
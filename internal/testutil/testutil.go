@@ -1,12 +1,16 @@
 package testutil
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	_ "github.com/lib/pq"
+	"github.com/tehsis/logmeup-api/pkg/database"
+	_ "modernc.org/sqlite"
 )
 
 // SetupTestDB creates a test database connection
@@ -39,16 +43,18 @@ func SetupTestDB(t *testing.T) *sql.DB {
 	return db
 }
 
-// CleanupTestDB cleans up the test database
+// CleanupTestDB rolls back every migration and closes the connection
 func CleanupTestDB(t *testing.T, db *sql.DB) {
 	t.Helper()
 
-	// Drop all tables
-	_, err := db.Exec(`
-		DROP TABLE IF EXISTS actions;
-		DROP TABLE IF EXISTS notes;
-	`)
+	migrator, err := database.NewMigrator(db, "postgres")
 	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.To(context.Background(), 0); err != nil {
+		t.Fatalf("Failed to clean up test database: %v", err)
+	}
+	if _, err := db.Exec("DROP TABLE IF EXISTS schema_migrations"); err != nil {
 		t.Fatalf("Failed to clean up test database: %v", err)
 	}
 
@@ -58,33 +64,37 @@ func CleanupTestDB(t *testing.T, db *sql.DB) {
 	}
 }
 
-// SetupTestSchema creates the test database schema
+// SetupTestSchema applies the Postgres migrations
 func SetupTestSchema(t *testing.T, db *sql.DB) {
 	t.Helper()
+	migrate(t, db, "postgres")
+}
+
+// SetupSQLiteTestDB creates a SQLite database in a temporary directory with
+// the SQLite migrations applied. It is removed when the test ends.
+func SetupSQLiteTestDB(t *testing.T) *sql.DB {
+	t.Helper()
 
-	// Create tables
-	_, err := db.Exec(`
-		CREATE TABLE notes (
-			id BIGSERIAL PRIMARY KEY,
-			content TEXT NOT NULL,
-			date DATE NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
-			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
-		);
-
-		CREATE TABLE actions (
-			id BIGSERIAL PRIMARY KEY,
-			note_id BIGINT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
-			description TEXT NOT NULL,
-			completed BOOLEAN NOT NULL DEFAULT FALSE,
-			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
-			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
-		);
-
-		CREATE INDEX idx_notes_date ON notes(date);
-		CREATE INDEX idx_actions_note_id ON actions(note_id);
-	`)
+	path := filepath.Join(t.TempDir(), "logmeup_test.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)")
 	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db, "sqlite")
+	return db
+}
+
+// migrate brings db to the latest schema using the embedded migrations
+func migrate(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+
+	migrator, err := database.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
 		t.Fatalf("Failed to create test schema: %v", err)
 	}
 }
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/tehsis/logmeup-api/internal/tracing"
+
+// startQuery starts a client span for a repository method. The span is
+// named after the statement, such as "INSERT actions", and ended by the
+// returned function with the method's error.
+func startQuery(ctx context.Context, system, table, method, operation string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	attrs = append(attrs,
+		attribute.String("db.system", system),
+		attribute.String("db.collection.name", table),
+		attribute.String("db.operation.name", operation),
+		attribute.String("code.function", method),
+	)
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, func(err error) {
+		// A missing record is an answer, not a failure of the query
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// noteStore traces every call to a NoteStore
+type noteStore struct {
+	next   repository.NoteStore
+	system string
+}
+
+// NoteStore wraps store so each call gets a span; system names the database
+// ("postgres", "sqlite" or "memory")
+func NoteStore(store repository.NoteStore, system string) repository.NoteStore {
+	return &noteStore{next: store, system: system}
+}
+
+func (s *noteStore) Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.Create", "INSERT")
+	created, err := s.next.Create(ctx, note)
+	end(err)
+	return created, err
+}
+
+func (s *noteStore) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.GetByID", "SELECT", attribute.Int64("logmeup.note_id", id))
+	note, err := s.next.GetByID(ctx, id)
+	end(err)
+	return note, err
+}
+
+func (s *noteStore) GetByDate(ctx context.Context, date time.Time) ([]*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.GetByDate", "SELECT", attribute.String("logmeup.date", date.Format("2006-01-02")))
+	notes, err := s.next.GetByDate(ctx, date)
+	end(err)
+	return notes, err
+}
+
+func (s *noteStore) GetByDateRange(ctx context.Context, from, to time.Time) ([]*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.GetByDateRange", "SELECT",
+		attribute.String("logmeup.from", from.Format("2006-01-02")),
+		attribute.String("logmeup.to", to.Format("2006-01-02")),
+	)
+	notes, err := s.next.GetByDateRange(ctx, from, to)
+	end(err)
+	return notes, err
+}
+
+func (s *noteStore) GetByIDs(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.GetByIDs", "SELECT", attribute.Int("logmeup.note_count", len(ids)))
+	notes, err := s.next.GetByIDs(ctx, ids)
+	end(err)
+	return notes, err
+}
+
+func (s *noteStore) GetByDates(ctx context.Context, dates []time.Time) ([]*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.GetByDates", "SELECT", attribute.Int("logmeup.date_count", len(dates)))
+	notes, err := s.next.GetByDates(ctx, dates)
+	end(err)
+	return notes, err
+}
+
+func (s *noteStore) Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error) {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.Update", "UPDATE", attribute.Int64("logmeup.note_id", id))
+	updated, err := s.next.Update(ctx, id, note)
+	end(err)
+	return updated, err
+}
+
+func (s *noteStore) Delete(ctx context.Context, id int64) error {
+	ctx, end := startQuery(ctx, s.system, "notes", "NoteStore.Delete", "DELETE", attribute.Int64("logmeup.note_id", id))
+	err := s.next.Delete(ctx, id)
+	end(err)
+	return err
+}
+
+// actionStore traces every call to an ActionStore
+type actionStore struct {
+	next   repository.ActionStore
+	system string
+}
+
+// ActionStore wraps store so each call gets a span; system names the
+// database ("postgres", "sqlite" or "memory")
+func ActionStore(store repository.ActionStore, system string) repository.ActionStore {
+	return &actionStore{next: store, system: system}
+}
+
+func (s *actionStore) Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.Create", "INSERT", attribute.Int64("logmeup.note_id", action.NoteID))
+	created, err := s.next.Create(ctx, action)
+	end(err)
+	return created, err
+}
+
+func (s *actionStore) CreateMany(ctx context.Context, actions []*models.Action) ([]*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.CreateMany", "INSERT", attribute.Int("logmeup.action_count", len(actions)))
+	created, err := s.next.CreateMany(ctx, actions)
+	end(err)
+	return created, err
+}
+
+func (s *actionStore) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.GetByID", "SELECT", attribute.Int64("logmeup.action_id", id))
+	action, err := s.next.GetByID(ctx, id)
+	end(err)
+	return action, err
+}
+
+func (s *actionStore) GetAll(ctx context.Context) ([]*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.GetAll", "SELECT")
+	actions, err := s.next.GetAll(ctx)
+	end(err)
+	return actions, err
+}
+
+func (s *actionStore) GetByNoteID(ctx context.Context, noteID int64) ([]*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.GetByNoteID", "SELECT", attribute.Int64("logmeup.note_id", noteID))
+	actions, err := s.next.GetByNoteID(ctx, noteID)
+	end(err)
+	return actions, err
+}
+
+func (s *actionStore) Find(ctx context.Context, filter models.ActionFilter) ([]*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.Find", "SELECT", attribute.Int("logmeup.note_count", len(filter.NoteIDs)))
+	actions, err := s.next.Find(ctx, filter)
+	end(err)
+	return actions, err
+}
+
+func (s *actionStore) CountByNote(ctx context.Context, filter models.ActionFilter) (map[int64]models.ActionCount, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.CountByNote", "SELECT", attribute.Int("logmeup.note_count", len(filter.NoteIDs)))
+	counts, err := s.next.CountByNote(ctx, filter)
+	end(err)
+	return counts, err
+}
+
+func (s *actionStore) Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.Update", "UPDATE", attribute.Int64("logmeup.action_id", id))
+	updated, changed, err := s.next.Update(ctx, id, action)
+	end(err)
+	return updated, changed, err
+}
+
+func (s *actionStore) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.Toggle", "UPDATE", attribute.Int64("logmeup.action_id", id))
+	toggled, err := s.next.Toggle(ctx, id)
+	end(err)
+	return toggled, err
+}
+
+func (s *actionStore) Delete(ctx context.Context, id int64) error {
+	ctx, end := startQuery(ctx, s.system, "actions", "ActionStore.Delete", "DELETE", attribute.Int64("logmeup.action_id", id))
+	err := s.next.Delete(ctx, id)
+	end(err)
+	return err
+}
@@ -0,0 +1,120 @@
+// Package tracing configures OpenTelemetry and traces the layers of a
+// request: the Gin or gRPC server span, repository calls and hub broadcasts.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// ServiceName identifies the API in traces
+const ServiceName = "logmeup-api"
+
+// Options selects where spans are exported
+type Options struct {
+	// Exporter is "none", "stdout" or "otlp"
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector address (host:port). When empty
+	// the standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string
+	// Insecure sends OTLP spans over plain HTTP
+	Insecure bool
+	// SampleRatio is the fraction of new traces recorded; requests whose
+	// caller sampled the trace are always recorded
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace-context and
+// baggage propagators. The returned function flushes and stops exporting.
+// With the "none" exporter spans are still created, so trace IDs propagate,
+// but nothing is exported.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case "none", "":
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q: expected none, stdout or otlp", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s exporter: %v", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// HTTP returns the middleware tracing requests. It starts a server span
+// for each request, continuing the caller's trace from its traceparent
+// header, names it after the method and route and records the handler
+// function, such as handlers.(*ActionHandler).Create.
+func HTTP() []gin.HandlerFunc {
+	return []gin.HandlerFunc{otelgin.Middleware(ServiceName), describeSpan}
+}
+
+// GRPC returns the server option tracing gRPC calls. Each call gets a
+// server span named after its method, continuing the caller's trace from
+// the traceparent metadata.
+func GRPC() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+func describeSpan(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if route := c.FullPath(); route != "" {
+		span.SetName(c.Request.Method + " " + route)
+	}
+	handler := c.HandlerName()
+	if i := strings.LastIndex(handler, "/"); i >= 0 {
+		handler = handler[i+1:]
+	}
+	span.SetAttributes(attribute.String("code.function", strings.TrimSuffix(handler, "-fm")))
+	c.Next()
+}
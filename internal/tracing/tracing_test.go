@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+	"github.com/tehsis/logmeup-api/internal/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	t.Run("Setup", func(t *testing.T) {
+		if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+			t.Error("Expected error for an unknown exporter")
+		}
+	})
+
+	t.Run("ActionCreate", func(t *testing.T) {
+		exporter.Reset()
+		gin.SetMode(gin.TestMode)
+
+		store := repository.NewMemoryStore()
+		notes := repository.NewMemoryNoteRepository(store)
+		note, err := notes.Create(context.Background(), &models.CreateNoteRequest{Content: "Note", Date: time.Now()})
+		if err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+		actions := ActionStore(repository.NewMemoryActionRepository(store), "memory")
+		actionHandler := handlers.NewActionHandler(actions, websocket.NewHub(nil, nil))
+
+		r := gin.New()
+		r.Use(HTTP()...)
+		r.POST("/api/actions", actionHandler.Create)
+
+		body := `{"note_id":` + strconv.FormatInt(note.ID, 10) + `,"description":"Traced"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/actions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+
+		spans := exporter.GetSpans()
+		byName := make(map[string]tracetest.SpanStub)
+		for _, span := range spans {
+			byName[span.Name] = span
+		}
+
+		server, ok := byName["POST /api/actions"]
+		if !ok {
+			t.Fatalf("Expected a server span, got %v", spanNames(spans))
+		}
+		if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected the caller's trace to continue, got trace %s", server.SpanContext.TraceID())
+		}
+
+		for _, name := range []string{"INSERT actions", "Hub.BroadcastActionCreated"} {
+			span, ok := byName[name]
+			if !ok {
+				t.Errorf("Expected a %q span, got %v", name, spanNames(spans))
+				continue
+			}
+			if span.Parent.SpanID() != server.SpanContext.SpanID() {
+				t.Errorf("Expected %q to be a child of the server span", name)
+			}
+		}
+	})
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
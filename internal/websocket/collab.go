@@ -0,0 +1,507 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+// noteSnapshotDelay is how long a note has to be idle before edits are saved
+const noteSnapshotDelay = 2 * time.Second
+
+// maxSaveAttempts bounds the tries at saving a session's edits before they
+// wait for the next edit, and maxSaveRetryDelay the wait between two tries
+const (
+	maxSaveAttempts   = 5
+	maxSaveRetryDelay = time.Minute
+)
+
+// Request types for collaborative note editing
+const (
+	JoinNote   RequestType = "join_note"
+	LeaveNote  RequestType = "leave_note"
+	EditNote   RequestType = "edit_note"
+	MoveCursor RequestType = "move_cursor"
+)
+
+// Message types for collaborative note editing
+const (
+	NoteEdited        MessageType = "note_edited"
+	NoteCursorMoved   MessageType = "note_cursor"
+	ParticipantJoined MessageType = "note_participant_joined"
+	ParticipantLeft   MessageType = "note_participant_left"
+)
+
+// Cursor is a participant's caret and selection within a note
+type Cursor struct {
+	Position     int `json:"position"`
+	SelectionEnd int `json:"selection_end"`
+}
+
+// Participant is a client currently editing a note
+type Participant struct {
+	ClientID string  `json:"client_id"`
+	User     string  `json:"user"`
+	Cursor   *Cursor `json:"cursor,omitempty"`
+}
+
+// SessionSnapshot is the authoritative state sent to a client joining a note
+type SessionSnapshot struct {
+	NoteID       int64         `json:"note_id"`
+	ClientID     string        `json:"client_id"`
+	Revision     int           `json:"revision"`
+	Content      string        `json:"content"`
+	Participants []Participant `json:"participants"`
+}
+
+// SessionMessage is sent to the other participants of a note
+type SessionMessage struct {
+	Type      MessageType    `json:"type"`
+	NoteID    int64          `json:"note_id"`
+	ClientID  string         `json:"client_id"`
+	User      string         `json:"user"`
+	Revision  int            `json:"revision,omitempty"`
+	Operation *TextOperation `json:"operation,omitempty"`
+	Cursor    *Cursor        `json:"cursor,omitempty"`
+}
+
+// Payloads for collaborative editing requests
+type noteSessionPayload struct {
+	NoteID int64 `json:"note_id" binding:"required"`
+}
+
+type editNotePayload struct {
+	NoteID    int64          `json:"note_id" binding:"required"`
+	Revision  int            `json:"revision"`
+	Operation *TextOperation `json:"operation" binding:"required"`
+}
+
+type moveCursorPayload struct {
+	NoteID   int64 `json:"note_id" binding:"required"`
+	Revision int   `json:"revision"`
+	Cursor
+}
+
+// noteSession holds the authoritative content of a note being edited.
+// Operations are numbered by revision; a client's operation made against an
+// older revision is transformed against everything applied since.
+type noteSession struct {
+	mu           sync.Mutex
+	noteID       int64
+	content      string
+	history      []*TextOperation
+	participants map[*Client]*Cursor
+	dirty        bool
+	closed       bool
+	// failures counts failed saves since the last successful one
+	failures int
+	timer    *time.Timer
+}
+
+func (s *noteSession) revision() int {
+	return len(s.history)
+}
+
+// rebase transforms an operation made at revision onto the current content
+func (s *noteSession) rebase(op *TextOperation, revision int) (*TextOperation, error) {
+	if revision < 0 || revision > s.revision() {
+		return nil, invalidField("revision", "max", "must be a revision of this note")
+	}
+	for _, applied := range s.history[revision:] {
+		var err error
+		if op, _, err = TransformOperations(op, applied); err != nil {
+			return nil, invalidField("operation", "base", err.Error())
+		}
+	}
+	return op, nil
+}
+
+// rebaseCursor moves a cursor made at revision onto the current content
+func (s *noteSession) rebaseCursor(cursor Cursor, revision int) (*Cursor, error) {
+	if revision < 0 || revision > s.revision() {
+		return nil, invalidField("revision", "max", "must be a revision of this note")
+	}
+	for _, applied := range s.history[revision:] {
+		cursor.Position = applied.TransformIndex(cursor.Position)
+		cursor.SelectionEnd = applied.TransformIndex(cursor.SelectionEnd)
+	}
+	length := utf8.RuneCountInString(s.content)
+	cursor.Position = max(0, min(cursor.Position, length))
+	cursor.SelectionEnd = max(0, min(cursor.SelectionEnd, length))
+	return &cursor, nil
+}
+
+// sendToOthers queues a message for every participant but client. It must be
+// called with s.mu held so participants see events in revision order.
+func (s *noteSession) sendToOthers(h *Hub, client *Client, message interface{}) {
+	for participant := range s.participants {
+		if participant != client {
+			h.sendTo(participant, message)
+		}
+	}
+}
+
+// session returns the open session for a note, if any
+func (h *Hub) session(noteID int64) (*noteSession, error) {
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+
+	s, ok := h.sessions[noteID]
+	if !ok {
+		return nil, &requestError{CodeNotFound, "not editing this note"}
+	}
+	return s, nil
+}
+
+// openSession returns the session of a note with s.mu held, opening one if
+// the note has none. The note is read without holding h.sessionsMu, so a
+// slow read does not hold up other notes; if a session closed meanwhile, its
+// last save may be newer than the read, and the note is read again.
+func (h *Hub) openSession(ctx context.Context, noteID int64) (*noteSession, error) {
+	for {
+		h.sessionsMu.Lock()
+		if s, ok := h.sessions[noteID]; ok {
+			s.mu.Lock()
+			h.sessionsMu.Unlock()
+			return s, nil
+		}
+		closed := h.sessionsClosed
+		h.sessionsMu.Unlock()
+
+		note, err := h.notes.GetByID(ctx, noteID)
+		if err != nil {
+			return nil, repositoryError(err)
+		}
+
+		h.sessionsMu.Lock()
+		s, ok := h.sessions[noteID]
+		if !ok && h.sessionsClosed != closed {
+			h.sessionsMu.Unlock()
+			continue
+		}
+		if !ok {
+			s = &noteSession{
+				noteID:       note.ID,
+				content:      note.Content,
+				participants: make(map[*Client]*Cursor),
+			}
+			h.sessions[note.ID] = s
+		}
+		s.mu.Lock()
+		h.sessionsMu.Unlock()
+		return s, nil
+	}
+}
+
+func (h *Hub) joinNote(ctx context.Context, client *Client, req *Request) error {
+	var payload noteSessionPayload
+	if err := decodePayload(req.Data, &payload); err != nil {
+		return err
+	}
+
+	s, err := h.openSession(ctx, payload.NoteID)
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	s.participants[client] = nil
+	snapshot := SessionSnapshot{
+		NoteID:       s.noteID,
+		ClientID:     client.id,
+		Revision:     s.revision(),
+		Content:      s.content,
+		Participants: []Participant{},
+	}
+	for participant, cursor := range s.participants {
+		if participant != client {
+			snapshot.Participants = append(snapshot.Participants, Participant{ClientID: participant.id, User: participant.user, Cursor: cursor})
+		}
+	}
+
+	h.sendTo(client, Response{Type: Ack, ID: req.ID, Data: snapshot})
+	s.sendToOthers(h, client, SessionMessage{Type: ParticipantJoined, NoteID: s.noteID, ClientID: client.id, User: client.user})
+	return nil
+}
+
+func (h *Hub) leaveNote(ctx context.Context, client *Client, req *Request) error {
+	var payload noteSessionPayload
+	if err := decodePayload(req.Data, &payload); err != nil {
+		return err
+	}
+
+	s, err := h.session(payload.NoteID)
+	if err != nil {
+		return err
+	}
+	h.sendTo(client, Response{Type: Ack, ID: req.ID, Data: payload})
+	h.leaveSession(s, client)
+	return nil
+}
+
+func (h *Hub) editNote(ctx context.Context, client *Client, req *Request) error {
+	var payload editNotePayload
+	if err := decodePayload(req.Data, &payload); err != nil {
+		return err
+	}
+
+	s, err := h.session(payload.NoteID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.participants[client]; !ok {
+		return &requestError{CodeNotFound, "not editing this note"}
+	}
+
+	op, err := s.rebase(payload.Operation, payload.Revision)
+	if err != nil {
+		return err
+	}
+	content, err := op.Apply(s.content)
+	if err != nil {
+		return invalidField("operation", "base", err.Error())
+	}
+	if utf8.RuneCountInString(content) > models.MaxNoteContentLength {
+		return invalidField("operation", "max", fmt.Sprintf("must leave the note at most %d characters long", models.MaxNoteContentLength))
+	}
+
+	s.content = content
+	s.history = append(s.history, op)
+	for participant, cursor := range s.participants {
+		if cursor != nil {
+			s.participants[participant] = &Cursor{
+				Position:     op.TransformIndex(cursor.Position),
+				SelectionEnd: op.TransformIndex(cursor.SelectionEnd),
+			}
+		}
+	}
+	if !op.IsNoop() {
+		s.dirty = true
+		h.schedulePersist(s, noteSnapshotDelay)
+	}
+
+	h.sendTo(client, Response{Type: Ack, ID: req.ID, Data: map[string]int{"revision": s.revision()}})
+	s.sendToOthers(h, client, SessionMessage{
+		Type:      NoteEdited,
+		NoteID:    s.noteID,
+		ClientID:  client.id,
+		User:      client.user,
+		Revision:  s.revision(),
+		Operation: op,
+	})
+	return nil
+}
+
+func (h *Hub) moveCursor(ctx context.Context, client *Client, req *Request) error {
+	var payload moveCursorPayload
+	if err := decodePayload(req.Data, &payload); err != nil {
+		return err
+	}
+
+	s, err := h.session(payload.NoteID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.participants[client]; !ok {
+		return &requestError{CodeNotFound, "not editing this note"}
+	}
+
+	cursor, err := s.rebaseCursor(payload.Cursor, payload.Revision)
+	if err != nil {
+		return err
+	}
+	s.participants[client] = cursor
+
+	h.sendTo(client, Response{Type: Ack, ID: req.ID, Data: cursor})
+	s.sendToOthers(h, client, SessionMessage{
+		Type:     NoteCursorMoved,
+		NoteID:   s.noteID,
+		ClientID: client.id,
+		User:     client.user,
+		Revision: s.revision(),
+		Cursor:   cursor,
+	})
+	return nil
+}
+
+// leaveSession removes a client from a note. The last participant to leave
+// closes the session, unless edits are still unsaved: then the session stays
+// open, so anyone reopening the note gets the latest content, until
+// persistSession has saved them.
+func (h *Hub) leaveSession(s *noteSession, client *Client) {
+	h.sessionsMu.Lock()
+	s.mu.Lock()
+	if _, ok := s.participants[client]; !ok {
+		s.mu.Unlock()
+		h.sessionsMu.Unlock()
+		return
+	}
+
+	delete(s.participants, client)
+	s.sendToOthers(h, client, SessionMessage{Type: ParticipantLeft, NoteID: s.noteID, ClientID: client.id, User: client.user})
+
+	last := len(s.participants) == 0
+	if last && !s.dirty {
+		h.closeSession(s)
+	}
+	s.mu.Unlock()
+	h.sessionsMu.Unlock()
+
+	if last {
+		h.persistSession(s)
+	}
+}
+
+// closeSession forgets a session. It must be called with h.sessionsMu and
+// s.mu held.
+func (h *Hub) closeSession(s *noteSession) {
+	s.closed = true
+	h.sessionsClosed++
+	if h.sessions[s.noteID] == s {
+		delete(h.sessions, s.noteID)
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// leaveAllSessions removes a disconnecting client from every note it edits
+func (h *Hub) leaveAllSessions(client *Client) {
+	h.sessionsMu.Lock()
+	var joined []*noteSession
+	for _, s := range h.sessions {
+		s.mu.Lock()
+		if _, ok := s.participants[client]; ok {
+			joined = append(joined, s)
+		}
+		s.mu.Unlock()
+	}
+	h.sessionsMu.Unlock()
+
+	for _, s := range joined {
+		h.leaveSession(s, client)
+	}
+}
+
+// persistSession saves the session content through the note repository and
+// tells every client the note changed, closing the session once it is saved
+// and nobody is editing. A failed save is retried with a growing delay, up
+// to maxSaveAttempts times; a note deleted meanwhile ends the session.
+func (h *Hub) persistSession(s *noteSession) {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	s.dirty = false
+	content := s.content
+	s.mu.Unlock()
+
+	note, err := h.notes.Update(context.Background(), s.noteID, &models.UpdateNoteRequest{Content: content})
+	if errors.Is(err, repository.ErrNotFound) {
+		h.endDeletedSession(s)
+		return
+	}
+
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.dirty = true
+		s.failures++
+		switch {
+		case s.closed || h.isStopping():
+			// Run makes the last attempt itself once the hub is stopping
+			slog.Error("saving note from editing session failed", "note_id", s.noteID, "error", err)
+		case s.failures < maxSaveAttempts:
+			delay := saveRetryDelay(s.failures)
+			slog.Error("saving note from editing session failed", "note_id", s.noteID, "error", err, "retry_in", delay)
+			h.schedulePersist(s, delay)
+		default:
+			// The next edit, or the last participant leaving, tries again;
+			// with nobody editing, the edits are given up
+			slog.Error("saving note from editing session failed, giving up", "note_id", s.noteID, "error", err, "attempts", s.failures)
+			if len(s.participants) == 0 {
+				h.closeSession(s)
+			}
+		}
+		return
+	}
+
+	s.failures = 0
+	h.broadcastMessage(NoteMessage{Type: NoteUpdated, Note: note})
+	if len(s.participants) == 0 && !s.dirty && !s.closed {
+		h.closeSession(s)
+	}
+}
+
+// saveRetryDelay is how long to wait before retrying a session's save after
+// failures failed attempts
+func saveRetryDelay(failures int) time.Duration {
+	return min(noteSnapshotDelay<<failures, maxSaveRetryDelay)
+}
+
+// endDeletedSession closes the session of a note deleted while it was being
+// edited, telling its participants the note is gone
+func (h *Hub) endDeletedSession(s *noteSession) {
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slog.Warn("note deleted while being edited; dropping its session", "note_id", s.noteID)
+	for participant := range s.participants {
+		h.sendTo(participant, NoteMessage{Type: NoteDeleted, ID: s.noteID})
+	}
+	s.participants = make(map[*Client]*Cursor)
+	s.dirty = false
+	if !s.closed {
+		h.closeSession(s)
+	}
+}
+
+// schedulePersist saves the session after delay, replacing a save already
+// scheduled. It must be called with s.mu held.
+func (h *Hub) schedulePersist(s *noteSession, delay time.Duration) {
+	if s.timer != nil {
+		s.timer.Reset(delay)
+		return
+	}
+	s.timer = time.AfterFunc(delay, func() {
+		// Once the hub is stopping, Run saves the session instead
+		if !h.startWork() {
+			return
+		}
+		defer h.work.Done()
+		h.persistSession(s)
+	})
+}
+
+// flushSessions saves every note with edits that have not been persisted yet
+func (h *Hub) flushSessions() {
+	h.sessionsMu.Lock()
+	sessions := make([]*noteSession, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.sessionsMu.Unlock()
+
+	for _, s := range sessions {
+		h.persistSession(s)
+	}
+}
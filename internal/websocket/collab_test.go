@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+type fakeNoteRepo struct {
+	notes   map[int64]*models.Note
+	updates int
+	// fail is returned by Update when set
+	fail error
+}
+
+func (r *fakeNoteRepo) Create(ctx context.Context, req *models.CreateNoteRequest) (*models.Note, error) {
+	note := &models.Note{ID: int64(len(r.notes) + 1), Content: req.Content, Date: req.Date}
+	r.notes[note.ID] = note
+	return note, nil
+}
+
+func (r *fakeNoteRepo) GetByID(ctx context.Context, id int64) (*models.Note, error) {
+	note, ok := r.notes[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *note
+	return &copied, nil
+}
+
+func (r *fakeNoteRepo) Update(ctx context.Context, id int64, req *models.UpdateNoteRequest) (*models.Note, error) {
+	if r.fail != nil {
+		return nil, r.fail
+	}
+	note, ok := r.notes[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	r.updates++
+	note.Content = req.Content
+	copied := *note
+	return &copied, nil
+}
+
+func (r *fakeNoteRepo) Delete(ctx context.Context, id int64) error {
+	delete(r.notes, id)
+	return nil
+}
+
+// drain returns every queued message addressed to client, in order
+func drain(h *Hub, client *Client) []map[string]interface{} {
+	var messages []map[string]interface{}
+	for len(h.broadcast) > 0 {
+		e := <-h.broadcast
+		if e.to != nil && e.to != client {
+			continue
+		}
+		var message map[string]interface{}
+		json.Unmarshal(e.data, &message)
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestCollaborativeEditing(t *testing.T) {
+	repo := &fakeNoteRepo{notes: map[int64]*models.Note{1: {ID: 1, Content: "abc"}}}
+	h := NewHub(repo, nil)
+	alice, bob := &Client{hub: h, id: "alice"}, &Client{hub: h, id: "bob"}
+
+	h.handleRequest(alice, []byte(`{"type":"join_note","id":"1","data":{"note_id":1}}`))
+	h.handleRequest(bob, []byte(`{"type":"join_note","id":"2","data":{"note_id":1}}`))
+	drain(h, nil)
+
+	// Both edit revision 0 concurrently
+	h.handleRequest(alice, []byte(`{"type":"edit_note","id":"3","data":{"note_id":1,"revision":0,"operation":[1,"X",2]}}`))
+	h.handleRequest(bob, []byte(`{"type":"edit_note","id":"4","data":{"note_id":1,"revision":0,"operation":[2,"Y",1]}}`))
+
+	messages := drain(h, alice)
+	if len(messages) != 2 {
+		t.Fatalf("Expected an ack and bob's edit for alice, got %v", messages)
+	}
+	edit := messages[1]
+	if edit["type"] != string(NoteEdited) || edit["client_id"] != "bob" || edit["revision"] != float64(2) {
+		t.Errorf("Unexpected edit event: %v", edit)
+	}
+
+	s, err := h.session(1)
+	if err != nil {
+		t.Fatalf("Expected an open session: %v", err)
+	}
+	if s.content != "aXbYc" {
+		t.Errorf("Expected merged content %q, got %q", "aXbYc", s.content)
+	}
+
+	// An edit against a revision from the future is rejected
+	h.handleRequest(bob, []byte(`{"type":"edit_note","id":"5","data":{"note_id":1,"revision":9,"operation":[5]}}`))
+	if messages := drain(h, bob); len(messages) != 1 || messages[0]["type"] != string(Error) ||
+		messages[0]["error"].(map[string]interface{})["code"] != CodeValidation {
+		t.Errorf("Expected a %s error frame, got %v", CodeValidation, messages)
+	}
+
+	// An edit making the note too long is rejected
+	long := fmt.Sprintf(`{"type":"edit_note","id":"5","data":{"note_id":1,"revision":2,"operation":[5,%q]}}`, strings.Repeat("x", models.MaxNoteContentLength))
+	h.handleRequest(bob, []byte(long))
+	if messages := drain(h, bob); len(messages) != 1 || messages[0]["type"] != string(Error) {
+		t.Errorf("Expected an error frame, got %v", messages)
+	}
+	if s.content != "aXbYc" {
+		t.Errorf("Expected the content to be unchanged, got %d characters", len(s.content))
+	}
+
+	// The last participant leaving saves the note
+	h.handleRequest(alice, []byte(`{"type":"leave_note","id":"6","data":{"note_id":1}}`))
+	if repo.updates != 0 {
+		t.Error("Expected the note not to be saved while bob is still editing")
+	}
+	h.leaveAllSessions(bob)
+	if repo.notes[1].Content != "aXbYc" || repo.updates != 1 {
+		t.Errorf("Expected the merged content to be saved once, got %q after %d updates", repo.notes[1].Content, repo.updates)
+	}
+	if _, err := h.session(1); err == nil {
+		t.Error("Expected the session to be closed")
+	}
+}
+
+func TestFailedSessionSave(t *testing.T) {
+	repo := &fakeNoteRepo{notes: map[int64]*models.Note{1: {ID: 1, Content: "abc"}}, fail: errors.New("database is down")}
+	h := NewHub(repo, nil)
+	alice, bob := &Client{hub: h, id: "alice"}, &Client{hub: h, id: "bob"}
+
+	h.handleRequest(alice, []byte(`{"type":"join_note","id":"1","data":{"note_id":1}}`))
+	h.handleRequest(alice, []byte(`{"type":"edit_note","id":"2","data":{"note_id":1,"revision":0,"operation":[3,"d"]}}`))
+	h.leaveAllSessions(alice)
+
+	// The unsaved edit stays in the session for whoever opens the note next
+	s, err := h.session(1)
+	if err != nil {
+		t.Fatal("Expected the session to stay open after a failed save")
+	}
+	h.handleRequest(bob, []byte(`{"type":"join_note","id":"3","data":{"note_id":1}}`))
+	messages := drain(h, bob)
+	if len(messages) == 0 || messages[0]["data"].(map[string]interface{})["content"] != "abcd" {
+		t.Errorf("Expected bob to get the unsaved content, got %v", messages)
+	}
+	h.leaveAllSessions(bob)
+
+	repo.fail = nil
+	h.persistSession(s)
+	if repo.notes[1].Content != "abcd" {
+		t.Errorf("Expected the retried save to store the edit, got %q", repo.notes[1].Content)
+	}
+	if _, err := h.session(1); err == nil {
+		t.Error("Expected the session to close once saved")
+	}
+}
+
+func TestSessionSaveRetriesAreCapped(t *testing.T) {
+	repo := &fakeNoteRepo{notes: map[int64]*models.Note{1: {ID: 1, Content: "abc"}}, fail: errors.New("database is down")}
+	h := NewHub(repo, nil)
+	alice := &Client{hub: h, id: "alice"}
+
+	h.handleRequest(alice, []byte(`{"type":"join_note","id":"1","data":{"note_id":1}}`))
+	h.handleRequest(alice, []byte(`{"type":"edit_note","id":"2","data":{"note_id":1,"revision":0,"operation":[3,"d"]}}`))
+	h.leaveAllSessions(alice)
+
+	s, err := h.session(1)
+	if err != nil {
+		t.Fatal("Expected the session to stay open after a failed save")
+	}
+	for attempt := 2; attempt < maxSaveAttempts; attempt++ {
+		h.persistSession(s)
+		if _, err := h.session(1); err != nil {
+			t.Fatalf("Expected the session to stay open after %d failed saves", attempt)
+		}
+	}
+	h.persistSession(s)
+	if _, err := h.session(1); err == nil {
+		t.Errorf("Expected the session to close after %d failed saves", maxSaveAttempts)
+	}
+}
+
+func TestSaveRetryDelay(t *testing.T) {
+	if got := saveRetryDelay(1); got != 2*noteSnapshotDelay {
+		t.Errorf("Expected the first retry after %v, got %v", 2*noteSnapshotDelay, got)
+	}
+	if got := saveRetryDelay(20); got != maxSaveRetryDelay {
+		t.Errorf("Expected retries to wait at most %v, got %v", maxSaveRetryDelay, got)
+	}
+}
+
+func TestDeletedNoteEndsSession(t *testing.T) {
+	repo := &fakeNoteRepo{notes: map[int64]*models.Note{1: {ID: 1, Content: "abc"}}}
+	h := NewHub(repo, nil)
+	alice := &Client{hub: h, id: "alice"}
+
+	h.handleRequest(alice, []byte(`{"type":"join_note","id":"1","data":{"note_id":1}}`))
+	h.handleRequest(alice, []byte(`{"type":"edit_note","id":"2","data":{"note_id":1,"revision":0,"operation":[3,"d"]}}`))
+	s, err := h.session(1)
+	if err != nil {
+		t.Fatal("Expected the session to be open")
+	}
+	drain(h, alice)
+
+	repo.Delete(context.Background(), 1)
+	h.persistSession(s)
+
+	if _, err := h.session(1); err == nil {
+		t.Error("Expected the session of a deleted note to close")
+	}
+	messages := drain(h, alice)
+	if len(messages) != 1 || messages[0]["type"] != string(NoteDeleted) {
+		t.Errorf("Expected alice to be told the note was deleted, got %v", messages)
+	}
+	if repo.updates != 0 {
+		t.Errorf("Expected nothing to be saved, got %d updates", repo.updates)
+	}
+}
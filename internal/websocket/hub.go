@@ -1,13 +1,26 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/metrics"
 	"github.com/tehsis/logmeup-api/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var upgrader = websocket.Upgrader{
@@ -25,6 +38,11 @@ const (
 	ActionCreated MessageType = "action_created"
 	ActionUpdated MessageType = "action_updated"
 	ActionDeleted MessageType = "action_deleted"
+	NoteCreated   MessageType = "note_created"
+	NoteUpdated   MessageType = "note_updated"
+	NoteDeleted   MessageType = "note_deleted"
+	Ack           MessageType = "ack"
+	Error         MessageType = "error"
 )
 
 // WebSocket message structure
@@ -40,11 +58,50 @@ type ActionMessage struct {
 	ID     int64          `json:"id,omitempty"` // For delete events
 }
 
+// NoteMessage for note-related events
+type NoteMessage struct {
+	Type MessageType  `json:"type"`
+	Note *models.Note `json:"note,omitempty"`
+	ID   int64        `json:"id,omitempty"` // For delete events
+}
+
+// envelope is a queued outbound message and its delivery constraints
+type envelope struct {
+	data []byte
+	// to restricts delivery to a single client when set
+	to *Client
+	// skip excludes a client from a broadcast, typically the one that caused it
+	skip *Client
+	// event is the note or action change being broadcast, for subscribers
+	event *Event
+}
+
+// Event is a note or action change, as delivered to Subscribe. ID is the
+// record's ID; deletions carry nothing else.
+type Event struct {
+	Type   MessageType
+	Note   *models.Note
+	Action *models.Action
+	ID     int64
+}
+
+// clientSeq numbers connections so other clients can tell them apart
+var clientSeq atomic.Uint64
+
 // Client represents a WebSocket connection
 type Client struct {
 	hub  *Hub
+	id   string
+	user string
 	conn *websocket.Conn
 	send chan []byte
+
+	// ctx is cancelled when the connection closes, abandoning its queries
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// closeMessage is written when send is closed; set before closing send
+	closeMessage []byte
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -52,56 +109,296 @@ type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
-	// Inbound messages from the clients
-	broadcast chan []byte
+	// Outbound messages waiting to be delivered to clients
+	broadcast chan envelope
 
 	// Register requests from the clients
 	register chan *Client
 
 	// Unregister requests from clients
 	unregister chan *Client
+
+	// Closed once Run has stopped; queuing after that is a no-op
+	done chan struct{}
+
+	// Ping requests from readiness checks, answered by Run
+	ping chan chan struct{}
+
+	// In-process listeners for note and action events
+	subscribers map[chan Event]bool
+	subscribe   chan chan Event
+	unsubscribe chan chan Event
+
+	// Repositories used to execute client requests
+	notes   NoteRepository
+	actions ActionRepository
+
+	// Notes being edited collaboratively, by note ID
+	sessionsMu sync.Mutex
+	sessions   map[int64]*noteSession
+	// sessionsClosed counts closed sessions, so a join that loaded a note
+	// without sessionsMu can tell whether a save may have overtaken it
+	sessionsClosed uint64
+
+	// Goroutines that may still save notes: client read pumps and session
+	// save timers. Run waits for them before it returns, and none start once
+	// the hub is stopping, so the repositories are idle after Run.
+	workMu   sync.Mutex
+	stopping bool
+	work     sync.WaitGroup
+
+	// Who is connected and what they are viewing
+	presence presence
 }
 
-// NewHub creates a new WebSocket hub
-func NewHub() *Hub {
+// NewHub creates a new WebSocket hub. The repositories are used to execute
+// mutations requested by clients over the socket.
+func NewHub(notes NoteRepository, actions ActionRepository) *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+		notes:       notes,
+		actions:     actions,
+		broadcast:   make(chan envelope, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		done:        make(chan struct{}),
+		ping:        make(chan chan struct{}),
+		subscribers: make(map[chan Event]bool),
+		subscribe:   make(chan chan Event),
+		unsubscribe: make(chan chan Event),
+		clients:     make(map[*Client]bool),
+		sessions:    make(map[int64]*noteSession),
+		presence:    presence{viewing: make(map[*Client]int64)},
 	}
 }
 
-// Run starts the hub and handles client registration/unregistration
-func (h *Hub) Run() {
+// Run starts the hub and handles client registration/unregistration until
+// ctx is cancelled. It then delivers broadcasts that were already queued,
+// closes every connection with a "server restarting" close frame, waits for
+// the clients' last requests and pending saves, and saves notes that are
+// being edited. The repositories can be closed once it returns.
+func (h *Hub) Run(ctx context.Context) {
 	for {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
-			log.Printf("Client connected. Total clients: %d", len(h.clients))
+			metrics.ClientConnected()
+			client.logger().Info("websocket client connected", "clients", len(h.clients))
 
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
 				close(client.send)
-				log.Printf("Client disconnected. Total clients: %d", len(h.clients))
+				metrics.ClientDisconnected()
+				client.logger().Info("websocket client disconnected", "clients", len(h.clients))
 			}
 
 		case message := <-h.broadcast:
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+			h.deliver(message)
+
+		case reply := <-h.ping:
+			close(reply)
+
+		case events := <-h.subscribe:
+			h.subscribers[events] = true
+
+		case events := <-h.unsubscribe:
+			if h.subscribers[events] {
+				delete(h.subscribers, events)
+				close(events)
 			}
+
+		case <-ctx.Done():
+			h.stop()
+			return
+		}
+	}
+}
+
+// deliver hands a message to the clients it is addressed to, dropping
+// clients that are too slow to keep up
+func (h *Hub) deliver(message envelope) {
+	for client := range h.clients {
+		if client == message.skip || (message.to != nil && client != message.to) {
+			continue
+		}
+		select {
+		case client.send <- message.data:
+			metrics.MessageSent()
+		default:
+			close(client.send)
+			delete(h.clients, client)
+			metrics.ClientDisconnected()
+			metrics.MessageDropped("slow_client")
+		}
+	}
+
+	if message.event == nil {
+		return
+	}
+	for events := range h.subscribers {
+		select {
+		case events <- *message.event:
+		default:
+			close(events)
+			delete(h.subscribers, events)
+			metrics.MessageDropped("slow_subscriber")
 		}
 	}
 }
 
+// stop shuts the hub down once Run has been cancelled
+func (h *Hub) stop() {
+	close(h.done)
+
+	pending := len(h.broadcast)
+	for i := 0; i < pending; i++ {
+		h.deliver(<-h.broadcast)
+	}
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	for client := range h.clients {
+		client.closeMessage = closeMessage
+		close(client.send)
+		delete(h.clients, client)
+		metrics.ClientDisconnected()
+	}
+	for events := range h.subscribers {
+		close(events)
+		delete(h.subscribers, events)
+	}
+	slog.Info("websocket hub stopped", "pending_delivered", pending)
+
+	h.workMu.Lock()
+	h.stopping = true
+	h.workMu.Unlock()
+	h.work.Wait()
+
+	h.flushSessions()
+}
+
+// startWork registers a goroutine that may save notes, failing once the hub
+// is stopping. Every successful call must be matched by h.work.Done.
+func (h *Hub) startWork() bool {
+	h.workMu.Lock()
+	defer h.workMu.Unlock()
+	if h.stopping {
+		return false
+	}
+	h.work.Add(1)
+	return true
+}
+
+// isStopping reports whether Run is shutting the hub down
+func (h *Hub) isStopping() bool {
+	h.workMu.Lock()
+	defer h.workMu.Unlock()
+	return h.stopping
+}
+
+// enqueue queues a message for Run, dropping it once the hub has stopped
+func (h *Hub) enqueue(message envelope) {
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+		metrics.MessageDropped("hub_stopped")
+	}
+}
+
+// ErrHubStopped is returned by Ping once the hub has shut down
+var ErrHubStopped = errors.New("websocket hub stopped")
+
+// Ping checks that Run is processing events, waiting at most until ctx ends
+func (h *Hub) Ping(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case h.ping <- reply:
+	case <-h.done:
+		return ErrHubStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe returns the note and action events broadcast from now on, in
+// the order clients receive them. The channel is closed when ctx ends, when
+// the hub stops, or when the subscriber falls too far behind.
+func (h *Hub) Subscribe(ctx context.Context) (<-chan Event, error) {
+	events := make(chan Event, 64)
+	select {
+	case h.subscribe <- events:
+	case <-h.done:
+		return nil, ErrHubStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-h.done:
+			return
+		}
+		select {
+		case h.unsubscribe <- events:
+		case <-h.done:
+		}
+	}()
+	return events, nil
+}
+
+// QueueDepth is the number of messages waiting to be delivered
+func (h *Hub) QueueDepth() int {
+	return len(h.broadcast)
+}
+
+// BroadcastNoteCreated broadcasts when a note is created
+func (h *Hub) BroadcastNoteCreated(ctx context.Context, note *models.Note) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastNoteCreated", trace.WithAttributes(attribute.Int64("logmeup.note_id", note.ID)))
+	defer span.End()
+
+	message := NoteMessage{
+		Type: NoteCreated,
+		Note: note,
+	}
+	h.broadcastMessage(message)
+}
+
+// BroadcastNoteUpdated broadcasts when a note is updated
+func (h *Hub) BroadcastNoteUpdated(ctx context.Context, note *models.Note) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastNoteUpdated", trace.WithAttributes(attribute.Int64("logmeup.note_id", note.ID)))
+	defer span.End()
+
+	message := NoteMessage{
+		Type: NoteUpdated,
+		Note: note,
+	}
+	h.broadcastMessage(message)
+}
+
+// BroadcastNoteDeleted broadcasts when a note is deleted
+func (h *Hub) BroadcastNoteDeleted(ctx context.Context, noteID int64) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastNoteDeleted", trace.WithAttributes(attribute.Int64("logmeup.note_id", noteID)))
+	defer span.End()
+
+	message := NoteMessage{
+		Type: NoteDeleted,
+		ID:   noteID,
+	}
+	h.broadcastMessage(message)
+}
+
 // BroadcastActionCreated broadcasts when an action is created
-func (h *Hub) BroadcastActionCreated(action *models.Action) {
+func (h *Hub) BroadcastActionCreated(ctx context.Context, action *models.Action) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastActionCreated", trace.WithAttributes(attribute.Int64("logmeup.action_id", action.ID)))
+	defer span.End()
+
 	message := ActionMessage{
 		Type:   ActionCreated,
 		Action: action,
@@ -110,7 +407,10 @@ func (h *Hub) BroadcastActionCreated(action *models.Action) {
 }
 
 // BroadcastActionUpdated broadcasts when an action is updated
-func (h *Hub) BroadcastActionUpdated(action *models.Action) {
+func (h *Hub) BroadcastActionUpdated(ctx context.Context, action *models.Action) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastActionUpdated", trace.WithAttributes(attribute.Int64("logmeup.action_id", action.ID)))
+	defer span.End()
+
 	message := ActionMessage{
 		Type:   ActionUpdated,
 		Action: action,
@@ -119,7 +419,10 @@ func (h *Hub) BroadcastActionUpdated(action *models.Action) {
 }
 
 // BroadcastActionDeleted broadcasts when an action is deleted
-func (h *Hub) BroadcastActionDeleted(actionID int64) {
+func (h *Hub) BroadcastActionDeleted(ctx context.Context, actionID int64) {
+	_, span := tracer.Start(ctx, "Hub.BroadcastActionDeleted", trace.WithAttributes(attribute.Int64("logmeup.action_id", actionID)))
+	defer span.End()
+
 	message := ActionMessage{
 		Type: ActionDeleted,
 		ID:   actionID,
@@ -127,33 +430,106 @@ func (h *Hub) BroadcastActionDeleted(actionID int64) {
 	h.broadcastMessage(message)
 }
 
+// tracer records hub broadcasts and WebSocket requests
+var tracer = otel.Tracer("github.com/tehsis/logmeup-api/internal/websocket")
+
 // broadcastMessage sends a message to all connected clients
 func (h *Hub) broadcastMessage(message interface{}) {
+	h.broadcastExcept(message, nil)
+}
+
+// broadcastExcept sends a message to all connected clients but skip
+func (h *Hub) broadcastExcept(message interface{}, skip *Client) {
 	data, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("marshaling websocket message failed", "error", err)
 		return
 	}
 
-	log.Printf("Broadcasting message: %s", string(data))
-	h.broadcast <- data
+	slog.Debug("broadcasting websocket message", "bytes", len(data))
+	h.enqueue(envelope{data: data, skip: skip, event: eventOf(message)})
+}
+
+// eventOf returns the subscriber event for a broadcast message, or nil for
+// messages such as presence that are not note or action changes
+func eventOf(message interface{}) *Event {
+	switch m := message.(type) {
+	case ActionMessage:
+		event := &Event{Type: m.Type, Action: m.Action, ID: m.ID}
+		if m.Action != nil {
+			event.ID = m.Action.ID
+		}
+		return event
+	case NoteMessage:
+		event := &Event{Type: m.Type, Note: m.Note, ID: m.ID}
+		if m.Note != nil {
+			event.ID = m.Note.ID
+		}
+		return event
+	}
+	return nil
+}
+
+// sendTo queues a message for a single client. Delivery goes through Run so
+// that it is ordered with broadcasts and never races with the client being
+// unregistered.
+func (h *Hub) sendTo(client *Client, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("marshaling websocket message failed", "error", err)
+		return
+	}
+
+	h.enqueue(envelope{data: data, to: client})
 }
 
 // HandleWebSocket handles WebSocket connection requests
 func (h *Hub) HandleWebSocket(c *gin.Context) {
+	user, err := userFromRequest(c)
+	if err != nil {
+		handlers.AbortInvalidParameter(c, "user", err.Error())
+		return
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		logging.FromContext(c.Request.Context()).Warn("websocket upgrade failed", "error", err)
 		return
 	}
 
+	id := strconv.FormatUint(clientSeq.Add(1), 10)
+	if user == "" {
+		user = guestPrefix + id
+	}
+	// The connection outlives the upgrade request, so only its logger is kept
+	ctx := logging.WithAttrs(context.Background(), "client_id", id, "user", user)
+	ctx, cancel := context.WithCancel(ctx)
 	client := &Client{
-		hub:  h,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:    h,
+		id:     id,
+		user:   user,
+		conn:   conn,
+		send:   make(chan []byte, 256),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 
-	client.hub.register <- client
+	registered := h.startWork()
+	if registered {
+		select {
+		case h.register <- client:
+		case <-h.done:
+			h.work.Done()
+			registered = false
+		}
+	}
+	if !registered {
+		cancel()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting"))
+		conn.Close()
+		return
+	}
+	client.hub.userConnected(client)
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
@@ -161,21 +537,81 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 	go client.readPump()
 }
 
-// readPump pumps messages from the websocket connection to the hub
+// MaxUserLength bounds the user name a connection gives
+const MaxUserLength = 64
+
+// guestPrefix starts the names of anonymous connections; given names may
+// not use it
+const guestPrefix = "guest-"
+
+// userFromRequest reads the user behind a connection from the X-User-ID
+// header or the user query parameter, returning "" when neither is set.
+//
+// The name is taken on trust: like the REST API, the socket does not
+// authenticate clients, so any client can claim any name. It only labels
+// presence, collaborative edits and log lines, and must not be used for
+// access control. Names are limited to MaxUserLength letters, digits and
+// ".", "_", "-" or "@" so they are safe to echo to other clients and logs.
+func userFromRequest(c *gin.Context) (string, error) {
+	user := c.GetHeader(handlers.UserHeader)
+	if user == "" {
+		user = c.Query("user")
+	}
+	if user == "" {
+		return "", nil
+	}
+	if len(user) > MaxUserLength {
+		return "", fmt.Errorf("must be at most %d characters", MaxUserLength)
+	}
+	if strings.HasPrefix(user, guestPrefix) {
+		return "", fmt.Errorf("must not start with %q", guestPrefix)
+	}
+	for _, r := range user {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-@", r)) {
+			return "", errors.New(`may only contain letters, digits, ".", "_", "-" and "@"`)
+		}
+	}
+	return user, nil
+}
+
+// context returns the context requests from the client run under
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// logger returns the client's logger, tagged with its ID and user
+func (c *Client) logger() *slog.Logger {
+	return logging.FromContext(c.context())
+}
+
+// readPump pumps messages from the websocket connection to the hub. It is
+// part of the hub's work, since leaving sessions may save notes.
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.cancel()
+		c.hub.leaveAllSessions(c)
+		c.hub.userDisconnected(c)
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
 		c.conn.Close()
+		c.hub.work.Done()
 	}()
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				c.logger().Warn("websocket read failed", "error", err)
 			}
 			break
 		}
+
+		c.hub.handleRequest(c, message)
 	}
 }
 
@@ -187,12 +623,12 @@ func (c *Client) writePump() {
 		select {
 		case message, ok := <-c.send:
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage)
 				return
 			}
 
 			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("WebSocket write error: %v", err)
+				c.logger().Warn("websocket write failed", "error", err)
 				return
 			}
 		}
@@ -0,0 +1,187 @@
+package websocket
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/tehsis/logmeup-api/internal/models"
+)
+
+func TestHubShutdown(t *testing.T) {
+	h := NewHub(nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.Run(ctx)
+		close(done)
+	}()
+
+	if err := h.Ping(context.Background()); err != nil {
+		t.Fatalf("Expected a running hub to answer pings: %v", err)
+	}
+
+	client := &Client{hub: h, id: "1", send: make(chan []byte, 4)}
+	h.register <- client
+
+	// Queue a broadcast and stop straight away; it must still be delivered
+	h.BroadcastActionDeleted(context.Background(), 42)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after the context is cancelled")
+	}
+
+	message, ok := <-client.send
+	if !ok || string(message) != `{"type":"action_deleted","id":42}` {
+		t.Errorf("Expected the pending broadcast, got %q", message)
+	}
+	if _, ok := <-client.send; ok {
+		t.Error("Expected the client's send channel to be closed")
+	}
+
+	if err := h.Ping(context.Background()); err != ErrHubStopped {
+		t.Errorf("Expected ErrHubStopped from a stopped hub, got %v", err)
+	}
+
+	want := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	if string(client.closeMessage) != string(want) {
+		t.Errorf("Expected a server restarting close frame, got %q", client.closeMessage)
+	}
+
+	// Broadcasting after the hub stopped must not block
+	finished := make(chan struct{})
+	go func() {
+		h.BroadcastActionDeleted(context.Background(), 43)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("Expected broadcasts after shutdown to be dropped")
+	}
+}
+
+// slowNoteRepo holds every Update until release is closed, announcing it on
+// saving first
+type slowNoteRepo struct {
+	*fakeNoteRepo
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (r *slowNoteRepo) Update(ctx context.Context, id int64, req *models.UpdateNoteRequest) (*models.Note, error) {
+	r.saving <- struct{}{}
+	<-r.release
+	return r.fakeNoteRepo.Update(ctx, id, req)
+}
+
+func TestHubShutdownWaitsForSaves(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &slowNoteRepo{
+		fakeNoteRepo: &fakeNoteRepo{notes: map[int64]*models.Note{1: {ID: 1, Content: "abc"}}},
+		saving:       make(chan struct{}, 4),
+		release:      make(chan struct{}),
+	}
+	h := NewHub(repo, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.Run(ctx)
+		close(done)
+	}()
+
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"join_note","id":"1","data":{"note_id":1}}`))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"edit_note","id":"2","data":{"note_id":1,"revision":0,"operation":[3,"d"]}}`))
+	for {
+		var response Response
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("Expected the edit to be acknowledged: %v", err)
+		}
+		if response.ID == "2" {
+			break
+		}
+	}
+
+	// Disconnecting saves the edit from the client's goroutine; stopping the
+	// hub meanwhile must wait for that save
+	conn.Close()
+	select {
+	case <-repo.saving:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the edit to be saved when the client disconnects")
+	}
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Expected Run to wait for the save in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(repo.release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return once the save finished")
+	}
+	if repo.notes[1].Content != "abcd" || repo.updates != 1 {
+		t.Errorf("Expected the edit to be saved once, got %q after %d updates", repo.notes[1].Content, repo.updates)
+	}
+	if h.startWork() {
+		t.Error("Expected no saves to start once the hub has stopped")
+	}
+}
+
+func TestHubSubscribe(t *testing.T) {
+	h := NewHub(nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.Run(ctx)
+
+	subCtx, unsubscribe := context.WithCancel(context.Background())
+	events, err := h.Subscribe(subCtx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	h.BroadcastActionCreated(context.Background(), &models.Action{ID: 7, NoteID: 1})
+	h.broadcastMessage(PresenceMessage{Type: PresenceJoined, User: "alice"})
+	h.BroadcastActionDeleted(context.Background(), 7)
+
+	for _, want := range []Event{{Type: ActionCreated, ID: 7}, {Type: ActionDeleted, ID: 7}} {
+		select {
+		case event := <-events:
+			if event.Type != want.Type || event.ID != want.ID {
+				t.Errorf("Expected %s for %d, got %s for %d", want.Type, want.ID, event.Type, event.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a %s event", want.Type)
+		}
+	}
+
+	unsubscribe()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no events besides note and action changes")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the channel to be closed once the subscriber's context ends")
+	}
+}
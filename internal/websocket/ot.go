@@ -0,0 +1,249 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// TextOperation is an operational transform over plain text, compatible with
+// the ot.js wire format: a JSON array where a positive integer retains that
+// many characters, a negative integer deletes them and a string is inserted.
+// Lengths are counted in Unicode code points.
+type TextOperation struct {
+	ops          []opComponent
+	baseLength   int
+	targetLength int
+}
+
+// opComponent is a single retain, insert or delete; exactly one field is set
+type opComponent struct {
+	retain int
+	insert string
+	delete int
+}
+
+var (
+	errBaseLength     = errors.New("operation does not match the document length")
+	errIncompatibleOp = errors.New("operations were not made against the same document")
+)
+
+func (o *TextOperation) retain(n int) {
+	if n <= 0 {
+		return
+	}
+	o.baseLength += n
+	o.targetLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].retain > 0 {
+		o.ops[last].retain += n
+		return
+	}
+	o.ops = append(o.ops, opComponent{retain: n})
+}
+
+func (o *TextOperation) insert(s string) {
+	if s == "" {
+		return
+	}
+	o.targetLength += utf8.RuneCountInString(s)
+	last := len(o.ops) - 1
+	if last >= 0 && o.ops[last].insert != "" {
+		o.ops[last].insert += s
+		return
+	}
+	// Keep inserts ahead of deletes at the same position so equivalent
+	// operations have a single representation
+	if last >= 0 && o.ops[last].delete > 0 {
+		if last > 0 && o.ops[last-1].insert != "" {
+			o.ops[last-1].insert += s
+			return
+		}
+		o.ops = append(o.ops, o.ops[last])
+		o.ops[last] = opComponent{insert: s}
+		return
+	}
+	o.ops = append(o.ops, opComponent{insert: s})
+}
+
+func (o *TextOperation) delete(n int) {
+	if n <= 0 {
+		return
+	}
+	o.baseLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].delete > 0 {
+		o.ops[last].delete += n
+		return
+	}
+	o.ops = append(o.ops, opComponent{delete: n})
+}
+
+// IsNoop reports whether applying the operation leaves the document unchanged
+func (o *TextOperation) IsNoop() bool {
+	return len(o.ops) == 0 || (len(o.ops) == 1 && o.ops[0].retain > 0)
+}
+
+// Apply returns doc with the operation applied
+func (o *TextOperation) Apply(doc string) (string, error) {
+	runes := []rune(doc)
+	if len(runes) != o.baseLength {
+		return "", errBaseLength
+	}
+
+	result := make([]rune, 0, o.targetLength)
+	index := 0
+	for _, op := range o.ops {
+		switch {
+		case op.retain > 0:
+			result = append(result, runes[index:index+op.retain]...)
+			index += op.retain
+		case op.insert != "":
+			result = append(result, []rune(op.insert)...)
+		default:
+			index += op.delete
+		}
+	}
+	return string(result), nil
+}
+
+// TransformOperations takes two operations made concurrently against the
+// same document and returns a' and b' such that applying a then b' yields
+// the same document as applying b then a'.
+func TransformOperations(a, b *TextOperation) (*TextOperation, *TextOperation, error) {
+	if a.baseLength != b.baseLength {
+		return nil, nil, errIncompatibleOp
+	}
+
+	aPrime, bPrime := &TextOperation{}, &TextOperation{}
+	ops1, ops2 := a.ops, b.ops
+	var op1, op2 *opComponent
+	next := func(ops *[]opComponent) *opComponent {
+		if len(*ops) == 0 {
+			return nil
+		}
+		op := (*ops)[0]
+		*ops = (*ops)[1:]
+		return &op
+	}
+	op1, op2 = next(&ops1), next(&ops2)
+
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.insert != "" {
+			aPrime.insert(op1.insert)
+			bPrime.retain(utf8.RuneCountInString(op1.insert))
+			op1 = next(&ops1)
+			continue
+		}
+		if op2 != nil && op2.insert != "" {
+			aPrime.retain(utf8.RuneCountInString(op2.insert))
+			bPrime.insert(op2.insert)
+			op2 = next(&ops2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return nil, nil, errIncompatibleOp
+		}
+
+		len1, len2 := op1.retain+op1.delete, op2.retain+op2.delete
+		n := min(len1, len2)
+		switch {
+		case op1.retain > 0 && op2.retain > 0:
+			aPrime.retain(n)
+			bPrime.retain(n)
+		case op1.delete > 0 && op2.retain > 0:
+			aPrime.delete(n)
+		case op1.retain > 0 && op2.delete > 0:
+			bPrime.delete(n)
+		}
+		// Both deleting the same range needs nothing in either result
+
+		if len1 == n {
+			op1 = next(&ops1)
+		} else {
+			shrink(op1, n)
+		}
+		if len2 == n {
+			op2 = next(&ops2)
+		} else {
+			shrink(op2, n)
+		}
+	}
+
+	return aPrime, bPrime, nil
+}
+
+func shrink(op *opComponent, n int) {
+	if op.retain > 0 {
+		op.retain -= n
+	} else {
+		op.delete -= n
+	}
+}
+
+// TransformIndex moves a cursor position so it points at the same place
+// after the operation is applied
+func (o *TextOperation) TransformIndex(index int) int {
+	newIndex := index
+	for _, op := range o.ops {
+		switch {
+		case op.retain > 0:
+			index -= op.retain
+		case op.insert != "":
+			newIndex += utf8.RuneCountInString(op.insert)
+		default:
+			newIndex -= min(index, op.delete)
+			index -= op.delete
+		}
+		if index < 0 {
+			break
+		}
+	}
+	return newIndex
+}
+
+// MarshalJSON encodes the operation in the ot.js format
+func (o TextOperation) MarshalJSON() ([]byte, error) {
+	out := make([]interface{}, 0, len(o.ops))
+	for _, op := range o.ops {
+		switch {
+		case op.retain > 0:
+			out = append(out, op.retain)
+		case op.insert != "":
+			out = append(out, op.insert)
+		default:
+			out = append(out, -op.delete)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an operation in the ot.js format
+func (o *TextOperation) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*o = TextOperation{}
+	for i, item := range raw {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			if s == "" {
+				return fmt.Errorf("operation component %d: empty insert", i)
+			}
+			o.insert(s)
+			continue
+		}
+
+		var n int
+		if err := json.Unmarshal(item, &n); err != nil || n == 0 {
+			return fmt.Errorf("operation component %d: expected a string or a non-zero integer", i)
+		}
+		if n > 0 {
+			o.retain(n)
+		} else {
+			o.delete(-n)
+		}
+	}
+	return nil
+}
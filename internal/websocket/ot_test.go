@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parseOperation(t *testing.T, s string) *TextOperation {
+	t.Helper()
+	var op TextOperation
+	if err := json.Unmarshal([]byte(s), &op); err != nil {
+		t.Fatalf("Failed to parse operation %s: %v", s, err)
+	}
+	return &op
+}
+
+func TestTextOperation(t *testing.T) {
+	t.Run("Apply", func(t *testing.T) {
+		op := parseOperation(t, `[6, "big ", 5, -1]`)
+		got, err := op.Apply("hello world!")
+		if err != nil {
+			t.Fatalf("Failed to apply operation: %v", err)
+		}
+		if got != "hello big world" {
+			t.Errorf("Expected %q, got %q", "hello big world", got)
+		}
+
+		if _, err := op.Apply("too short"); err == nil {
+			t.Error("Expected error applying to a document of the wrong length")
+		}
+	})
+
+	t.Run("JSONRoundTrip", func(t *testing.T) {
+		op := parseOperation(t, `[2, -3, "ñu", 1]`)
+		data, err := json.Marshal(op)
+		if err != nil {
+			t.Fatalf("Failed to marshal operation: %v", err)
+		}
+		// Inserts are normalised ahead of deletes at the same position
+		if string(data) != `[2,"ñu",-3,1]` {
+			t.Errorf("Unexpected encoding %s", data)
+		}
+
+		var invalid TextOperation
+		if err := json.Unmarshal([]byte(`[1, 0]`), &invalid); err == nil {
+			t.Error("Expected error for a zero-length component")
+		}
+	})
+
+	t.Run("Transform", func(t *testing.T) {
+		tests := []struct {
+			name string
+			doc  string
+			a, b string
+			want string
+		}{
+			{"concurrent inserts", "abc", `[1, "X", 2]`, `[2, "Y", 1]`, "aXbYc"},
+			{"insert inside deleted range", "abcdef", `[2, "X", 4]`, `[1, -4, 1]`, "aXf"},
+			{"overlapping deletes", "abcdef", `[1, -3, 2]`, `[2, -3, 1]`, "af"},
+			{"same position inserts", "ab", `[1, "X", 1]`, `[1, "Y", 1]`, "aXYb"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				a, b := parseOperation(t, tt.a), parseOperation(t, tt.b)
+				aPrime, bPrime, err := TransformOperations(a, b)
+				if err != nil {
+					t.Fatalf("Failed to transform: %v", err)
+				}
+
+				afterA, _ := a.Apply(tt.doc)
+				left, err := bPrime.Apply(afterA)
+				if err != nil {
+					t.Fatalf("Failed to apply b': %v", err)
+				}
+				afterB, _ := b.Apply(tt.doc)
+				right, err := aPrime.Apply(afterB)
+				if err != nil {
+					t.Fatalf("Failed to apply a': %v", err)
+				}
+
+				if left != right || left != tt.want {
+					t.Errorf("Expected both orders to give %q, got %q and %q", tt.want, left, right)
+				}
+			})
+		}
+	})
+
+	t.Run("TransformIndex", func(t *testing.T) {
+		op := parseOperation(t, `["ab", 3, -2, 1]`)
+		for index, want := range map[int]int{0: 2, 3: 5, 4: 5, 6: 6} {
+			if got := op.TransformIndex(index); got != want {
+				t.Errorf("Index %d: expected %d, got %d", index, want, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewNote tells other clients which note a connection is looking at
+const ViewNote RequestType = "view_note"
+
+// Message types for presence
+const (
+	PresenceJoined  MessageType = "presence_joined"
+	PresenceLeft    MessageType = "presence_left"
+	PresenceViewing MessageType = "presence_viewing"
+)
+
+// PresenceMessage announces a user coming online, going offline or changing
+// the note one of their connections is viewing. NoteID is omitted when the
+// connection stopped viewing a note.
+type PresenceMessage struct {
+	Type     MessageType `json:"type"`
+	User     string      `json:"user"`
+	ClientID string      `json:"client_id"`
+	NoteID   int64       `json:"note_id,omitempty"`
+}
+
+// PresenceUser is an online user in the presence snapshot
+type PresenceUser struct {
+	User        string  `json:"user"`
+	Connections int     `json:"connections"`
+	Viewing     []int64 `json:"viewing"`
+}
+
+// PresenceSnapshot lists who is online and who is viewing each note
+type PresenceSnapshot struct {
+	Users []PresenceUser     `json:"users"`
+	Notes map[int64][]string `json:"notes"`
+}
+
+type viewNotePayload struct {
+	NoteID int64 `json:"note_id"`
+}
+
+// presence tracks connected users and the note each connection is viewing.
+// A note ID of zero means the connection is not viewing any note.
+type presence struct {
+	mu      sync.Mutex
+	viewing map[*Client]int64
+}
+
+// connectionsOf counts the connections belonging to user; callers hold p.mu
+func (p *presence) connectionsOf(user string) int {
+	count := 0
+	for client := range p.viewing {
+		if client.user == user {
+			count++
+		}
+	}
+	return count
+}
+
+// userConnected records a new connection and announces the user when it is
+// their first one
+func (h *Hub) userConnected(client *Client) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+
+	first := h.presence.connectionsOf(client.user) == 0
+	h.presence.viewing[client] = 0
+	if first {
+		h.broadcastMessage(PresenceMessage{Type: PresenceJoined, User: client.user, ClientID: client.id})
+	}
+}
+
+// userDisconnected forgets a connection, clearing what it was viewing and
+// announcing the user as gone when it was their last one
+func (h *Hub) userDisconnected(client *Client) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+
+	noteID, ok := h.presence.viewing[client]
+	if !ok {
+		return
+	}
+	delete(h.presence.viewing, client)
+
+	if h.presence.connectionsOf(client.user) == 0 {
+		h.broadcastExcept(PresenceMessage{Type: PresenceLeft, User: client.user, ClientID: client.id}, client)
+	} else if noteID != 0 {
+		h.broadcastExcept(PresenceMessage{Type: PresenceViewing, User: client.user, ClientID: client.id}, client)
+	}
+}
+
+// viewNote records the note a connection is viewing and returns the event
+// announcing it
+func (h *Hub) viewNote(client *Client, req *Request) (interface{}, interface{}, error) {
+	var payload viewNotePayload
+	if err := decodePayload(req.Data, &payload); err != nil {
+		return nil, nil, err
+	}
+
+	h.presence.mu.Lock()
+	h.presence.viewing[client] = payload.NoteID
+	h.presence.mu.Unlock()
+
+	return payload, PresenceMessage{
+		Type:     PresenceViewing,
+		User:     client.user,
+		ClientID: client.id,
+		NoteID:   payload.NoteID,
+	}, nil
+}
+
+// Presence returns who is online and which notes they are viewing
+func (h *Hub) Presence() PresenceSnapshot {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+
+	users := make(map[string]*PresenceUser)
+	viewers := make(map[int64]map[string]bool)
+	for client, noteID := range h.presence.viewing {
+		u, ok := users[client.user]
+		if !ok {
+			u = &PresenceUser{User: client.user, Viewing: []int64{}}
+			users[client.user] = u
+		}
+		u.Connections++
+		if noteID == 0 {
+			continue
+		}
+		if viewers[noteID] == nil {
+			viewers[noteID] = make(map[string]bool)
+		}
+		if !viewers[noteID][client.user] {
+			viewers[noteID][client.user] = true
+			u.Viewing = append(u.Viewing, noteID)
+		}
+	}
+
+	snapshot := PresenceSnapshot{Users: []PresenceUser{}, Notes: make(map[int64][]string)}
+	for _, u := range users {
+		sort.Slice(u.Viewing, func(i, j int) bool { return u.Viewing[i] < u.Viewing[j] })
+		snapshot.Users = append(snapshot.Users, *u)
+	}
+	sort.Slice(snapshot.Users, func(i, j int) bool { return snapshot.Users[i].User < snapshot.Users[j].User })
+	for noteID, names := range viewers {
+		for name := range names {
+			snapshot.Notes[noteID] = append(snapshot.Notes[noteID], name)
+		}
+		sort.Strings(snapshot.Notes[noteID])
+	}
+	return snapshot
+}
+
+// HandlePresence serves the presence snapshot
+func (h *Hub) HandlePresence(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Presence())
+}
@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPresence(t *testing.T) {
+	h := NewHub(nil, nil)
+	aliceLaptop := &Client{hub: h, id: "1", user: "alice"}
+	alicePhone := &Client{hub: h, id: "2", user: "alice"}
+	bob := &Client{hub: h, id: "3", user: "bob"}
+
+	h.userConnected(aliceLaptop)
+	h.userConnected(alicePhone)
+	h.userConnected(bob)
+
+	var joined []string
+	for _, message := range drain(h, nil) {
+		if message["type"] == string(PresenceJoined) {
+			joined = append(joined, message["user"].(string))
+		}
+	}
+	if len(joined) != 2 {
+		t.Errorf("Expected one presence_joined per user, got %v", joined)
+	}
+
+	h.handleRequest(alicePhone, []byte(`{"type":"view_note","id":"1","data":{"note_id":7}}`))
+	h.handleRequest(bob, []byte(`{"type":"view_note","id":"2","data":{"note_id":7}}`))
+	drain(h, nil)
+
+	snapshot := h.Presence()
+	data, _ := json.Marshal(snapshot)
+	want := `{"users":[{"user":"alice","connections":2,"viewing":[7]},{"user":"bob","connections":1,"viewing":[7]}],"notes":{"7":["alice","bob"]}}`
+	if string(data) != want {
+		t.Errorf("Unexpected snapshot:\n got %s\nwant %s", data, want)
+	}
+
+	// Closing a connection clears what it was viewing without marking the
+	// user offline while they have another connection
+	h.userDisconnected(alicePhone)
+	messages := drain(h, nil)
+	if len(messages) != 1 || messages[0]["type"] != string(PresenceViewing) || messages[0]["note_id"] != nil {
+		t.Errorf("Expected a cleared presence_viewing event, got %v", messages)
+	}
+
+	h.userDisconnected(aliceLaptop)
+	messages = drain(h, nil)
+	if len(messages) != 1 || messages[0]["type"] != string(PresenceLeft) || messages[0]["user"] != "alice" {
+		t.Errorf("Expected presence_left for alice, got %v", messages)
+	}
+
+	if users := h.Presence().Users; len(users) != 1 || users[0].User != "bob" {
+		t.Errorf("Expected only bob to be online, got %v", users)
+	}
+}
+
+func TestUserFromRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		want   string
+		valid  bool
+	}{
+		{"Header", "alice", "", "alice", true},
+		{"Query", "", "bob.smith@example.com", "bob.smith@example.com", true},
+		{"HeaderFirst", "alice", "bob", "alice", true},
+		{"Anonymous", "", "", "", true},
+		{"TooLong", strings.Repeat("a", MaxUserLength+1), "", "", false},
+		{"Spaces", "", "alice smith", "", false},
+		{"Markup", "<script>", "", "", false},
+		{"GuestName", "guest-7", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/ws?user="+url.QueryEscape(tt.query), nil)
+			if tt.header != "" {
+				c.Request.Header.Set("X-User-ID", tt.header)
+			}
+
+			user, err := userFromRequest(c)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Expected valid=%v, got error %v", tt.valid, err)
+			}
+			if user != tt.want {
+				t.Errorf("Expected user %q, got %q", tt.want, user)
+			}
+		})
+	}
+}
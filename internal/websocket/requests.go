@@ -0,0 +1,322 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/tehsis/logmeup-api/internal/handlers"
+	"github.com/tehsis/logmeup-api/internal/logging"
+	"github.com/tehsis/logmeup-api/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NoteRepository is the subset of the note repository used by socket requests
+type NoteRepository interface {
+	Create(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error)
+	GetByID(ctx context.Context, id int64) (*models.Note, error)
+	Update(ctx context.Context, id int64, note *models.UpdateNoteRequest) (*models.Note, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// ActionRepository is the subset of the action repository used by socket requests
+type ActionRepository interface {
+	Create(ctx context.Context, action *models.CreateActionRequest) (*models.Action, error)
+	Update(ctx context.Context, id int64, action *models.UpdateActionRequest) (*models.Action, bool, error)
+	Toggle(ctx context.Context, id int64) (*models.Action, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// RequestType identifies a mutation requested by a client
+type RequestType string
+
+const (
+	CreateAction RequestType = "create_action"
+	UpdateAction RequestType = "update_action"
+	ToggleAction RequestType = "toggle_action"
+	DeleteAction RequestType = "delete_action"
+	CreateNote   RequestType = "create_note"
+	UpdateNote   RequestType = "update_note"
+	DeleteNote   RequestType = "delete_note"
+)
+
+// Error codes sent back in error frames
+const (
+	CodeInvalidJSON      = "INVALID_JSON"
+	CodeMissingID        = "MISSING_REQUEST_ID"
+	CodeUnknownType      = "UNKNOWN_TYPE"
+	CodeNotFound         = "NOT_FOUND"
+	CodeInvalidReference = "INVALID_REFERENCE"
+	CodeConflict         = "CONFLICT"
+	CodeValidation       = "VALIDATION_ERROR"
+	CodeDatabaseError    = "DATABASE_ERROR"
+	CodeTimeout          = "DATABASE_TIMEOUT"
+	CodeCanceled         = "REQUEST_CANCELED"
+	CodeUnavailable      = "UNAVAILABLE"
+)
+
+// Request is a frame sent by a client. ID is chosen by the client and echoed
+// back in the matching ack or error frame.
+type Request struct {
+	Type RequestType     `json:"type"`
+	ID   string          `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Response is the ack or error frame answering a Request
+type Response struct {
+	Type  MessageType    `json:"type"`
+	ID    string         `json:"id"`
+	Data  interface{}    `json:"data,omitempty"`
+	Error *ResponseError `json:"error,omitempty"`
+}
+
+// ResponseError describes why a request failed
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields lists the invalid data fields of a VALIDATION_ERROR
+	Fields []handlers.FieldError `json:"fields,omitempty"`
+}
+
+// Payloads for requests addressing an existing record
+type idPayload struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+type updateActionPayload struct {
+	ID int64 `json:"id" binding:"required"`
+	models.UpdateActionRequest
+}
+
+type updateNotePayload struct {
+	ID int64 `json:"id" binding:"required"`
+	models.UpdateNoteRequest
+}
+
+// requestError is returned by request handlers to produce an error frame
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// validationError is returned for request data breaking the binding rules,
+// listing the offending fields as REST problems do
+type validationError struct {
+	fields []handlers.FieldError
+}
+
+func (e *validationError) Error() string {
+	return "request data has invalid fields"
+}
+
+// invalidField is a validation error for a single data field
+func invalidField(field, rule, message string) error {
+	return &validationError{[]handlers.FieldError{{Field: field, Rule: rule, Message: message}}}
+}
+
+// sessionRequests handle collaborative editing. They answer the request
+// themselves so the ack and events are queued while the note is locked.
+var sessionRequests = map[RequestType]func(*Hub, context.Context, *Client, *Request) error{
+	JoinNote:   (*Hub).joinNote,
+	LeaveNote:  (*Hub).leaveNote,
+	EditNote:   (*Hub).editNote,
+	MoveCursor: (*Hub).moveCursor,
+}
+
+// handleRequest executes a client frame and answers it. On success the ack is
+// queued before the resulting event is broadcast to the other clients.
+func (h *Hub) handleRequest(client *Client, raw []byte) {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		client.logger().Debug("invalid websocket frame", "error", err, logging.Content("frame", string(raw)))
+		h.sendTo(client, errorResponse(req.ID, &requestError{CodeInvalidJSON, "invalid request frame"}))
+		return
+	}
+	if req.ID == "" {
+		h.sendTo(client, errorResponse(req.ID, &requestError{CodeMissingID, "request id is required"}))
+		return
+	}
+
+	ctx, span := tracer.Start(client.context(), "websocket "+string(req.Type), trace.WithAttributes(
+		attribute.String("logmeup.request_id", req.ID),
+		attribute.String("logmeup.client_id", client.id),
+	))
+	defer span.End()
+
+	if handler, ok := sessionRequests[req.Type]; ok {
+		if h.notes == nil {
+			h.sendTo(client, errorResponse(req.ID, &requestError{CodeUnavailable, "notes are not available"}))
+			return
+		}
+		if err := handler(h, ctx, client, &req); err != nil {
+			h.requestFailed(span, client, &req, err)
+		}
+		return
+	}
+
+	data, event, err := h.execute(ctx, client, &req)
+	if err != nil {
+		h.requestFailed(span, client, &req, err)
+		return
+	}
+
+	h.sendTo(client, Response{Type: Ack, ID: req.ID, Data: data})
+	h.broadcastExcept(event, client)
+}
+
+// requestFailed logs a failed request, marks its span and answers with an
+// error frame
+func (h *Hub) requestFailed(span trace.Span, client *Client, req *Request, err error) {
+	client.logger().Warn("websocket request failed", "request_id", req.ID, "type", req.Type, "error", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	h.sendTo(client, errorResponse(req.ID, err))
+}
+
+// execute runs a request and returns the ack payload and the event to broadcast
+func (h *Hub) execute(ctx context.Context, client *Client, req *Request) (interface{}, interface{}, error) {
+	switch req.Type {
+	case ViewNote:
+		return h.viewNote(client, req)
+	case CreateAction, UpdateAction, ToggleAction, DeleteAction:
+		if h.actions == nil {
+			return nil, nil, &requestError{CodeUnavailable, "actions are not available"}
+		}
+	case CreateNote, UpdateNote, DeleteNote:
+		if h.notes == nil {
+			return nil, nil, &requestError{CodeUnavailable, "notes are not available"}
+		}
+	}
+
+	switch req.Type {
+	case CreateAction:
+		var payload models.CreateActionRequest
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		action, err := h.actions.Create(ctx, &payload)
+		if err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return action, ActionMessage{Type: ActionCreated, Action: action}, nil
+
+	case UpdateAction:
+		var payload updateActionPayload
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		action, _, err := h.actions.Update(ctx, payload.ID, &payload.UpdateActionRequest)
+		if err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return action, ActionMessage{Type: ActionUpdated, Action: action}, nil
+
+	case ToggleAction:
+		var payload idPayload
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		action, err := h.actions.Toggle(ctx, payload.ID)
+		if err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return action, ActionMessage{Type: ActionUpdated, Action: action}, nil
+
+	case DeleteAction:
+		var payload idPayload
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		if err := h.actions.Delete(ctx, payload.ID); err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return payload, ActionMessage{Type: ActionDeleted, ID: payload.ID}, nil
+
+	case CreateNote:
+		var payload models.CreateNoteRequest
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		note, err := h.notes.Create(ctx, &payload)
+		if err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return note, NoteMessage{Type: NoteCreated, Note: note}, nil
+
+	case UpdateNote:
+		var payload updateNotePayload
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		note, err := h.notes.Update(ctx, payload.ID, &payload.UpdateNoteRequest)
+		if err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return note, NoteMessage{Type: NoteUpdated, Note: note}, nil
+
+	case DeleteNote:
+		var payload idPayload
+		if err := decodePayload(req.Data, &payload); err != nil {
+			return nil, nil, err
+		}
+		if err := h.notes.Delete(ctx, payload.ID); err != nil {
+			return nil, nil, repositoryError(err)
+		}
+		return payload, NoteMessage{Type: NoteDeleted, ID: payload.ID}, nil
+	}
+
+	return nil, nil, &requestError{CodeUnknownType, "unknown request type: " + string(req.Type)}
+}
+
+// decodePayload unmarshals request data and applies the same binding rules
+// the REST handlers use
+func decodePayload(data json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return &requestError{CodeInvalidJSON, err.Error()}
+	}
+	err := binding.Validator.ValidateStruct(v)
+	if fields, ok := handlers.ValidationFields(err, validator.FieldError.Field); ok {
+		return &validationError{fields}
+	}
+	return err
+}
+
+// repositoryError converts a repository failure into a request error. Only
+// the log gets the underlying database error.
+func repositoryError(err error) error {
+	failure := handlers.ClassifyStoreError(err, "record")
+	if failure.Code == handlers.CodeDatabaseError {
+		slog.Error("websocket repository error", "error", err)
+	}
+	return &requestError{failure.Code, failure.Message}
+}
+
+func errorResponse(id string, err error) Response {
+	var validationErr *validationError
+	if errors.As(err, &validationErr) {
+		return Response{
+			Type:  Error,
+			ID:    id,
+			Error: &ResponseError{Code: CodeValidation, Message: validationErr.Error(), Fields: validationErr.fields},
+		}
+	}
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) {
+		reqErr = &requestError{CodeDatabaseError, "internal server error"}
+	}
+	return Response{
+		Type:  Error,
+		ID:    id,
+		Error: &ResponseError{Code: reqErr.code, Message: reqErr.message},
+	}
+}
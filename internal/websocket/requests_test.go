@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tehsis/logmeup-api/internal/models"
+	"github.com/tehsis/logmeup-api/internal/repository"
+)
+
+type fakeActionRepo struct {
+	actions map[int64]*models.Action
+	nextID  int64
+}
+
+func newFakeActionRepo() *fakeActionRepo {
+	return &fakeActionRepo{actions: make(map[int64]*models.Action)}
+}
+
+func (r *fakeActionRepo) Create(ctx context.Context, req *models.CreateActionRequest) (*models.Action, error) {
+	r.nextID++
+	action := &models.Action{ID: r.nextID, NoteID: req.NoteID, Description: req.Description, CreatedAt: time.Now()}
+	r.actions[action.ID] = action
+	return action, nil
+}
+
+func (r *fakeActionRepo) GetByID(ctx context.Context, id int64) (*models.Action, error) {
+	action, ok := r.actions[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *action
+	return &copied, nil
+}
+
+func (r *fakeActionRepo) Update(ctx context.Context, id int64, req *models.UpdateActionRequest) (*models.Action, bool, error) {
+	action, ok := r.actions[id]
+	if !ok {
+		return nil, false, repository.ErrNotFound
+	}
+	changed := action.Completed != req.Completed
+	action.Completed = req.Completed
+	copied := *action
+	return &copied, changed, nil
+}
+
+func (r *fakeActionRepo) Toggle(ctx context.Context, id int64) (*models.Action, error) {
+	action, ok := r.actions[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	action.Completed = !action.Completed
+	copied := *action
+	return &copied, nil
+}
+
+func (r *fakeActionRepo) Delete(ctx context.Context, id int64) error {
+	delete(r.actions, id)
+	return nil
+}
+
+func readEnvelope(t *testing.T, h *Hub) envelope {
+	t.Helper()
+	select {
+	case e := <-h.broadcast:
+		return e
+	default:
+		t.Fatal("Expected a queued message")
+		return envelope{}
+	}
+}
+
+func TestHandleRequest(t *testing.T) {
+	t.Run("CreateAction", func(t *testing.T) {
+		h := NewHub(nil, newFakeActionRepo())
+		client := &Client{hub: h}
+
+		h.handleRequest(client, []byte(`{"type":"create_action","id":"req-1","data":{"note_id":1,"description":"Call Bob"}}`))
+
+		ack := readEnvelope(t, h)
+		if ack.to != client {
+			t.Fatal("Expected ack to be addressed to the sender")
+		}
+		var response Response
+		if err := json.Unmarshal(ack.data, &response); err != nil {
+			t.Fatalf("Failed to unmarshal ack: %v", err)
+		}
+		if response.Type != Ack || response.ID != "req-1" {
+			t.Errorf("Expected ack for req-1, got %s for %q", response.Type, response.ID)
+		}
+
+		event := readEnvelope(t, h)
+		if event.skip != client || event.to != nil {
+			t.Fatal("Expected event to be broadcast to everyone but the sender")
+		}
+		var message ActionMessage
+		if err := json.Unmarshal(event.data, &message); err != nil {
+			t.Fatalf("Failed to unmarshal event: %v", err)
+		}
+		if message.Type != ActionCreated || message.Action.Description != "Call Bob" {
+			t.Errorf("Unexpected event: %+v", message)
+		}
+	})
+
+	t.Run("ToggleAction", func(t *testing.T) {
+		repo := newFakeActionRepo()
+		created, _ := repo.Create(context.Background(), &models.CreateActionRequest{NoteID: 1, Description: "Toggle me"})
+		h := NewHub(nil, repo)
+
+		h.handleRequest(&Client{hub: h}, []byte(`{"type":"toggle_action","id":"t","data":{"id":1}}`))
+		readEnvelope(t, h)
+		readEnvelope(t, h)
+
+		if !repo.actions[created.ID].Completed {
+			t.Error("Expected action to be completed after toggle")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			frame string
+			code  string
+		}{
+			{"malformed frame", `{`, CodeInvalidJSON},
+			{"missing id", `{"type":"create_action"}`, CodeMissingID},
+			{"unknown type", `{"type":"launch","id":"x"}`, CodeUnknownType},
+			{"failed validation", `{"type":"create_action","id":"x","data":{"note_id":1}}`, CodeValidation},
+			{"missing record", `{"type":"toggle_action","id":"x","data":{"id":42}}`, CodeNotFound},
+			{"no note repository", `{"type":"delete_note","id":"x","data":{"id":1}}`, CodeUnavailable},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h := NewHub(nil, newFakeActionRepo())
+				h.handleRequest(&Client{hub: h}, []byte(tt.frame))
+
+				var response Response
+				if err := json.Unmarshal(readEnvelope(t, h).data, &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Type != Error || response.Error == nil || response.Error.Code != tt.code {
+					t.Errorf("Expected error %s, got %+v", tt.code, response)
+				}
+				if len(h.broadcast) != 0 {
+					t.Error("Expected nothing to be broadcast for a failed request")
+				}
+			})
+		}
+	})
+
+	t.Run("ValidationFields", func(t *testing.T) {
+		h := NewHub(nil, newFakeActionRepo())
+		h.handleRequest(&Client{hub: h}, []byte(`{"type":"create_action","id":"x","data":{"note_id":1}}`))
+
+		var response Response
+		json.Unmarshal(readEnvelope(t, h).data, &response)
+		if response.Error == nil || len(response.Error.Fields) != 1 || response.Error.Fields[0].Field != "description" {
+			t.Errorf("Expected the description field to be reported, got %+v", response.Error)
+		}
+	})
+}
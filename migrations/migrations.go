@@ -0,0 +1,25 @@
+// Package migrations embeds the SQL schema migrations so the binary can
+// apply them without the files being deployed next to it.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// Postgres holds the migrations for the postgres driver
+var Postgres = mustSub(".")
+
+// SQLite holds the migrations for the sqlite driver
+var SQLite = mustSub("sqlite")
+
+func mustSub(dir string) fs.FS {
+	sub, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
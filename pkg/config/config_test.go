@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Run in an empty directory so no .env is picked up
+	t.Chdir(t.TempDir())
+	for _, s := range settings {
+		if _, ok := os.LookupEnv(s.env); ok {
+			t.Setenv(s.env, s.def)
+		}
+	}
+	t.Setenv("CONFIG_FILE", "")
+
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, args, err := LoadConfig(nil)
+		if err != nil {
+			t.Fatalf("Expected defaults without a .env file, got %v", err)
+		}
+		if cfg.ServerPort != "5173" || cfg.GRPCPort != "9090" {
+			t.Errorf("Expected ports 5173 and 9090, got %s and %s", cfg.ServerPort, cfg.GRPCPort)
+		}
+		if cfg.DBSSLMode != "disable" {
+			t.Errorf("Expected sslmode disable, got %s", cfg.DBSSLMode)
+		}
+		if cfg.DBMaxOpenConns != 25 || cfg.DBConnMaxLifetime != 30*time.Minute {
+			t.Errorf("Expected the default pool settings, got %d open and %v lifetime", cfg.DBMaxOpenConns, cfg.DBConnMaxLifetime)
+		}
+		if cfg.MaxBodyBytes != 1<<20 || cfg.ImportMaxBytes != 100<<20 {
+			t.Errorf("Expected body limits of 1 MiB and 100 MiB for imports, got %d and %d", cfg.MaxBodyBytes, cfg.ImportMaxBytes)
+		}
+		if len(cfg.TrustedProxies) != 0 {
+			t.Errorf("Expected no trusted proxies, got %v", cfg.TrustedProxies)
+		}
+		if len(args) != 0 {
+			t.Errorf("Expected no arguments, got %v", args)
+		}
+	})
+
+	t.Run("TrustedProxies", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1,")
+
+		cfg, _, err := LoadConfig(nil)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if strings.Join(cfg.TrustedProxies, " ") != "10.0.0.0/8 192.168.1.1" {
+			t.Errorf("Expected both proxies, got %v", cfg.TrustedProxies)
+		}
+
+		t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+		if _, _, err := LoadConfig(nil); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+			t.Errorf("Expected a host name to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("DotEnv", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("LOG_FORMAT=json\n"), 0o600); err != nil {
+			t.Fatalf("Failed to write .env: %v", err)
+		}
+		t.Chdir(dir)
+		// godotenv sets the variable for the process; t.Setenv restores it
+		t.Setenv("LOG_FORMAT", "")
+		os.Unsetenv("LOG_FORMAT")
+
+		cfg, _, err := LoadConfig(nil)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.LogFormat != "json" {
+			t.Errorf("Expected LOG_FORMAT from .env, got %s", cfg.LogFormat)
+		}
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+database:
+  driver: sqlite
+  query_timeout: 2s
+log:
+  level: debug
+`)
+		t.Setenv("DB_QUERY_TIMEOUT", "3s")
+		t.Setenv("LOG_LEVEL", "warn")
+
+		cfg, args, err := LoadConfig([]string{"-config", path, "-log-level", "error", "migrate", "up"})
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.ServerPort != "9000" || cfg.DBDriver != "sqlite" {
+			t.Errorf("Expected values from the file, got port %s and driver %s", cfg.ServerPort, cfg.DBDriver)
+		}
+		if cfg.DBQueryTimeout != 3*time.Second {
+			t.Errorf("Expected the environment to override the file, got %v", cfg.DBQueryTimeout)
+		}
+		if cfg.LogLevel.String() != "ERROR" {
+			t.Errorf("Expected the flag to override the environment, got %v", cfg.LogLevel)
+		}
+		if strings.Join(args, " ") != "migrate up" {
+			t.Errorf("Expected the subcommand to be returned, got %v", args)
+		}
+	})
+
+	t.Run("TOML", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `
+[database]
+url = "postgres://app:secret@db:5432/logmeup"
+max_open_conns = 10
+
+[tracing]
+sample_ratio = 0.5
+`)
+		t.Setenv("CONFIG_FILE", path)
+
+		cfg, _, err := LoadConfig(nil)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.DatabaseURL != "postgres://app:secret@db:5432/logmeup" || cfg.DBMaxOpenConns != 10 || cfg.TracingSampleRatio != 0.5 {
+			t.Errorf("Expected values from the TOML file, got %+v", cfg)
+		}
+	})
+
+	t.Run("ReportsEveryError", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  prot: 9000
+database:
+  port: abc
+`)
+		t.Setenv("DB_DRIVER", "mysql")
+		t.Setenv("DB_SSLCERT", filepath.Join(t.TempDir(), "missing.crt"))
+		t.Setenv("GRPC_TLS_KEY", writeFile(t, "grpc.key", "key"))
+
+		_, _, err := LoadConfig([]string{"-config", path, "-shutdown-timeout", "soon", "-grpc-port", "5173"})
+		if err == nil {
+			t.Fatal("Expected an error for invalid configuration")
+		}
+		for _, want := range []string{
+			"unknown setting server.prot",
+			`database.port in ` + path + `: invalid value "abc"`,
+			`DB_DRIVER: invalid value "mysql"`,
+			`-shutdown-timeout: invalid value "soon"`,
+			"DB_SSLCERT and DB_SSLKEY must be set together",
+			"GRPC_TLS_CERT and GRPC_TLS_KEY must be set together",
+			"GRPC_PORT and SERVER_PORT must differ",
+			"no such file",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected the error to mention %q, got:\n%v", want, err)
+			}
+		}
+	})
+
+	t.Run("HidesDatabaseURL", func(t *testing.T) {
+		t.Setenv("DATABASE_URL", "mysql://app:secret@db/logmeup")
+
+		_, _, err := LoadConfig(nil)
+		if err == nil {
+			t.Fatal("Expected an error for a non-postgres URL")
+		}
+		if strings.Contains(err.Error(), "secret") {
+			t.Errorf("Expected the password to be left out of the error, got %v", err)
+		}
+	})
+}
@@ -1,27 +1,126 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/tehsis/logmeup-api/internal/backoff"
 	"github.com/tehsis/logmeup-api/pkg/config"
 )
 
 func NewDBConnection(cfg *config.Config) (*sql.DB, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
-	)
+	connStr, err := ConnString(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %v", err)
 	}
+	configurePool(db, cfg)
 
-	if err := db.Ping(); err != nil {
+	if err := waitForDatabase(db, cfg.DBConnectTimeout); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error connecting to the database: %v", err)
 	}
 
 	return db, nil
-} 
\ No newline at end of file
+}
+
+// ConnString builds the Postgres connection string for cfg: DatabaseURL when
+// set, otherwise the separate host, port, user, password and name. The SSL
+// settings are added unless the URL already sets them.
+func ConnString(cfg *config.Config) (string, error) {
+	ssl := [][2]string{
+		{"sslmode", cfg.DBSSLMode},
+		{"sslrootcert", cfg.DBSSLRootCert},
+		{"sslcert", cfg.DBSSLCert},
+		{"sslkey", cfg.DBSSLKey},
+	}
+
+	if cfg.DatabaseURL != "" {
+		u, err := url.Parse(cfg.DatabaseURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid DATABASE_URL")
+		}
+		query := u.Query()
+		for _, param := range ssl {
+			if param[1] != "" && !query.Has(param[0]) {
+				query.Set(param[0], param[1])
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	params := [][2]string{
+		{"host", cfg.DBHost},
+		{"port", cfg.DBPort},
+		{"user", cfg.DBUser},
+		{"password", cfg.DBPassword},
+		{"dbname", cfg.DBName},
+	}
+	var b strings.Builder
+	for _, param := range append(params, ssl...) {
+		if param[1] == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(param[0] + "=" + quote(param[1]))
+	}
+	return b.String(), nil
+}
+
+// quote escapes a connection string value so spaces, quotes and
+// backslashes in passwords survive
+func quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// waitForDatabase pings db until it answers, backing off exponentially
+// between attempts, so the server can start alongside a database that is
+// still coming up. It gives up with the last error after timeout, or after
+// one attempt when timeout is zero.
+func waitForDatabase(db *sql.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return db.Ping()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	b := &backoff.Backoff{Initial: 250 * time.Millisecond, Max: 5 * time.Second}
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("gave up after %d attempts in %v: %v", attempt, timeout, err)
+		}
+		slog.Warn("database not reachable yet, retrying", "attempt", attempt, "error", err)
+		if b.Wait(ctx) != nil {
+			return fmt.Errorf("gave up after %d attempts in %v: %v", attempt, timeout, err)
+		}
+	}
+}
+
+// configurePool applies the connection pool settings of cfg
+func configurePool(db *sql.DB, cfg *config.Config) {
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+}
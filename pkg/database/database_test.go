@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/tehsis/logmeup-api/pkg/config"
+)
+
+func TestConnString(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{
+			name: "Fields",
+			cfg:  config.Config{DBHost: "db", DBPort: "5432", DBUser: "app", DBPassword: "it's a secret", DBName: "logmeup", DBSSLMode: "disable"},
+			want: `host=db port=5432 user=app password='it\'s a secret' dbname=logmeup sslmode=disable`,
+		},
+		{
+			name: "Certificates",
+			cfg:  config.Config{DBHost: "db", DBName: "logmeup", DBSSLMode: "verify-full", DBSSLRootCert: "/certs/ca.pem", DBSSLCert: "/certs/client.pem", DBSSLKey: "/certs/client.key"},
+			want: "host=db dbname=logmeup sslmode=verify-full sslrootcert=/certs/ca.pem sslcert=/certs/client.pem sslkey=/certs/client.key",
+		},
+		{
+			name: "URL",
+			cfg:  config.Config{DatabaseURL: "postgres://app:secret@db:5432/logmeup", DBHost: "ignored", DBSSLMode: "require"},
+			want: "postgres://app:secret@db:5432/logmeup?sslmode=require",
+		},
+		{
+			name: "URLKeepsItsSSLMode",
+			cfg:  config.Config{DatabaseURL: "postgres://db/logmeup?sslmode=verify-ca", DBSSLMode: "disable"},
+			want: "postgres://db/logmeup?sslmode=verify-ca",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConnString(&tt.cfg)
+			if err != nil {
+				t.Fatalf("Failed to build connection string: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tehsis/logmeup-api/migrations"
+)
+
+// migrationLockID is the Postgres advisory lock held while migrating, so
+// servers auto-migrating at the same time apply each migration once
+const migrationLockID = 7415297261
+
+// Migration is one numbered schema change read from a pair of
+// NNNNNN_name.up.sql and NNNNNN_name.down.sql files
+type Migration struct {
+	Version uint
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Migrator applies the embedded migrations for a driver. The version is kept
+// in a schema_migrations table laid out like golang-migrate's, so databases
+// migrated with that tool are picked up where they left off.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for db using the migrations embedded for
+// driver ("postgres" or "sqlite")
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	var fsys fs.FS
+	switch driver {
+	case "postgres":
+		fsys = migrations.Postgres
+	case "sqlite":
+		fsys = migrations.SQLite
+	default:
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	list, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: list}, nil
+}
+
+// LoadMigrations reads the migrations at the top level of fsys in version order
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := base[strings.LastIndex(base, ".")+1:]
+		base = strings.TrimSuffix(base, "."+direction)
+		number, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(number, 10, 32)
+		if err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: name}
+			byVersion[uint(version)] = m
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Latest is the version the newest migration brings the schema to
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the applied version, zero when nothing has been applied
+func (m *Migrator) Version(ctx context.Context) (uint, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	return m.version(ctx, m.db)
+}
+
+// Check returns an error unless every migration has been applied. Unlike
+// Version it only reads, so readiness probes never write to the database; a
+// missing schema_migrations table counts as version 0.
+func (m *Migrator) Check(ctx context.Context) error {
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return err
+	}
+	var version uint
+	if exists {
+		if version, err = m.version(ctx, m.db); err != nil {
+			return err
+		}
+	}
+	if latest := m.Latest(); version != latest {
+		return fmt.Errorf("schema is at version %d, expected %d", version, latest)
+	}
+	return nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		status[i] = MigrationStatus{Migration: migration, Applied: migration.Version <= version}
+	}
+	return status, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the given number of applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	target := uint(0)
+	index := m.index(version)
+	if index-steps >= 0 {
+		target = m.migrations[index-steps].Version
+	}
+	return m.To(ctx, target)
+}
+
+// To migrates up or down until target is the applied version. Each
+// migration runs in its own transaction together with the version update.
+func (m *Migrator) To(ctx context.Context, target uint) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	for {
+		done, err := m.step(ctx, target)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// step applies the next migration towards target, reporting whether target
+// had already been reached
+func (m *Migrator) step(ctx context.Context, target uint) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting migration: %v", err)
+	}
+	defer tx.Rollback()
+
+	if m.driver == "postgres" {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+			return false, fmt.Errorf("error locking migrations: %v", err)
+		}
+	}
+
+	version, err := m.version(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if version == target {
+		return true, nil
+	}
+
+	index := m.index(version)
+	if version != 0 && index < 0 {
+		return false, fmt.Errorf("database is at version %d, which this binary does not know", version)
+	}
+
+	var migration Migration
+	var script string
+	var next uint
+	if version < target {
+		migration = m.migrations[index+1]
+		script, next = migration.up, migration.Version
+	} else {
+		migration = m.migrations[index]
+		script = migration.down
+		if index > 0 {
+			next = m.migrations[index-1].Version
+		}
+		if script == "" {
+			return false, fmt.Errorf("migration %d has no down file", migration.Version)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, fmt.Errorf("error applying migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return false, fmt.Errorf("error recording migration version: %v", err)
+	}
+	if next > 0 {
+		if _, err := tx.ExecContext(ctx, m.rebind("INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)"), next); err != nil {
+			return false, fmt.Errorf("error recording migration version: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing migration %d: %v", migration.Version, err)
+	}
+	return false, nil
+}
+
+// index finds the position of version in the migration list, or -1
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// tableExists reports whether schema_migrations has been created
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	query := "SELECT to_regclass('schema_migrations') IS NOT NULL"
+	if m.driver == "sqlite" {
+		query = "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')"
+	}
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error looking for schema_migrations: %v", err)
+	}
+	return exists, nil
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (m *Migrator) version(ctx context.Context, q querier) (uint, error) {
+	var version int64
+	var dirty bool
+	err := q.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading migration version: %v", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("database is dirty at version %d: a previous migration failed part way and must be fixed by hand", version)
+	}
+	return uint(version), nil
+}
+
+// rebind turns the $1 placeholder into ? for SQLite
+func (m *Migrator) rebind(query string) string {
+	if m.driver != "sqlite" {
+		return query
+	}
+	return strings.ReplaceAll(query, "$1", "?")
+}
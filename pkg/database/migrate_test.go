@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/tehsis/logmeup-api/migrations"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DriversInStep", func(t *testing.T) {
+		postgres, err := LoadMigrations(migrations.Postgres)
+		if err != nil {
+			t.Fatalf("Failed to load Postgres migrations: %v", err)
+		}
+		sqlite, err := LoadMigrations(migrations.SQLite)
+		if err != nil {
+			t.Fatalf("Failed to load SQLite migrations: %v", err)
+		}
+
+		if len(postgres) != len(sqlite) {
+			t.Fatalf("Expected the same number of migrations, got %d for Postgres and %d for SQLite", len(postgres), len(sqlite))
+		}
+		for i := range postgres {
+			if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+				t.Errorf("Expected migration %d_%s in both drivers, got %d_%s for SQLite",
+					postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+			}
+			if postgres[i].down == "" || sqlite[i].down == "" {
+				t.Errorf("Expected migration %d to have a down file for both drivers", postgres[i].Version)
+			}
+		}
+	})
+
+	t.Run("UpAndDown", func(t *testing.T) {
+		db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "migrate.db"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		migrator, err := NewMigrator(db, "sqlite")
+		if err != nil {
+			t.Fatalf("Failed to create migrator: %v", err)
+		}
+
+		if err := migrator.Check(ctx); err == nil || err.Error() != fmt.Sprintf("schema is at version 0, expected %d", migrator.Latest()) {
+			t.Errorf("Expected Check to report version 0 before migrating, got %v", err)
+		}
+		var tables int
+		db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&tables)
+		if tables != 0 {
+			t.Error("Expected Check not to create schema_migrations")
+		}
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Failed to migrate up: %v", err)
+		}
+		if err := migrator.Check(ctx); err != nil {
+			t.Errorf("Expected Check to pass after migrating: %v", err)
+		}
+		// Running again is a no-op
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Failed to migrate up twice: %v", err)
+		}
+		if version, err := migrator.Version(ctx); err != nil || version != migrator.Latest() {
+			t.Errorf("Expected version %d, got %d (%v)", migrator.Latest(), version, err)
+		}
+		if _, err := db.Exec("INSERT INTO notes (content, date, created_at, updated_at) VALUES ('x', '2024-01-01', '', '')"); err != nil {
+			t.Errorf("Expected the notes table to exist: %v", err)
+		}
+
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		for _, s := range status {
+			if !s.Applied {
+				t.Errorf("Expected migration %d to be applied", s.Version)
+			}
+		}
+
+		if err := migrator.Down(ctx, len(status)); err != nil {
+			t.Fatalf("Failed to migrate down: %v", err)
+		}
+		if version, _ := migrator.Version(ctx); version != 0 {
+			t.Errorf("Expected version 0, got %d", version)
+		}
+		if _, err := db.Exec("SELECT 1 FROM notes"); err == nil {
+			t.Error("Expected the notes table to be dropped")
+		}
+
+		if err := migrator.To(ctx, 999); err == nil {
+			t.Error("Expected error migrating to an unknown version")
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/tehsis/logmeup-api/pkg/config"
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLiteConnection opens the SQLite database at cfg.DBPath, creating the
+// file if needed. Foreign keys are enabled so deleting a note removes its
+// actions, and writers wait for each other instead of failing.
+func NewSQLiteConnection(cfg *config.Config) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", cfg.DBPath)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	configurePool(db, cfg)
+
+	if err := waitForDatabase(db, cfg.DBConnectTimeout); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to the database: %v", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,1210 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: logmeup/v1/logmeup.proto
+
+// logmeup.v1 serves the notes and actions of the REST API over gRPC. Errors
+// use the standard status codes and carry a google.rpc.ErrorInfo whose
+// reason is the REST error code, such as NOT_FOUND or INVALID_REFERENCE,
+// plus a google.rpc.BadRequest listing invalid fields.
+
+package logmeupv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ActionEvent_Type int32
+
+const (
+	ActionEvent_TYPE_UNSPECIFIED ActionEvent_Type = 0
+	ActionEvent_TYPE_CREATED     ActionEvent_Type = 1
+	ActionEvent_TYPE_UPDATED     ActionEvent_Type = 2
+	ActionEvent_TYPE_DELETED     ActionEvent_Type = 3
+)
+
+// Enum value maps for ActionEvent_Type.
+var (
+	ActionEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_CREATED",
+		2: "TYPE_UPDATED",
+		3: "TYPE_DELETED",
+	}
+	ActionEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_CREATED":     1,
+		"TYPE_UPDATED":     2,
+		"TYPE_DELETED":     3,
+	}
+)
+
+func (x ActionEvent_Type) Enum() *ActionEvent_Type {
+	p := new(ActionEvent_Type)
+	*p = x
+	return p
+}
+
+func (x ActionEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ActionEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_logmeup_v1_logmeup_proto_enumTypes[0].Descriptor()
+}
+
+func (ActionEvent_Type) Type() protoreflect.EnumType {
+	return &file_logmeup_v1_logmeup_proto_enumTypes[0]
+}
+
+func (x ActionEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ActionEvent_Type.Descriptor instead.
+func (ActionEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{17, 0}
+}
+
+// A note written on a given day.
+type Note struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Content string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	// The day the note is for, as YYYY-MM-DD.
+	Date          string                 `protobuf:"bytes,3,opt,name=date,proto3" json:"date,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Note) Reset() {
+	*x = Note{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Note) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Note) ProtoMessage() {}
+
+func (x *Note) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Note.ProtoReflect.Descriptor instead.
+func (*Note) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Note) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Note) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Note) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Note) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Note) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CreateNoteRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Content string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	// YYYY-MM-DD
+	Date          string `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateNoteRequest) Reset() {
+	*x = CreateNoteRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateNoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateNoteRequest) ProtoMessage() {}
+
+func (x *CreateNoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateNoteRequest.ProtoReflect.Descriptor instead.
+func (*CreateNoteRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateNoteRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *CreateNoteRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+type GetNoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNoteRequest) Reset() {
+	*x = GetNoteRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNoteRequest) ProtoMessage() {}
+
+func (x *GetNoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNoteRequest.ProtoReflect.Descriptor instead.
+func (*GetNoteRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetNoteRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListNotesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// First day, YYYY-MM-DD.
+	From string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	// Last day, inclusive; defaults to from.
+	To            string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNotesRequest) Reset() {
+	*x = ListNotesRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNotesRequest) ProtoMessage() {}
+
+func (x *ListNotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNotesRequest.ProtoReflect.Descriptor instead.
+func (*ListNotesRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListNotesRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ListNotesRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type ListNotesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Notes         []*Note                `protobuf:"bytes,1,rep,name=notes,proto3" json:"notes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNotesResponse) Reset() {
+	*x = ListNotesResponse{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNotesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNotesResponse) ProtoMessage() {}
+
+func (x *ListNotesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNotesResponse.ProtoReflect.Descriptor instead.
+func (*ListNotesResponse) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListNotesResponse) GetNotes() []*Note {
+	if x != nil {
+		return x.Notes
+	}
+	return nil
+}
+
+type UpdateNoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateNoteRequest) Reset() {
+	*x = UpdateNoteRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateNoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateNoteRequest) ProtoMessage() {}
+
+func (x *UpdateNoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateNoteRequest.ProtoReflect.Descriptor instead.
+func (*UpdateNoteRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateNoteRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateNoteRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type DeleteNoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteNoteRequest) Reset() {
+	*x = DeleteNoteRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteNoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteNoteRequest) ProtoMessage() {}
+
+func (x *DeleteNoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteNoteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteNoteRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteNoteRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteNoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteNoteResponse) Reset() {
+	*x = DeleteNoteResponse{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteNoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteNoteResponse) ProtoMessage() {}
+
+func (x *DeleteNoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteNoteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteNoteResponse) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{7}
+}
+
+// A follow-up taken from a note.
+type Action struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	NoteId        int64                  `protobuf:"varint,2,opt,name=note_id,json=noteId,proto3" json:"note_id,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Completed     bool                   `protobuf:"varint,4,opt,name=completed,proto3" json:"completed,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Action) Reset() {
+	*x = Action{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Action) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Action) ProtoMessage() {}
+
+func (x *Action) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Action.ProtoReflect.Descriptor instead.
+func (*Action) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Action) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Action) GetNoteId() int64 {
+	if x != nil {
+		return x.NoteId
+	}
+	return 0
+}
+
+func (x *Action) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Action) GetCompleted() bool {
+	if x != nil {
+		return x.Completed
+	}
+	return false
+}
+
+func (x *Action) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Action) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CreateActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NoteId        int64                  `protobuf:"varint,1,opt,name=note_id,json=noteId,proto3" json:"note_id,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateActionRequest) Reset() {
+	*x = CreateActionRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateActionRequest) ProtoMessage() {}
+
+func (x *CreateActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateActionRequest.ProtoReflect.Descriptor instead.
+func (*CreateActionRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateActionRequest) GetNoteId() int64 {
+	if x != nil {
+		return x.NoteId
+	}
+	return 0
+}
+
+func (x *CreateActionRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type GetActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetActionRequest) Reset() {
+	*x = GetActionRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetActionRequest) ProtoMessage() {}
+
+func (x *GetActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetActionRequest.ProtoReflect.Descriptor instead.
+func (*GetActionRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetActionRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListActionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Keep actions on any of these notes.
+	NoteIds []int64 `protobuf:"varint,1,rep,packed,name=note_ids,json=noteIds,proto3" json:"note_ids,omitempty"`
+	// Keep actions in this state.
+	Completed *bool `protobuf:"varint,2,opt,name=completed,proto3,oneof" json:"completed,omitempty"`
+	// Keep actions created strictly after and before these times.
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListActionsRequest) Reset() {
+	*x = ListActionsRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListActionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListActionsRequest) ProtoMessage() {}
+
+func (x *ListActionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListActionsRequest.ProtoReflect.Descriptor instead.
+func (*ListActionsRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListActionsRequest) GetNoteIds() []int64 {
+	if x != nil {
+		return x.NoteIds
+	}
+	return nil
+}
+
+func (x *ListActionsRequest) GetCompleted() bool {
+	if x != nil && x.Completed != nil {
+		return *x.Completed
+	}
+	return false
+}
+
+func (x *ListActionsRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListActionsRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+type ListActionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Actions       []*Action              `protobuf:"bytes,1,rep,name=actions,proto3" json:"actions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListActionsResponse) Reset() {
+	*x = ListActionsResponse{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListActionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListActionsResponse) ProtoMessage() {}
+
+func (x *ListActionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListActionsResponse.ProtoReflect.Descriptor instead.
+func (*ListActionsResponse) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListActionsResponse) GetActions() []*Action {
+	if x != nil {
+		return x.Actions
+	}
+	return nil
+}
+
+type UpdateActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Completed     bool                   `protobuf:"varint,2,opt,name=completed,proto3" json:"completed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateActionRequest) Reset() {
+	*x = UpdateActionRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateActionRequest) ProtoMessage() {}
+
+func (x *UpdateActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateActionRequest.ProtoReflect.Descriptor instead.
+func (*UpdateActionRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UpdateActionRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateActionRequest) GetCompleted() bool {
+	if x != nil {
+		return x.Completed
+	}
+	return false
+}
+
+type DeleteActionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteActionRequest) Reset() {
+	*x = DeleteActionRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteActionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteActionRequest) ProtoMessage() {}
+
+func (x *DeleteActionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteActionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteActionRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteActionRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteActionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteActionResponse) Reset() {
+	*x = DeleteActionResponse{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteActionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteActionResponse) ProtoMessage() {}
+
+func (x *DeleteActionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteActionResponse.ProtoReflect.Descriptor instead.
+func (*DeleteActionResponse) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{15}
+}
+
+type WatchActionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream changes to actions on these notes; empty streams them all.
+	// Deletions are always sent since the note of a deleted action is unknown.
+	NoteIds       []int64 `protobuf:"varint,1,rep,packed,name=note_ids,json=noteIds,proto3" json:"note_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchActionsRequest) Reset() {
+	*x = WatchActionsRequest{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchActionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchActionsRequest) ProtoMessage() {}
+
+func (x *WatchActionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchActionsRequest.ProtoReflect.Descriptor instead.
+func (*WatchActionsRequest) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WatchActionsRequest) GetNoteIds() []int64 {
+	if x != nil {
+		return x.NoteIds
+	}
+	return nil
+}
+
+type ActionEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  ActionEvent_Type       `protobuf:"varint,1,opt,name=type,proto3,enum=logmeup.v1.ActionEvent_Type" json:"type,omitempty"`
+	// The ID of the changed action.
+	Id int64 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// The action after the change; unset for deletions.
+	Action        *Action `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActionEvent) Reset() {
+	*x = ActionEvent{}
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActionEvent) ProtoMessage() {}
+
+func (x *ActionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_logmeup_v1_logmeup_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActionEvent.ProtoReflect.Descriptor instead.
+func (*ActionEvent) Descriptor() ([]byte, []int) {
+	return file_logmeup_v1_logmeup_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ActionEvent) GetType() ActionEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return ActionEvent_TYPE_UNSPECIFIED
+}
+
+func (x *ActionEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ActionEvent) GetAction() *Action {
+	if x != nil {
+		return x.Action
+	}
+	return nil
+}
+
+var File_logmeup_v1_logmeup_proto protoreflect.FileDescriptor
+
+const file_logmeup_v1_logmeup_proto_rawDesc = "" +
+	"\n" +
+	"\x18logmeup/v1/logmeup.proto\x12\n" +
+	"logmeup.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xba\x01\n" +
+	"\x04Note\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x12\n" +
+	"\x04date\x18\x03 \x01(\tR\x04date\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"A\n" +
+	"\x11CreateNoteRequest\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
+	"\x04date\x18\x02 \x01(\tR\x04date\" \n" +
+	"\x0eGetNoteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"6\n" +
+	"\x10ListNotesRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\";\n" +
+	"\x11ListNotesResponse\x12&\n" +
+	"\x05notes\x18\x01 \x03(\v2\x10.logmeup.v1.NoteR\x05notes\"=\n" +
+	"\x11UpdateNoteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"#\n" +
+	"\x11DeleteNoteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x14\n" +
+	"\x12DeleteNoteResponse\"\xe7\x01\n" +
+	"\x06Action\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x17\n" +
+	"\anote_id\x18\x02 \x01(\x03R\x06noteId\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1c\n" +
+	"\tcompleted\x18\x04 \x01(\bR\tcompleted\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"P\n" +
+	"\x13CreateActionRequest\x12\x17\n" +
+	"\anote_id\x18\x01 \x01(\x03R\x06noteId\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"\"\n" +
+	"\x10GetActionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\xe4\x01\n" +
+	"\x12ListActionsRequest\x12\x19\n" +
+	"\bnote_ids\x18\x01 \x03(\x03R\anoteIds\x12!\n" +
+	"\tcompleted\x18\x02 \x01(\bH\x00R\tcompleted\x88\x01\x01\x12?\n" +
+	"\rcreated_after\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBeforeB\f\n" +
+	"\n" +
+	"_completed\"C\n" +
+	"\x13ListActionsResponse\x12,\n" +
+	"\aactions\x18\x01 \x03(\v2\x12.logmeup.v1.ActionR\aactions\"C\n" +
+	"\x13UpdateActionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1c\n" +
+	"\tcompleted\x18\x02 \x01(\bR\tcompleted\"%\n" +
+	"\x13DeleteActionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x16\n" +
+	"\x14DeleteActionResponse\"0\n" +
+	"\x13WatchActionsRequest\x12\x19\n" +
+	"\bnote_ids\x18\x01 \x03(\x03R\anoteIds\"\xcf\x01\n" +
+	"\vActionEvent\x120\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1c.logmeup.v1.ActionEvent.TypeR\x04type\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\x12*\n" +
+	"\x06action\x18\x03 \x01(\v2\x12.logmeup.v1.ActionR\x06action\"R\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fTYPE_CREATED\x10\x01\x12\x10\n" +
+	"\fTYPE_UPDATED\x10\x02\x12\x10\n" +
+	"\fTYPE_DELETED\x10\x032\xdb\x02\n" +
+	"\vNoteService\x12=\n" +
+	"\n" +
+	"CreateNote\x12\x1d.logmeup.v1.CreateNoteRequest\x1a\x10.logmeup.v1.Note\x127\n" +
+	"\aGetNote\x12\x1a.logmeup.v1.GetNoteRequest\x1a\x10.logmeup.v1.Note\x12H\n" +
+	"\tListNotes\x12\x1c.logmeup.v1.ListNotesRequest\x1a\x1d.logmeup.v1.ListNotesResponse\x12=\n" +
+	"\n" +
+	"UpdateNote\x12\x1d.logmeup.v1.UpdateNoteRequest\x1a\x10.logmeup.v1.Note\x12K\n" +
+	"\n" +
+	"DeleteNote\x12\x1d.logmeup.v1.DeleteNoteRequest\x1a\x1e.logmeup.v1.DeleteNoteResponse2\xc7\x03\n" +
+	"\rActionService\x12C\n" +
+	"\fCreateAction\x12\x1f.logmeup.v1.CreateActionRequest\x1a\x12.logmeup.v1.Action\x12=\n" +
+	"\tGetAction\x12\x1c.logmeup.v1.GetActionRequest\x1a\x12.logmeup.v1.Action\x12N\n" +
+	"\vListActions\x12\x1e.logmeup.v1.ListActionsRequest\x1a\x1f.logmeup.v1.ListActionsResponse\x12C\n" +
+	"\fUpdateAction\x12\x1f.logmeup.v1.UpdateActionRequest\x1a\x12.logmeup.v1.Action\x12Q\n" +
+	"\fDeleteAction\x12\x1f.logmeup.v1.DeleteActionRequest\x1a .logmeup.v1.DeleteActionResponse\x12J\n" +
+	"\fWatchActions\x12\x1f.logmeup.v1.WatchActionsRequest\x1a\x17.logmeup.v1.ActionEvent0\x01B;Z9github.com/tehsis/logmeup-api/pkg/pb/logmeup/v1;logmeupv1b\x06proto3"
+
+var (
+	file_logmeup_v1_logmeup_proto_rawDescOnce sync.Once
+	file_logmeup_v1_logmeup_proto_rawDescData []byte
+)
+
+func file_logmeup_v1_logmeup_proto_rawDescGZIP() []byte {
+	file_logmeup_v1_logmeup_proto_rawDescOnce.Do(func() {
+		file_logmeup_v1_logmeup_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_logmeup_v1_logmeup_proto_rawDesc), len(file_logmeup_v1_logmeup_proto_rawDesc)))
+	})
+	return file_logmeup_v1_logmeup_proto_rawDescData
+}
+
+var file_logmeup_v1_logmeup_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_logmeup_v1_logmeup_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_logmeup_v1_logmeup_proto_goTypes = []any{
+	(ActionEvent_Type)(0),         // 0: logmeup.v1.ActionEvent.Type
+	(*Note)(nil),                  // 1: logmeup.v1.Note
+	(*CreateNoteRequest)(nil),     // 2: logmeup.v1.CreateNoteRequest
+	(*GetNoteRequest)(nil),        // 3: logmeup.v1.GetNoteRequest
+	(*ListNotesRequest)(nil),      // 4: logmeup.v1.ListNotesRequest
+	(*ListNotesResponse)(nil),     // 5: logmeup.v1.ListNotesResponse
+	(*UpdateNoteRequest)(nil),     // 6: logmeup.v1.UpdateNoteRequest
+	(*DeleteNoteRequest)(nil),     // 7: logmeup.v1.DeleteNoteRequest
+	(*DeleteNoteResponse)(nil),    // 8: logmeup.v1.DeleteNoteResponse
+	(*Action)(nil),                // 9: logmeup.v1.Action
+	(*CreateActionRequest)(nil),   // 10: logmeup.v1.CreateActionRequest
+	(*GetActionRequest)(nil),      // 11: logmeup.v1.GetActionRequest
+	(*ListActionsRequest)(nil),    // 12: logmeup.v1.ListActionsRequest
+	(*ListActionsResponse)(nil),   // 13: logmeup.v1.ListActionsResponse
+	(*UpdateActionRequest)(nil),   // 14: logmeup.v1.UpdateActionRequest
+	(*DeleteActionRequest)(nil),   // 15: logmeup.v1.DeleteActionRequest
+	(*DeleteActionResponse)(nil),  // 16: logmeup.v1.DeleteActionResponse
+	(*WatchActionsRequest)(nil),   // 17: logmeup.v1.WatchActionsRequest
+	(*ActionEvent)(nil),           // 18: logmeup.v1.ActionEvent
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_logmeup_v1_logmeup_proto_depIdxs = []int32{
+	19, // 0: logmeup.v1.Note.created_at:type_name -> google.protobuf.Timestamp
+	19, // 1: logmeup.v1.Note.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: logmeup.v1.ListNotesResponse.notes:type_name -> logmeup.v1.Note
+	19, // 3: logmeup.v1.Action.created_at:type_name -> google.protobuf.Timestamp
+	19, // 4: logmeup.v1.Action.updated_at:type_name -> google.protobuf.Timestamp
+	19, // 5: logmeup.v1.ListActionsRequest.created_after:type_name -> google.protobuf.Timestamp
+	19, // 6: logmeup.v1.ListActionsRequest.created_before:type_name -> google.protobuf.Timestamp
+	9,  // 7: logmeup.v1.ListActionsResponse.actions:type_name -> logmeup.v1.Action
+	0,  // 8: logmeup.v1.ActionEvent.type:type_name -> logmeup.v1.ActionEvent.Type
+	9,  // 9: logmeup.v1.ActionEvent.action:type_name -> logmeup.v1.Action
+	2,  // 10: logmeup.v1.NoteService.CreateNote:input_type -> logmeup.v1.CreateNoteRequest
+	3,  // 11: logmeup.v1.NoteService.GetNote:input_type -> logmeup.v1.GetNoteRequest
+	4,  // 12: logmeup.v1.NoteService.ListNotes:input_type -> logmeup.v1.ListNotesRequest
+	6,  // 13: logmeup.v1.NoteService.UpdateNote:input_type -> logmeup.v1.UpdateNoteRequest
+	7,  // 14: logmeup.v1.NoteService.DeleteNote:input_type -> logmeup.v1.DeleteNoteRequest
+	10, // 15: logmeup.v1.ActionService.CreateAction:input_type -> logmeup.v1.CreateActionRequest
+	11, // 16: logmeup.v1.ActionService.GetAction:input_type -> logmeup.v1.GetActionRequest
+	12, // 17: logmeup.v1.ActionService.ListActions:input_type -> logmeup.v1.ListActionsRequest
+	14, // 18: logmeup.v1.ActionService.UpdateAction:input_type -> logmeup.v1.UpdateActionRequest
+	15, // 19: logmeup.v1.ActionService.DeleteAction:input_type -> logmeup.v1.DeleteActionRequest
+	17, // 20: logmeup.v1.ActionService.WatchActions:input_type -> logmeup.v1.WatchActionsRequest
+	1,  // 21: logmeup.v1.NoteService.CreateNote:output_type -> logmeup.v1.Note
+	1,  // 22: logmeup.v1.NoteService.GetNote:output_type -> logmeup.v1.Note
+	5,  // 23: logmeup.v1.NoteService.ListNotes:output_type -> logmeup.v1.ListNotesResponse
+	1,  // 24: logmeup.v1.NoteService.UpdateNote:output_type -> logmeup.v1.Note
+	8,  // 25: logmeup.v1.NoteService.DeleteNote:output_type -> logmeup.v1.DeleteNoteResponse
+	9,  // 26: logmeup.v1.ActionService.CreateAction:output_type -> logmeup.v1.Action
+	9,  // 27: logmeup.v1.ActionService.GetAction:output_type -> logmeup.v1.Action
+	13, // 28: logmeup.v1.ActionService.ListActions:output_type -> logmeup.v1.ListActionsResponse
+	9,  // 29: logmeup.v1.ActionService.UpdateAction:output_type -> logmeup.v1.Action
+	16, // 30: logmeup.v1.ActionService.DeleteAction:output_type -> logmeup.v1.DeleteActionResponse
+	18, // 31: logmeup.v1.ActionService.WatchActions:output_type -> logmeup.v1.ActionEvent
+	21, // [21:32] is the sub-list for method output_type
+	10, // [10:21] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_logmeup_v1_logmeup_proto_init() }
+func file_logmeup_v1_logmeup_proto_init() {
+	if File_logmeup_v1_logmeup_proto != nil {
+		return
+	}
+	file_logmeup_v1_logmeup_proto_msgTypes[11].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_logmeup_v1_logmeup_proto_rawDesc), len(file_logmeup_v1_logmeup_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_logmeup_v1_logmeup_proto_goTypes,
+		DependencyIndexes: file_logmeup_v1_logmeup_proto_depIdxs,
+		EnumInfos:         file_logmeup_v1_logmeup_proto_enumTypes,
+		MessageInfos:      file_logmeup_v1_logmeup_proto_msgTypes,
+	}.Build()
+	File_logmeup_v1_logmeup_proto = out.File
+	file_logmeup_v1_logmeup_proto_goTypes = nil
+	file_logmeup_v1_logmeup_proto_depIdxs = nil
+}